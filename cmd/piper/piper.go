@@ -2,55 +2,88 @@ package main
 
 import (
 	rookout "github.com/Rookout/GoSDK"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
-	"github.com/quickube/piper/pkg/event_handler"
 	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
 	"github.com/quickube/piper/pkg/server"
+	"github.com/quickube/piper/pkg/tracing"
 	"github.com/quickube/piper/pkg/utils"
 	workflowHandler "github.com/quickube/piper/pkg/workflow_handler"
 	"golang.org/x/net/context"
 	"log"
+	"log/slog"
+	"os"
 	"os/signal"
 	"syscall"
 )
 
+const workflowsConfigPath = "/piper-config/..data"
+
 func main() {
 	cfg, err := conf.LoadConfig()
 	if err != nil {
 		log.Panicf("failed to load the configuration for Piper, error: %v", err)
 	}
+	logger, err := logging.New(os.Stderr, cfg)
+	if err != nil {
+		log.Panicf("failed to create the logger for Piper, error: %v", err)
+	}
+	slog.SetDefault(logger)
 
 	if cfg.RookoutConfig.Token != "" {
 		labels := utils.StringToMap(cfg.RookoutConfig.Labels)
 		err = rookout.Start(rookout.RookOptions{Token: cfg.RookoutConfig.Token, Labels: labels})
 		if err != nil {
-			log.Printf("failed to start Rookout, error: %v\n", err)
+			logger.Error("failed to start Rookout", "error", err)
 		}
 	}
 
-	err = cfg.WorkflowsConfig.WorkflowsSpecLoad("/piper-config/..data")
+	err = cfg.WorkflowsConfig.WorkflowsSpecLoad(context.Background(), workflowsConfigPath, logger)
+	if err != nil {
+		logger.Error("failed to load workflow spec configuration", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Start(context.Background(), cfg)
 	if err != nil {
-		log.Panicf("Failed to load workflow spec configuration, error: %v", err)
+		logger.Error("failed to start tracing", "error", err)
+		os.Exit(1)
 	}
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			logger.Error("failed to flush the remaining spans", "error", err)
+		}
+	}()
 
-	gitProvider, err := git_provider.NewGitProviderClient(cfg)
+	recorder := metrics.NewRecorder(prometheus.DefaultRegisterer)
+	gitProvider, err := git_provider.NewGitProviderClient(cfg, logger)
 	if err != nil {
-		log.Panicf("failed to load the Git client for Piper, error: %v", err)
+		logger.Error("failed to load the Git client for Piper", "error", err)
+		os.Exit(1)
 	}
-	workflows, err := workflowHandler.NewWorkflowsClient(cfg)
+	workflows, err := workflowHandler.NewWorkflowsClient(cfg, logger)
 	if err != nil {
-		log.Panicf("failed to load the Argo Workflows client for Piper, error: %v", err)
+		logger.Error("failed to load the Argo Workflows client for Piper", "error", err)
+		os.Exit(1)
 	}
 
 	globalClients := &clients.Clients{
-		GitProvider: gitProvider,
-		Workflows:   workflows,
+		GitProvider: git_provider.NewInstrumentedClient(gitProvider, recorder),
+		Workflows:   workflowHandler.NewInstrumentedWorkflowsClient(workflows, recorder),
+		Metrics:     recorder,
+		Logger:      logger,
 	}
 
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
-	event_handler.Start(ctx, stop, cfg, globalClients)
+	err = cfg.WorkflowsConfig.WatchWorkflowsSpec(ctx, workflowsConfigPath, logger)
+	if err != nil {
+		logger.Warn("workflow spec configuration will not be reloaded", "error", err)
+	}
 	server.Start(ctx, stop, cfg, globalClients)
 }
@@ -1,11 +1,18 @@
 package clients
 
 import (
+	"log/slog"
+
 	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/metrics"
 	"github.com/quickube/piper/pkg/workflow_handler"
 )
 
 type Clients struct {
 	GitProvider git_provider.Client
 	Workflows   workflow_handler.WorkflowsClient
+	// Metrics may be nil, metrics.OrNoop drops the metrics then.
+	Metrics metrics.Recorder
+	// Logger may be nil, logging.OrDefault uses the default logger then.
+	Logger *slog.Logger
 }
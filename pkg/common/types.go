@@ -1,14 +1,38 @@
 package common
 
 import (
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/git_provider"
 )
 
 type WorkflowsBatch struct {
-	OnStart    []*git_provider.CommitFile
-	OnExit     []*git_provider.CommitFile
-	Templates  []*git_provider.CommitFile
-	Parameters *git_provider.CommitFile
-	Config     *string
-	Payload    *git_provider.WebhookPayload
+	OnStart             []*git_provider.CommitFile
+	OnExit              []*git_provider.CommitFile
+	Templates           []*git_provider.CommitFile
+	Parameters          *git_provider.CommitFile
+	TriggerParameters   []Parameter
+	Config              *string
+	WorkflowTemplateRef *v1alpha1.WorkflowTemplateRef
+	Concurrency         *Concurrency
+	IdempotencyKey      string
+	NodeStatuses        bool
+	TriggerIndex        int
+	Payload             *git_provider.WebhookPayload
+}
+
+type Concurrency struct {
+	Group            string
+	CancelInProgress bool
+	CancelStrategy   v1alpha1.ShutdownStrategy
+}
+
+// Parameter is a trigger parameter. A parameter without a value only validates the value
+// set by parameters.yaml or the global variables.
+type Parameter struct {
+	Name     string   `yaml:"name" json:"name"`
+	Value    *string  `yaml:"value" json:"value,omitempty"`
+	Type     string   `yaml:"type" json:"type,omitempty"`
+	Enum     []string `yaml:"enum" json:"enum,omitempty"`
+	Required bool     `yaml:"required" json:"required,omitempty"`
+	Pattern  string   `yaml:"pattern" json:"pattern,omitempty"`
 }
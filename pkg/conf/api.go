@@ -0,0 +1,20 @@
+package conf
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type ApiConfig struct {
+	Token string `envconfig:"PIPER_API_TOKEN" required:"false"`
+}
+
+func (cfg *ApiConfig) ApiConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the API configuration, error: %v", err)
+	}
+
+	return nil
+}
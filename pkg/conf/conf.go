@@ -10,6 +10,16 @@ type GlobalConfig struct {
 	WorkflowServerConfig
 	RookoutConfig
 	WorkflowsConfig
+	ApiConfig
+	WebhookConfig
+	EventStoreConfig
+	PolicyConfig
+	ControllerConfig
+	EventHandlerConfig
+	LeaderElectionConfig
+	NotificationsConfig
+	TracingConfig
+	LogConfig
 }
 
 func (cfg *GlobalConfig) Load() error {
@@ -0,0 +1,24 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type ControllerConfig struct {
+	Enabled        bool          `envconfig:"PIPER_CONTROLLER_ENABLED" default:"false"`
+	WatchNamespace string        `envconfig:"PIPER_CONTROLLER_NAMESPACE" default:""`
+	ResyncPeriod   time.Duration `envconfig:"PIPER_CONTROLLER_RESYNC_PERIOD" default:"10m"`
+	Workers        int           `envconfig:"PIPER_CONTROLLER_WORKERS" default:"2"`
+}
+
+func (cfg *ControllerConfig) ControllerConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the controller configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type EventHandlerConfig struct {
+	ResyncPeriod time.Duration `envconfig:"EVENT_HANDLER_RESYNC_PERIOD" default:"5m"`
+	Workers      int           `envconfig:"EVENT_HANDLER_WORKERS" default:"2"`
+	MaxRetries   int           `envconfig:"EVENT_HANDLER_MAX_RETRIES" default:"5"`
+	// StuckTimeout reports the workflows still pending or running after it as errors, 0 disables it.
+	StuckTimeout time.Duration `envconfig:"EVENT_HANDLER_STUCK_TIMEOUT" default:"0"`
+}
+
+func (cfg *EventHandlerConfig) EventHandlerConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the event handler configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package conf
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type EventStoreConfig struct {
+	Path      string `envconfig:"EVENT_STORE_PATH" default:""`
+	MaxEvents int    `envconfig:"EVENT_STORE_MAX_EVENTS" default:"1000"`
+}
+
+func (cfg *EventStoreConfig) EventStoreConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the event store configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type LeaderElectionConfig struct {
+	Enabled        bool          `envconfig:"LEADER_ELECTION_ENABLED" default:"false"`
+	LeaseName      string        `envconfig:"LEADER_ELECTION_LEASE_NAME" default:"piper-leader"`
+	LeaseNamespace string        `envconfig:"LEADER_ELECTION_NAMESPACE" default:""`
+	Identity       string        `envconfig:"LEADER_ELECTION_IDENTITY" default:""`
+	LeaseDuration  time.Duration `envconfig:"LEADER_ELECTION_LEASE_DURATION" default:"15s"`
+	RenewDeadline  time.Duration `envconfig:"LEADER_ELECTION_RENEW_DEADLINE" default:"10s"`
+	RetryPeriod    time.Duration `envconfig:"LEADER_ELECTION_RETRY_PERIOD" default:"2s"`
+}
+
+func (cfg *LeaderElectionConfig) LeaderElectionConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the leader election configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package conf
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string `envconfig:"LOG_LEVEL" default:"info"`
+	// Format is text or json.
+	Format string `envconfig:"LOG_FORMAT" default:"text"`
+}
+
+func (cfg *LogConfig) LogConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the log configuration, error: %v", err)
+	}
+
+	return nil
+}
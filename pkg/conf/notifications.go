@@ -0,0 +1,130 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/quickube/piper/pkg/utils"
+)
+
+const (
+	SinkTypeSlack   = "slack"
+	SinkTypeWebhook = "webhook"
+)
+
+var notificationPhases = []string{"Pending", "Running", "Succeeded", "Failed", "Error"}
+
+type NotificationsConfig struct {
+	Notifications Notifications `envconfig:"NOTIFICATIONS"`
+}
+
+// Notifications routes the workflows phases to sinks, in addition to the git provider commit status.
+type Notifications struct {
+	// DisableGitStatus stops setting the commit status of the workflows.
+	DisableGitStatus bool               `json:"disableGitStatus"`
+	Sinks            []NotificationSink `json:"sinks"`
+	Rules            []NotificationRule `json:"rules"`
+	// PullRequestComment keeps a summary comment of the workflows on the pull requests that triggered them.
+	PullRequestComment PullRequestComment `json:"pullRequestComment"`
+}
+
+// PullRequestComment lists the top-level DAG tasks of the completed workflows of a pull request in one comment,
+// updated in place. Outputs are path patterns of the output parameters shown for each task.
+type PullRequestComment struct {
+	Enabled bool     `json:"enabled"`
+	Outputs []string `json:"outputs"`
+}
+
+// NotificationSink is a Slack incoming webhook, or an HTTP endpoint receiving a JSON POST signed with Secret.
+// URLEnv and SecretEnv name environment variables holding the URL and the secret.
+type NotificationSink struct {
+	Name      string            `json:"name"`
+	Type      string            `json:"type"`
+	URL       string            `json:"url"`
+	URLEnv    string            `json:"urlEnv"`
+	Secret    string            `json:"secret"`
+	SecretEnv string            `json:"secretEnv"`
+	Headers   map[string]string `json:"headers"`
+}
+
+// NotificationRule sends the workflows matching every set filter to its sinks. Repos and Branches are path
+// patterns, matched against the repo and branch labels of the workflow.
+type NotificationRule struct {
+	Sinks    []string `json:"sinks"`
+	Repos    []string `json:"repos"`
+	Branches []string `json:"branches"`
+	Phases   []string `json:"phases"`
+	Template string   `json:"template"`
+}
+
+// Decode parses the NOTIFICATIONS value, given as YAML or JSON, and resolves the URLs and secrets of the sinks.
+func (n *Notifications) Decode(value string) error {
+	notifications := Notifications{}
+	err := yaml.Unmarshal([]byte(value), &notifications)
+	if err != nil {
+		return fmt.Errorf("failed to parse notifications, error: %v", err)
+	}
+
+	sinks := make(map[string]bool, len(notifications.Sinks))
+	for i := range notifications.Sinks {
+		sink := &notifications.Sinks[i]
+		if sink.Name == "" {
+			return fmt.Errorf("notification sink %d has no name", i)
+		}
+		if sinks[sink.Name] {
+			return fmt.Errorf("notification sink %s is defined twice", sink.Name)
+		}
+		sinks[sink.Name] = true
+
+		switch sink.Type {
+		case SinkTypeSlack, SinkTypeWebhook:
+		default:
+			return fmt.Errorf("unknown notification sink type %s for sink %s, expected slack or webhook", sink.Type, sink.Name)
+		}
+		if sink.URLEnv != "" {
+			sink.URL = os.Getenv(sink.URLEnv)
+		}
+		if !utils.ValidateHTTPFormat(sink.URL) {
+			return fmt.Errorf("notification sink %s has no valid url", sink.Name)
+		}
+		if sink.SecretEnv != "" {
+			sink.Secret = os.Getenv(sink.SecretEnv)
+			if sink.Secret == "" {
+				return fmt.Errorf("notification sink %s secret variable %s is empty", sink.Name, sink.SecretEnv)
+			}
+		}
+	}
+
+	for i, rule := range notifications.Rules {
+		if len(rule.Sinks) == 0 {
+			return fmt.Errorf("notification rule %d has no sinks", i)
+		}
+		for _, sink := range rule.Sinks {
+			if !sinks[sink] {
+				return fmt.Errorf("notification rule %d uses unknown sink %s", i, sink)
+			}
+		}
+		for _, phase := range rule.Phases {
+			if !utils.IsElementExists(notificationPhases, phase) {
+				return fmt.Errorf("notification rule %d uses unknown phase %s, expected one of %v", i, phase, notificationPhases)
+			}
+		}
+		if rule.Template != "" {
+			if _, err = utils.ParseTemplate(fmt.Sprintf("rule-%d", i), rule.Template); err != nil {
+				return fmt.Errorf("notification rule %d has an invalid template, error: %v", i, err)
+			}
+		}
+	}
+
+	for _, output := range notifications.PullRequestComment.Outputs {
+		if _, err = path.Match(output, ""); err != nil {
+			return fmt.Errorf("pull request comment output %s is not a valid pattern, error: %v", output, err)
+		}
+	}
+
+	*n = notifications
+	return nil
+}
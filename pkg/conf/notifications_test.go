@@ -0,0 +1,65 @@
+package conf
+
+import (
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestNotificationsDecode(t *testing.T) {
+	assert := assertion.New(t)
+	t.Setenv("TRACKER_SECRET", "s3cr3t")
+
+	tests := []struct {
+		name          string
+		value         string
+		expectedError bool
+	}{
+		{
+			name: "Valid notifications",
+			value: `sinks:
+  - name: slack
+    type: slack
+    url: https://hooks.slack.com/services/T0/B0/X
+  - name: tracker
+    type: webhook
+    url: https://tracker.example.com/api/deployments
+    secretEnv: TRACKER_SECRET
+rules:
+  - sinks: [slack]
+    branches: [main]
+    phases: [Failed, Error]
+  - sinks: [tracker]
+    repos: ["release-*"]
+    phases: [Succeeded]
+    template: "released ${{ .Commit }}"
+pullRequestComment:
+  enabled: true
+  outputs: ["image-*"]
+`,
+		},
+		{name: "Unknown sink type", value: `sinks: [{name: teams, type: teams, url: "https://example.com"}]`, expectedError: true},
+		{name: "Missing url", value: `sinks: [{name: slack, type: slack}]`, expectedError: true},
+		{name: "Empty secret variable", value: `sinks: [{name: hook, type: webhook, url: "https://example.com", secretEnv: MISSING_SECRET}]`, expectedError: true},
+		{name: "Unknown rule sink", value: `rules: [{sinks: [slack]}]`, expectedError: true},
+		{name: "Unknown phase", value: `sinks: [{name: slack, type: slack, url: "https://example.com"}]
+rules: [{sinks: [slack], phases: [Done]}]`, expectedError: true},
+		{name: "Invalid template", value: `sinks: [{name: slack, type: slack, url: "https://example.com"}]
+rules: [{sinks: [slack], template: "${{ .Repo "}]`, expectedError: true},
+		{name: "Invalid pull request comment output", value: `pullRequestComment: {enabled: true, outputs: ["[image"]}`, expectedError: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifications := &Notifications{}
+			err := notifications.Decode(test.value)
+			if test.expectedError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal("s3cr3t", notifications.Sinks[1].Secret)
+			assert.Len(notifications.Rules, 2)
+		})
+	}
+}
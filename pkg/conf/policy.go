@@ -0,0 +1,109 @@
+package conf
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+type PolicyMode string
+
+const (
+	PolicyModeWarn    PolicyMode = "warn"
+	PolicyModeEnforce PolicyMode = "enforce"
+)
+
+type PolicyConfig struct {
+	Policy Policy `envconfig:"WORKFLOW_POLICY"`
+}
+
+// Policy holds the rules every generated workflow is checked against before submission.
+// Each rule uses its own mode, or the policy mode when unset, and enforce when both are unset.
+type Policy struct {
+	Mode                  PolicyMode             `json:"mode"`
+	Images                *ImagePolicy           `json:"images"`
+	SecurityContext       *SecurityContextPolicy `json:"securityContext"`
+	ActiveDeadlineSeconds *DeadlinePolicy        `json:"activeDeadlineSeconds"`
+	Resources             *ResourcesPolicy       `json:"resources"`
+	Volumes               *VolumesPolicy         `json:"volumes"`
+}
+
+type ImagePolicy struct {
+	Mode              PolicyMode `json:"mode"`
+	AllowedRegistries []string   `json:"allowedRegistries"`
+}
+
+type SecurityContextPolicy struct {
+	Mode                      PolicyMode `json:"mode"`
+	ForbidPrivileged          bool       `json:"forbidPrivileged"`
+	ForbidPrivilegeEscalation bool       `json:"forbidPrivilegeEscalation"`
+	ForbidRunAsRoot           bool       `json:"forbidRunAsRoot"`
+	ForbidHostNetwork         bool       `json:"forbidHostNetwork"`
+	ForbidPodSpecPatch        bool       `json:"forbidPodSpecPatch"`
+	ForbiddenCapabilities     []string   `json:"forbiddenCapabilities"`
+}
+
+type DeadlinePolicy struct {
+	Mode PolicyMode `json:"mode"`
+	Max  int64      `json:"max"`
+}
+
+type ResourcesPolicy struct {
+	Mode          PolicyMode `json:"mode"`
+	RequireLimits []string   `json:"requireLimits"`
+}
+
+type VolumesPolicy struct {
+	Mode           PolicyMode `json:"mode"`
+	ForbiddenTypes []string   `json:"forbiddenTypes"`
+}
+
+// Decode parses the WORKFLOW_POLICY value, given as YAML or JSON.
+func (p *Policy) Decode(value string) error {
+	policy := Policy{}
+	err := yaml.Unmarshal([]byte(value), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to parse policy, error: %v", err)
+	}
+
+	modes := map[string]PolicyMode{"policy": policy.Mode}
+	if policy.Images != nil {
+		modes["images"] = policy.Images.Mode
+	}
+	if policy.SecurityContext != nil {
+		modes["securityContext"] = policy.SecurityContext.Mode
+	}
+	if policy.ActiveDeadlineSeconds != nil {
+		modes["activeDeadlineSeconds"] = policy.ActiveDeadlineSeconds.Mode
+		if policy.ActiveDeadlineSeconds.Max <= 0 {
+			return fmt.Errorf("policy activeDeadlineSeconds max must be positive")
+		}
+	}
+	if policy.Resources != nil {
+		modes["resources"] = policy.Resources.Mode
+	}
+	if policy.Volumes != nil {
+		modes["volumes"] = policy.Volumes.Mode
+	}
+	for rule, mode := range modes {
+		switch mode {
+		case "", PolicyModeWarn, PolicyModeEnforce:
+		default:
+			return fmt.Errorf("unknown %s mode %s, expected warn or enforce", rule, mode)
+		}
+	}
+
+	*p = policy
+	return nil
+}
+
+// ModeOf returns the mode of a rule.
+func (p *Policy) ModeOf(ruleMode PolicyMode) PolicyMode {
+	if ruleMode != "" {
+		return ruleMode
+	}
+	if p.Mode != "" {
+		return p.Mode
+	}
+	return PolicyModeEnforce
+}
@@ -0,0 +1,160 @@
+package conf
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/quickube/piper/pkg/utils"
+	"sigs.k8s.io/yaml"
+)
+
+// Route sends the workflows of the repos matching the Repo glob to a namespace, with a service account and the configs they may use.
+type Route struct {
+	Repo             string            `json:"repo"`
+	Namespace        string            `json:"namespace"`
+	ServiceAccount   string            `json:"serviceAccount"`
+	AllowedConfigs   []string          `json:"allowedConfigs"`
+	TriggerOverrides []TriggerOverride `json:"triggerOverrides"`
+}
+
+// TriggerOverride replaces the events, branches or config of the trigger of the same name in the repo triggers.yaml.
+type TriggerOverride struct {
+	Name     string   `json:"name"`
+	Events   []string `json:"events,omitempty"`
+	Branches []string `json:"branches,omitempty"`
+	Config   string   `json:"config,omitempty"`
+}
+
+// RepositoryRoutes holds the routes of the PiperRepository resources, by resource key. Their repo is an exact name.
+type RepositoryRoutes struct {
+	routes map[string]Route
+	mu     sync.RWMutex
+}
+
+// Routes is the routing table of ARGO_WORKFLOWS_ROUTES, given as a YAML or JSON list.
+type Routes []Route
+
+func (r *Routes) Decode(value string) error {
+	routes := make(Routes, 0)
+	err := yaml.Unmarshal([]byte(value), &routes)
+	if err != nil {
+		return fmt.Errorf("failed to parse routes, error: %v", err)
+	}
+
+	for i, route := range routes {
+		if route.Repo == "" {
+			return fmt.Errorf("route %d is missing the repo field", i)
+		}
+		if _, err = path.Match(route.Repo, ""); err != nil {
+			return fmt.Errorf("route %d has an invalid repo pattern %s, error: %v", i, route.Repo, err)
+		}
+	}
+
+	*r = routes
+	return nil
+}
+
+// RouteFor returns the PiperRepository route of the repo, or else the first route matching the repo.
+// Repos without a matching route go to ARGO_WORKFLOWS_NAMESPACE.
+func (cfg *WorkflowServerConfig) RouteFor(repo string) *Route {
+	if route, ok := cfg.RepositoryRoutes.Get(repo); ok {
+		if route.Namespace == "" {
+			route.Namespace = cfg.Namespace
+		}
+		return route
+	}
+
+	for _, route := range cfg.Routes {
+		if matched, _ := path.Match(route.Repo, repo); matched {
+			if route.Namespace == "" {
+				route.Namespace = cfg.Namespace
+			}
+			return &route
+		}
+	}
+
+	return &Route{Repo: repo, Namespace: cfg.Namespace}
+}
+
+// Namespaces returns ARGO_WORKFLOWS_NAMESPACE and the namespaces of all routes, including the PiperRepository routes.
+func (cfg *WorkflowServerConfig) Namespaces() []string {
+	namespaces := []string{cfg.Namespace}
+	for _, route := range append(cfg.RepositoryRoutes.List(), cfg.Routes...) {
+		if route.Namespace != "" && !utils.IsElementExists(namespaces, route.Namespace) {
+			namespaces = append(namespaces, route.Namespace)
+		}
+	}
+	return namespaces
+}
+
+func (r *Route) IsConfigAllowed(configName string) bool {
+	return len(r.AllowedConfigs) == 0 || utils.IsElementExists(r.AllowedConfigs, configName)
+}
+
+func NewRepositoryRoutes() *RepositoryRoutes {
+	return &RepositoryRoutes{
+		routes: make(map[string]Route),
+	}
+}
+
+// Claim sets the route of a resource, unless the repo is already declared by another resource, whose key is returned.
+func (r *RepositoryRoutes) Claim(key string, route Route) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for otherKey, otherRoute := range r.routes {
+		if otherKey != key && otherRoute.Repo == route.Repo {
+			return otherKey, false
+		}
+	}
+	r.routes[key] = route
+	return key, true
+}
+
+func (r *RepositoryRoutes) Delete(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.routes, key)
+}
+
+// ByKey returns the route of a resource.
+func (r *RepositoryRoutes) ByKey(key string) (*Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	route, ok := r.routes[key]
+	return &route, ok
+}
+
+// Get returns the route of the repo.
+func (r *RepositoryRoutes) Get(repo string) (*Route, bool) {
+	for _, route := range r.List() {
+		if route.Repo == repo {
+			return &route, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the routes sorted by key. It is safe to call on a nil RepositoryRoutes.
+func (r *RepositoryRoutes) List() []Route {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make([]Route, 0, len(r.routes))
+	for _, key := range r.sortedKeys() {
+		routes = append(routes, r.routes[key])
+	}
+	return routes
+}
+
+func (r *RepositoryRoutes) sortedKeys() []string {
+	keys := make([]string, 0, len(r.routes))
+	for key := range r.routes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,23 @@
+package conf
+
+import (
+	"fmt"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type TracingConfig struct {
+	// Exporter is none or otlp, the OTLP endpoint and headers are set with the OTEL_EXPORTER_OTLP_* environment variables.
+	Exporter    string  `envconfig:"TRACING_EXPORTER" default:"none"`
+	ServiceName string  `envconfig:"TRACING_SERVICE_NAME" default:"piper"`
+	SampleRatio float64 `envconfig:"TRACING_SAMPLE_RATIO" default:"1"`
+}
+
+func (cfg *TracingConfig) TracingConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the tracing configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package conf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+type WebhookConfig struct {
+	DeduplicationWindow time.Duration `envconfig:"WEBHOOK_DEDUPLICATION_WINDOW" default:"1h"`
+	QueueSize           int           `envconfig:"WEBHOOK_QUEUE_SIZE" default:"100"`
+	Workers             int           `envconfig:"WEBHOOK_WORKERS" default:"4"`
+	MaxRetries          int           `envconfig:"WEBHOOK_MAX_RETRIES" default:"3"`
+	RetryBackoff        time.Duration `envconfig:"WEBHOOK_RETRY_BACKOFF" default:"2s"`
+	DeadLetterSize      int           `envconfig:"WEBHOOK_DEAD_LETTER_SIZE" default:"100"`
+}
+
+func (cfg *WebhookConfig) WebhookConfLoad() error {
+	err := envconfig.Process("", cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the webhook configuration, error: %v", err)
+	}
+
+	return nil
+}
@@ -11,6 +11,9 @@ type WorkflowServerConfig struct {
 	CreateCRD   bool   `envconfig:"ARGO_WORKFLOWS_CREATE_CRD" default:"true"`
 	Namespace   string `envconfig:"ARGO_WORKFLOWS_NAMESPACE" default:"default"`
 	KubeConfig  string `envconfig:"KUBE_CONFIG" default:""`
+	Routes      Routes `envconfig:"ARGO_WORKFLOWS_ROUTES" default:""`
+	// RepositoryRoutes are set by the PiperRepository resources, and take precedence over Routes.
+	RepositoryRoutes *RepositoryRoutes `ignored:"true"`
 }
 
 func (cfg *WorkflowServerConfig) ArgoConfLoad() error {
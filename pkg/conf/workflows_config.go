@@ -1,47 +1,189 @@
 package conf
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/fsnotify/fsnotify"
 	"github.com/quickube/piper/pkg/utils"
 )
 
+// reloadDebounce coalesces the burst of events of a ConfigMap update into a single reload.
+const reloadDebounce = 500 * time.Millisecond
+
 type WorkflowsConfig struct {
 	Configs map[string]*ConfigInstance
+	// resourceConfigs are set by the PiperWorkflowConfig resources, and take precedence over Configs.
+	resourceConfigs map[string]*ConfigInstance
+	mu              sync.RWMutex
 }
 
 type ConfigInstance struct {
-	Spec   v1alpha1.WorkflowSpec `yaml:"spec"`
-	OnExit []v1alpha1.DAGTask    `yaml:"onExit"`
+	Spec                     v1alpha1.WorkflowSpec `yaml:"spec"`
+	OnExit                   []v1alpha1.DAGTask    `yaml:"onExit"`
+	WorkflowTemplates        []string              `yaml:"workflowTemplates"`
+	ClusterWorkflowTemplates []string              `yaml:"clusterWorkflowTemplates"`
 }
 
-func (wfc *WorkflowsConfig) WorkflowsSpecLoad(configPath string) error {
+// WorkflowsSpecLoad loads the configs at startup. Unlike reloads, a missing or unreadable configPath is an error.
+// logger may be nil, the default logger is used then.
+func (wfc *WorkflowsConfig) WorkflowsSpecLoad(ctx context.Context, configPath string, logger *slog.Logger) error {
+	logger = orDefaultLogger(logger)
+	configs, err := LoadConfigs(configPath)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		logger.WarnContext(ctx, "no config files to load", "path", configPath)
+	}
+	wfc.SetConfigs(configs)
+	return nil
+}
+
+// LoadConfigs parses and validates the configs at configPath, one config per file.
+// An existing empty directory has no configs, while a missing or unreadable one is an error.
+func LoadConfigs(configPath string) (map[string]*ConfigInstance, error) {
 	var jsonBytes []byte
-	wfc.Configs = make(map[string]*ConfigInstance)
+	loaded := make(map[string]*ConfigInstance)
 
 	configs, err := utils.GetFilesData(configPath)
-	if len(configs) == 0 {
-		log.Printf("No config files to load at %s", configPath)
-		return nil
-	}
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to read configs at %s, error: %w", configPath, err)
 	}
 
 	for key, config := range configs {
 		tmp := new(ConfigInstance)
 		jsonBytes, err = utils.ConvertYAMLToJSON(config)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to parse config %s, error: %v", key, err)
 		}
 		err = json.Unmarshal(jsonBytes, &tmp)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to parse config %s, error: %v", key, err)
+		}
+		err = tmp.Validate()
+		if err != nil {
+			return nil, fmt.Errorf("invalid config %s, error: %v", key, err)
 		}
-		wfc.Configs[key] = tmp
+		loaded[key] = tmp
 	}
 
+	return loaded, nil
+}
+
+// Validate checks that every onExit task has a name and references a template.
+func (ci *ConfigInstance) Validate() error {
+	for i, task := range ci.OnExit {
+		if task.Name == "" {
+			return fmt.Errorf("onExit task %d has no name", i)
+		}
+		if task.Template == "" && task.TemplateRef == nil {
+			return fmt.Errorf("onExit task %s has no template or templateRef", task.Name)
+		}
+	}
 	return nil
 }
+
+// GetConfig returns the config of the given name. The returned config is never modified, reloads replace it.
+func (wfc *WorkflowsConfig) GetConfig(name string) (*ConfigInstance, bool) {
+	wfc.mu.RLock()
+	defer wfc.mu.RUnlock()
+	if config, ok := wfc.resourceConfigs[name]; ok {
+		return config, true
+	}
+	config, ok := wfc.Configs[name]
+	return config, ok
+}
+
+// SetConfigs replaces the whole set of configs.
+func (wfc *WorkflowsConfig) SetConfigs(configs map[string]*ConfigInstance) {
+	wfc.mu.Lock()
+	defer wfc.mu.Unlock()
+	wfc.Configs = configs
+}
+
+// SetResourceConfig sets the config of a PiperWorkflowConfig resource.
+func (wfc *WorkflowsConfig) SetResourceConfig(name string, config *ConfigInstance) {
+	wfc.mu.Lock()
+	defer wfc.mu.Unlock()
+	if wfc.resourceConfigs == nil {
+		wfc.resourceConfigs = make(map[string]*ConfigInstance)
+	}
+	wfc.resourceConfigs[name] = config
+}
+
+func (wfc *WorkflowsConfig) DeleteResourceConfig(name string) {
+	wfc.mu.Lock()
+	defer wfc.mu.Unlock()
+	delete(wfc.resourceConfigs, name)
+}
+
+// WatchWorkflowsSpec reloads the configs whenever configPath changes, until ctx is done.
+// The parent directory is watched, as Kubernetes updates a mounted ConfigMap by swapping the ..data symlink.
+// If the new configs fail to load, the current configs are kept. logger may be nil, the default logger is used then.
+func (wfc *WorkflowsConfig) WatchWorkflowsSpec(ctx context.Context, configPath string, logger *slog.Logger) error {
+	logger = orDefaultLogger(logger)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher, error: %v", err)
+	}
+	err = watcher.Add(filepath.Dir(configPath))
+	if err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch %s, error: %v", filepath.Dir(configPath), err)
+	}
+	// Also catches edits of the files of a plain directory, outside Kubernetes
+	_ = watcher.Add(configPath)
+
+	go func() {
+		defer watcher.Close()
+		reload := time.NewTimer(reloadDebounce)
+		reload.Stop()
+		defer reload.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op == fsnotify.Chmod {
+					continue
+				}
+				reload.Reset(reloadDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.ErrorContext(ctx, "config watcher error", "error", err)
+			case <-reload.C:
+				configs, err := LoadConfigs(configPath)
+				if err != nil {
+					logger.ErrorContext(ctx, "failed to reload workflows configs, keeping the current configs", "error", err)
+					continue
+				}
+				wfc.SetConfigs(configs)
+				logger.InfoContext(ctx, "reloaded workflows configs", "configs", len(configs), "path", configPath)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// orDefaultLogger returns logger, or the default logger when it is nil. conf can't use the logging package, which
+// depends on it.
+func orDefaultLogger(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
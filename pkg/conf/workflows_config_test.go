@@ -0,0 +1,135 @@
+package conf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+// writeConfigMap mimics a ConfigMap volume update: the files are written to a new directory,
+// then the ..data symlink is swapped to it.
+func writeConfigMap(t *testing.T, dir string, version string, files map[string]string) {
+	t.Helper()
+	dataDir := filepath.Join(dir, "..data_"+version)
+	if err := os.Mkdir(dataDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dataDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tmpLink := filepath.Join(dir, "..data_tmp")
+	if err := os.Symlink(dataDir, tmpLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpLink, filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadConfigs(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		name          string
+		files         map[string]string
+		expectedNames []string
+		expectedError bool
+	}{
+		{
+			name: "Valid configs",
+			files: map[string]string{
+				"default": "spec:\n  serviceAccountName: argo-wf\nonExit:\n  - name: github-status\n    template: exit-handler\n",
+				"other":   "spec:\n  activeDeadlineSeconds: 60\n",
+			},
+			expectedNames: []string{"default", "other"},
+		},
+		{
+			name:          "Empty directory",
+			files:         map[string]string{},
+			expectedNames: []string{},
+		},
+		{
+			name:          "Invalid yaml",
+			files:         map[string]string{"default": "spec: [\n"},
+			expectedError: true,
+		},
+		{
+			name:          "OnExit task without template",
+			files:         map[string]string{"default": "onExit:\n  - name: github-status\n"},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeConfigMap(t, dir, "1", test.files)
+
+			configs, err := LoadConfigs(filepath.Join(dir, "..data"))
+			if test.expectedError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			names := make([]string, 0)
+			for name := range configs {
+				names = append(names, name)
+			}
+			assert.ElementsMatch(test.expectedNames, names)
+		})
+	}
+}
+
+func TestWatchWorkflowsSpec(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "..data")
+	writeConfigMap(t, dir, "1", map[string]string{"default": "spec:\n  serviceAccountName: v1\n"})
+
+	wfc := &WorkflowsConfig{}
+	assert.Nil(wfc.WorkflowsSpecLoad(ctx, configPath, nil))
+	assert.Nil(wfc.WatchWorkflowsSpec(ctx, configPath, nil))
+
+	serviceAccount := func() string {
+		config, ok := wfc.GetConfig("default")
+		if !ok {
+			return ""
+		}
+		return config.Spec.ServiceAccountName
+	}
+	assert.Equal("v1", serviceAccount())
+
+	writeConfigMap(t, dir, "2", map[string]string{"default": "spec:\n  serviceAccountName: v2\n"})
+	assert.Eventually(func() bool { return serviceAccount() == "v2" }, 5*time.Second, 50*time.Millisecond)
+
+	// Invalid configs are not swapped in
+	writeConfigMap(t, dir, "3", map[string]string{"default": "spec: [\n"})
+	time.Sleep(2 * reloadDebounce)
+	assert.Equal("v2", serviceAccount())
+
+	// Nor an empty set while the directory is briefly missing
+	assert.Nil(os.Remove(configPath))
+	time.Sleep(2 * reloadDebounce)
+	assert.Equal("v2", serviceAccount())
+
+	writeConfigMap(t, dir, "4", map[string]string{"default": "spec:\n  serviceAccountName: v4\n"})
+	assert.Eventually(func() bool { return serviceAccount() == "v4" }, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestWorkflowsSpecLoad_Missing(t *testing.T) {
+	assert := assertion.New(t)
+
+	wfc := &WorkflowsConfig{}
+	assert.NotNil(wfc.WorkflowsSpecLoad(context.Background(), filepath.Join(t.TempDir(), "..data"), nil))
+	_, ok := wfc.GetConfig("default")
+	assert.False(ok)
+}
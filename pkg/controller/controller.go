@@ -0,0 +1,462 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+type queueKey struct {
+	kind string
+	key  string
+}
+
+// ControllerImpl reconciles the PiperWorkflowConfig and PiperRepository resources into the workflows configs,
+// the repository routes and the repo webhooks.
+type ControllerImpl struct {
+	cfg          *conf.GlobalConfig
+	client       dynamic.Interface
+	webhooks     WebhookReconciler
+	factory      dynamicinformer.DynamicSharedInformerFactory
+	configs      cache.SharedIndexInformer
+	repositories cache.SharedIndexInformer
+	queue        workqueue.RateLimitingInterface
+	// configOwners maps each config name to the key of the PiperWorkflowConfig defining it.
+	configOwners map[string]string
+	mu           sync.Mutex
+	// leading is set on the leader replica, the only one writing the resources and the webhooks.
+	// The other replicas still apply the configs and routes, to serve the webhooks they receive.
+	leading atomic.Bool
+	logger  *slog.Logger
+}
+
+func NewController(cfg *conf.GlobalConfig, webhooks WebhookReconciler, logger *slog.Logger) (*ControllerImpl, error) {
+	restClientConfig, err := utils.GetClientConfig(cfg.WorkflowServerConfig.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dynamic.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newController(cfg, client, webhooks, logger), nil
+}
+
+func newController(cfg *conf.GlobalConfig, client dynamic.Interface, webhooks WebhookReconciler, logger *slog.Logger) *ControllerImpl {
+	if cfg.WorkflowServerConfig.RepositoryRoutes == nil {
+		cfg.WorkflowServerConfig.RepositoryRoutes = conf.NewRepositoryRoutes()
+	}
+
+	factory := dynamicinformer.NewFilteredDynamicSharedInformerFactory(client, cfg.ControllerConfig.ResyncPeriod, cfg.ControllerConfig.WatchNamespace, nil)
+	c := &ControllerImpl{
+		cfg:          cfg,
+		client:       client,
+		webhooks:     webhooks,
+		factory:      factory,
+		configs:      factory.ForResource(PiperWorkflowConfigResource).Informer(),
+		repositories: factory.ForResource(PiperRepositoryResource).Informer(),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "piper-controller"),
+		configOwners: make(map[string]string),
+		logger:       logger.With("component", "controller"),
+	}
+	c.configs.AddEventHandler(c.eventHandler(KindPiperWorkflowConfig))
+	c.repositories.AddEventHandler(c.eventHandler(KindPiperRepository))
+
+	return c
+}
+
+// Start syncs the resources and reconciles them until ctx is done. Every existing resource is reconciled on start,
+// and again every PIPER_CONTROLLER_RESYNC_PERIOD.
+func (c *ControllerImpl) Start(ctx context.Context) {
+	c.factory.Start(ctx.Done())
+
+	go func() {
+		defer c.queue.ShutDown()
+
+		c.logger.Info("waiting for the caches to sync")
+		if !cache.WaitForCacheSync(ctx.Done(), c.configs.HasSynced, c.repositories.HasSynced) {
+			c.logger.Error("failed to sync the caches")
+			return
+		}
+
+		workers := c.cfg.ControllerConfig.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		for i := 0; i < workers; i++ {
+			go wait.UntilWithContext(ctx, c.runWorker, time.Second)
+		}
+		c.logger.Info("started workers", "workers", workers)
+
+		<-ctx.Done()
+		c.logger.Info("context canceled, exiting")
+	}()
+}
+
+// Lead makes the replica write the resources and the webhooks until ctx is done. Every resource is requeued,
+// to catch up with the changes seen while following.
+func (c *ControllerImpl) Lead(ctx context.Context) {
+	c.leading.Store(true)
+	c.requeueAll()
+	c.logger.Info("leading")
+
+	go func() {
+		<-ctx.Done()
+		c.leading.Store(false)
+		c.logger.Info("stopped leading")
+	}()
+}
+
+func (c *ControllerImpl) requeueAll() {
+	for kind, informer := range map[string]cache.SharedIndexInformer{
+		KindPiperWorkflowConfig: c.configs,
+		KindPiperRepository:     c.repositories,
+	} {
+		for _, key := range informer.GetIndexer().ListKeys() {
+			c.queue.Add(queueKey{kind: kind, key: key})
+		}
+	}
+}
+
+func (c *ControllerImpl) eventHandler(kind string) cache.ResourceEventHandlerFuncs {
+	enqueue := func(obj interface{}) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			c.logger.Error("failed to get the key of resource", "kind", kind, "error", err)
+			return
+		}
+		c.queue.Add(queueKey{kind: kind, key: key})
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc:    enqueue,
+		UpdateFunc: func(_, newObj interface{}) { enqueue(newObj) },
+		DeleteFunc: enqueue,
+	}
+}
+
+func (c *ControllerImpl) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *ControllerImpl) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	key := item.(queueKey)
+	var err error
+	switch key.kind {
+	case KindPiperWorkflowConfig:
+		err = c.reconcileConfig(ctx, key.key)
+	case KindPiperRepository:
+		err = c.reconcileRepository(ctx, key.key)
+	}
+	if err != nil {
+		c.logger.ErrorContext(ctx, "failed to reconcile resource", "kind", key.kind, "key", key.key, "error", err)
+		c.queue.AddRateLimited(item)
+		return true
+	}
+
+	c.queue.Forget(item)
+	return true
+}
+
+func (c *ControllerImpl) reconcileConfig(ctx context.Context, key string) error {
+	obj, exists, err := c.configs.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		c.releaseConfig(key)
+		return nil
+	}
+
+	u := obj.(*unstructured.Unstructured)
+	resource := &PiperWorkflowConfig{}
+	status := &ResourceStatus{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), resource)
+	if err != nil {
+		setCondition(status, u.GetGeneration(), ConditionReady, metav1.ConditionFalse, "InvalidSpec", err.Error())
+		return c.updateStatus(ctx, PiperWorkflowConfigResource, u, status)
+	}
+	status = resource.Status.DeepCopy()
+
+	name := resource.GetName()
+	config := &conf.ConfigInstance{
+		Spec:                     resource.Spec.Spec,
+		OnExit:                   resource.Spec.OnExit,
+		WorkflowTemplates:        resource.Spec.WorkflowTemplates,
+		ClusterWorkflowTemplates: resource.Spec.ClusterWorkflowTemplates,
+	}
+	err = config.Validate()
+	if err != nil {
+		// The last valid spec, if any, stays in use
+		setCondition(status, resource.Generation, ConditionReady, metav1.ConditionFalse, "InvalidSpec", err.Error())
+		return c.updateStatus(ctx, PiperWorkflowConfigResource, u, status)
+	}
+
+	if owner, ok := c.claimConfig(name, key); !ok {
+		setCondition(status, resource.Generation, ConditionReady, metav1.ConditionFalse, "Conflict",
+			fmt.Sprintf("config %s is already defined by %s %s", name, KindPiperWorkflowConfig, owner))
+		return c.updateStatus(ctx, PiperWorkflowConfigResource, u, status)
+	}
+	c.cfg.WorkflowsConfig.SetResourceConfig(name, config)
+
+	setCondition(status, resource.Generation, ConditionReady, metav1.ConditionTrue, "Applied", fmt.Sprintf("config %s is available to triggers", name))
+	return c.updateStatus(ctx, PiperWorkflowConfigResource, u, status)
+}
+
+// claimConfig makes key the owner of the config name, unless another resource already owns it.
+func (c *ControllerImpl) claimConfig(name string, key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if owner, ok := c.configOwners[name]; ok && owner != key {
+		return owner, false
+	}
+	c.configOwners[name] = key
+	return key, true
+}
+
+// releaseConfig removes the config of a deleted resource, and requeues the resources defining the same config name.
+func (c *ControllerImpl) releaseConfig(key string) {
+	c.mu.Lock()
+	var released string
+	for name, owner := range c.configOwners {
+		if owner == key {
+			released = name
+			delete(c.configOwners, name)
+		}
+	}
+	c.mu.Unlock()
+	if released == "" {
+		return
+	}
+
+	c.cfg.WorkflowsConfig.DeleteResourceConfig(released)
+	for _, obj := range c.configs.GetIndexer().List() {
+		u := obj.(*unstructured.Unstructured)
+		if u.GetName() == released {
+			if otherKey, err := cache.MetaNamespaceKeyFunc(u); err == nil {
+				c.queue.Add(queueKey{kind: KindPiperWorkflowConfig, key: otherKey})
+			}
+		}
+	}
+}
+
+func (c *ControllerImpl) reconcileRepository(ctx context.Context, key string) error {
+	routes := c.cfg.WorkflowServerConfig.RepositoryRoutes
+	obj, exists, err := c.repositories.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		c.releaseRepository(key)
+		return nil
+	}
+
+	u := obj.(*unstructured.Unstructured)
+	resource := &PiperRepository{}
+	status := &ResourceStatus{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), resource)
+	if err != nil {
+		setCondition(status, u.GetGeneration(), ConditionReady, metav1.ConditionFalse, "InvalidSpec", err.Error())
+		return c.updateStatus(ctx, PiperRepositoryResource, u, status)
+	}
+	status = resource.Status.DeepCopy()
+	repo := resource.Spec.Repo
+
+	if resource.DeletionTimestamp != nil {
+		return c.finalizeRepository(ctx, key, u, resource)
+	}
+
+	if repo == "" {
+		setCondition(status, resource.Generation, ConditionReady, metav1.ConditionFalse, "InvalidSpec", "spec.repo is required")
+		return c.updateStatus(ctx, PiperRepositoryResource, u, status)
+	}
+	owner, ok := routes.Claim(key, conf.Route{
+		Repo:             repo,
+		Namespace:        resource.Spec.Namespace,
+		ServiceAccount:   resource.Spec.ServiceAccount,
+		AllowedConfigs:   resource.Spec.AllowedConfigs,
+		TriggerOverrides: resource.Spec.TriggerOverrides,
+	})
+	if !ok {
+		setCondition(status, resource.Generation, ConditionReady, metav1.ConditionFalse, "Conflict",
+			fmt.Sprintf("repo %s is already declared by %s %s", repo, KindPiperRepository, owner))
+		return c.updateStatus(ctx, PiperRepositoryResource, u, status)
+	}
+
+	if !c.leading.Load() {
+		return nil
+	}
+
+	if !utils.IsElementExists(u.GetFinalizers(), WEBHOOK_FINALIZER) {
+		withFinalizer := u.DeepCopy()
+		withFinalizer.SetFinalizers(append(withFinalizer.GetFinalizers(), WEBHOOK_FINALIZER))
+		u, err = c.client.Resource(PiperRepositoryResource).Namespace(u.GetNamespace()).Update(ctx, withFinalizer, metav1.UpdateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to add finalizer, error: %v", err)
+		}
+	}
+
+	err = c.reconcileWebhook(ctx, resource, status)
+	if err != nil {
+		setCondition(status, resource.Generation, ConditionWebhookReady, metav1.ConditionFalse, "WebhookFailed", err.Error())
+		setCondition(status, resource.Generation, ConditionReady, metav1.ConditionFalse, "WebhookFailed", "the repo webhook is not set")
+		if statusErr := c.updateStatus(ctx, PiperRepositoryResource, u, status); statusErr != nil {
+			c.logger.ErrorContext(ctx, "failed to update status of resource", "kind", KindPiperRepository, "key", key, "error", statusErr)
+		}
+		return err
+	}
+
+	setCondition(status, resource.Generation, ConditionReady, metav1.ConditionTrue, "Reconciled", fmt.Sprintf("repo %s is routed to Piper", repo))
+	return c.updateStatus(ctx, PiperRepositoryResource, u, status)
+}
+
+// reconcileWebhook sets the webhook of the repo, and deletes the webhook of the previous repo when spec.repo changed.
+// Org level webhooks already cover every repo.
+func (c *ControllerImpl) reconcileWebhook(ctx context.Context, resource *PiperRepository, status *ResourceStatus) error {
+	repo := resource.Spec.Repo
+	if c.cfg.GitProviderConfig.OrgLevelWebhook {
+		setCondition(status, resource.Generation, ConditionWebhookReady, metav1.ConditionTrue, "OrgLevelWebhook", "the repo is covered by the org level webhook")
+		return nil
+	}
+
+	if status.Repo != "" && status.Repo != repo {
+		err := c.webhooks.UnsetRepoWebhook(ctx, status.Repo, status.HookID)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook of previous repo %s, error: %v", status.Repo, err)
+		}
+		status.Repo = ""
+		status.HookID = 0
+	}
+
+	hook := c.webhooks.GetRepoWebhook(repo)
+	if hook == nil {
+		var err error
+		hook, err = c.webhooks.SetRepoWebhook(ctx, repo)
+		if err != nil {
+			return fmt.Errorf("failed to set webhook, error: %v", err)
+		}
+	}
+	status.Repo = repo
+	status.HookID = hook.HookID
+	setCondition(status, resource.Generation, ConditionWebhookReady, metav1.ConditionTrue, "WebhookSet", fmt.Sprintf("webhook %d is set", hook.HookID))
+	return nil
+}
+
+// finalizeRepository deletes the webhook and the route of a deleted PiperRepository, then removes its finalizer.
+func (c *ControllerImpl) finalizeRepository(ctx context.Context, key string, u *unstructured.Unstructured, resource *PiperRepository) error {
+	if !utils.IsElementExists(u.GetFinalizers(), WEBHOOK_FINALIZER) {
+		c.releaseRepository(key)
+		return nil
+	}
+
+	if !c.leading.Load() {
+		// The route is kept until the leader deletes the webhook
+		return nil
+	}
+
+	status := resource.Status.DeepCopy()
+	if status.Repo != "" && !c.cfg.GitProviderConfig.OrgLevelWebhook {
+		err := c.webhooks.UnsetRepoWebhook(ctx, status.Repo, status.HookID)
+		if err != nil {
+			setCondition(status, resource.Generation, ConditionWebhookReady, metav1.ConditionFalse, "DeleteFailed", err.Error())
+			if statusErr := c.updateStatus(ctx, PiperRepositoryResource, u, status); statusErr != nil {
+				c.logger.ErrorContext(ctx, "failed to update status of resource", "kind", KindPiperRepository, "key", key, "error", statusErr)
+			}
+			return fmt.Errorf("failed to delete webhook, error: %v", err)
+		}
+	}
+	c.releaseRepository(key)
+
+	finalizers := make([]string, 0)
+	for _, finalizer := range u.GetFinalizers() {
+		if finalizer != WEBHOOK_FINALIZER {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	withoutFinalizer := u.DeepCopy()
+	withoutFinalizer.SetFinalizers(finalizers)
+	_, err := c.client.Resource(PiperRepositoryResource).Namespace(u.GetNamespace()).Update(ctx, withoutFinalizer, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to remove finalizer, error: %v", err)
+	}
+	return nil
+}
+
+// releaseRepository removes the route of a deleted resource, and requeues the resources declaring the same repo.
+func (c *ControllerImpl) releaseRepository(key string) {
+	routes := c.cfg.WorkflowServerConfig.RepositoryRoutes
+	route, ok := routes.ByKey(key)
+	if !ok {
+		return
+	}
+	routes.Delete(key)
+	released := route.Repo
+
+	for _, obj := range c.repositories.GetIndexer().List() {
+		u := obj.(*unstructured.Unstructured)
+		repo, _, _ := unstructured.NestedString(u.Object, "spec", "repo")
+		if otherKey, err := cache.MetaNamespaceKeyFunc(u); err == nil && repo == released && otherKey != key {
+			c.queue.Add(queueKey{kind: KindPiperRepository, key: otherKey})
+		}
+	}
+}
+
+// updateStatus writes the status of the resource, when it changed and the replica is leading.
+func (c *ControllerImpl) updateStatus(ctx context.Context, resource schema.GroupVersionResource, u *unstructured.Unstructured, status *ResourceStatus) error {
+	if !c.leading.Load() {
+		return nil
+	}
+	statusMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	current, _, _ := unstructured.NestedMap(u.Object, "status")
+	if equality.Semantic.DeepEqual(current, statusMap) {
+		return nil
+	}
+
+	updated := u.DeepCopy()
+	updated.Object["status"] = statusMap
+	_, err = c.client.Resource(resource).Namespace(u.GetNamespace()).UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update status, error: %v", err)
+	}
+	return nil
+}
+
+func setCondition(status *ResourceStatus, generation int64, conditionType string, conditionStatus metav1.ConditionStatus, reason string, message string) {
+	status.ObservedGeneration = generation
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
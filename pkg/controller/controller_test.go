@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+)
+
+type fakeWebhooks struct {
+	mu     sync.Mutex
+	hooks  map[string]int64
+	nextID int64
+	unset  []string
+}
+
+func (f *fakeWebhooks) GetRepoWebhook(repo string) *git_provider.HookWithStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	hookID, ok := f.hooks[repo]
+	if !ok {
+		return nil
+	}
+	return &git_provider.HookWithStatus{HookID: hookID, RepoName: &repo, HealthStatus: true}
+}
+
+func (f *fakeWebhooks) SetRepoWebhook(ctx context.Context, repo string) (*git_provider.HookWithStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	f.hooks[repo] = f.nextID
+	return &git_provider.HookWithStatus{HookID: f.nextID, RepoName: &repo, HealthStatus: true}, nil
+}
+
+func (f *fakeWebhooks) UnsetRepoWebhook(ctx context.Context, repo string, hookID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.hooks, repo)
+	f.unset = append(f.unset, repo)
+	return nil
+}
+
+func newResource(kind string, namespace string, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	u.SetAPIVersion(Group + "/" + Version)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	u.SetGeneration(1)
+	return u
+}
+
+func getCondition(t *testing.T, client *fake.FakeDynamicClient, resource schema.GroupVersionResource, namespace string, name string) *metav1.Condition {
+	u, err := client.Resource(resource).Namespace(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil
+	}
+	status := &ResourceStatus{}
+	statusMap, _, _ := unstructured.NestedMap(u.Object, "status")
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(statusMap, status); err != nil {
+		t.Fatal(err)
+	}
+	return meta.FindStatusCondition(status.Conditions, ConditionReady)
+}
+
+func TestController(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			PiperWorkflowConfigResource: "PiperWorkflowConfigList",
+			PiperRepositoryResource:     "PiperRepositoryList",
+		},
+		newResource(KindPiperWorkflowConfig, "team-a", "team-a", map[string]interface{}{
+			"spec":   map[string]interface{}{"serviceAccountName": "team-a-workflows"},
+			"onExit": []interface{}{map[string]interface{}{"name": "github-status", "template": "exit-handler"}},
+		}),
+		newResource(KindPiperWorkflowConfig, "team-b", "broken", map[string]interface{}{
+			"onExit": []interface{}{map[string]interface{}{"name": "github-status"}},
+		}),
+		newResource(KindPiperRepository, "team-a", "my-repo", map[string]interface{}{
+			"repo":             "my-repo",
+			"namespace":        "team-a",
+			"allowedConfigs":   []interface{}{"team-a"},
+			"triggerOverrides": []interface{}{map[string]interface{}{"name": "build", "branches": []interface{}{"main"}}},
+		}),
+	)
+	webhooks := &fakeWebhooks{hooks: make(map[string]int64)}
+	cfg := &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+		ControllerConfig:     conf.ControllerConfig{Workers: 1},
+	}
+
+	controller := newController(cfg, client, webhooks, slog.Default())
+	controller.Start(ctx)
+	controller.Lead(ctx)
+
+	t.Run("Configs are applied", func(t *testing.T) {
+		assert.Eventually(func() bool {
+			config, ok := cfg.WorkflowsConfig.GetConfig("team-a")
+			return ok && config.Spec.ServiceAccountName == "team-a-workflows" && len(config.OnExit) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Eventually(func() bool {
+			condition := getCondition(t, client, PiperWorkflowConfigResource, "team-a", "team-a")
+			return condition != nil && condition.Status == metav1.ConditionTrue
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Invalid configs are reported", func(t *testing.T) {
+		assert.Eventually(func() bool {
+			condition := getCondition(t, client, PiperWorkflowConfigResource, "team-b", "broken")
+			return condition != nil && condition.Reason == "InvalidSpec"
+		}, 5*time.Second, 50*time.Millisecond)
+		_, ok := cfg.WorkflowsConfig.GetConfig("broken")
+		assert.False(ok)
+	})
+
+	t.Run("Repositories are routed and hooked", func(t *testing.T) {
+		assert.Eventually(func() bool {
+			condition := getCondition(t, client, PiperRepositoryResource, "team-a", "my-repo")
+			return condition != nil && condition.Status == metav1.ConditionTrue
+		}, 5*time.Second, 50*time.Millisecond)
+		route := cfg.WorkflowServerConfig.RouteFor("my-repo")
+		assert.Equal("team-a", route.Namespace)
+		assert.Equal([]string{"team-a"}, route.AllowedConfigs)
+		assert.Equal("build", route.TriggerOverrides[0].Name)
+		assert.Contains(cfg.WorkflowServerConfig.Namespaces(), "team-a")
+		assert.NotNil(webhooks.GetRepoWebhook("my-repo"))
+	})
+
+	t.Run("Duplicate repositories conflict", func(t *testing.T) {
+		_, err := client.Resource(PiperRepositoryResource).Namespace("team-b").Create(ctx, newResource(KindPiperRepository, "team-b", "my-repo", map[string]interface{}{
+			"repo":      "my-repo",
+			"namespace": "team-b",
+		}), metav1.CreateOptions{})
+		assert.Nil(err)
+		assert.Eventually(func() bool {
+			condition := getCondition(t, client, PiperRepositoryResource, "team-b", "my-repo")
+			return condition != nil && condition.Reason == "Conflict"
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Equal("team-a", cfg.WorkflowServerConfig.RouteFor("my-repo").Namespace)
+	})
+
+	t.Run("Deleted repositories release the webhook", func(t *testing.T) {
+		u, err := client.Resource(PiperRepositoryResource).Namespace("team-a").Get(ctx, "my-repo", metav1.GetOptions{})
+		assert.Nil(err)
+		assert.Equal([]string{WEBHOOK_FINALIZER}, u.GetFinalizers())
+
+		now := metav1.Now()
+		u.SetDeletionTimestamp(&now)
+		_, err = client.Resource(PiperRepositoryResource).Namespace("team-a").Update(ctx, u, metav1.UpdateOptions{})
+		assert.Nil(err)
+
+		assert.Eventually(func() bool {
+			u, err = client.Resource(PiperRepositoryResource).Namespace("team-a").Get(ctx, "my-repo", metav1.GetOptions{})
+			return err == nil && len(u.GetFinalizers()) == 0
+		}, 5*time.Second, 50*time.Millisecond)
+		webhooks.mu.Lock()
+		assert.Equal([]string{"my-repo"}, webhooks.unset)
+		webhooks.mu.Unlock()
+
+		// The conflicting repository takes over the repo
+		assert.Eventually(func() bool {
+			return cfg.WorkflowServerConfig.RouteFor("my-repo").Namespace == "team-b"
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
+
+func TestControllerFollower(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			PiperWorkflowConfigResource: "PiperWorkflowConfigList",
+			PiperRepositoryResource:     "PiperRepositoryList",
+		},
+		newResource(KindPiperRepository, "team-a", "my-repo", map[string]interface{}{
+			"repo":      "my-repo",
+			"namespace": "team-a",
+		}),
+	)
+	webhooks := &fakeWebhooks{hooks: make(map[string]int64)}
+	cfg := &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+		ControllerConfig:     conf.ControllerConfig{Workers: 1},
+	}
+
+	controller := newController(cfg, client, webhooks, slog.Default())
+	controller.Start(ctx)
+
+	// Followers route the webhooks they receive, without writing the resources or the webhooks
+	assert.Eventually(func() bool {
+		return cfg.WorkflowServerConfig.RouteFor("my-repo").Namespace == "team-a"
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Nil(webhooks.GetRepoWebhook("my-repo"))
+	assert.Nil(getCondition(t, client, PiperRepositoryResource, "team-a", "my-repo"))
+
+	leaderCtx, stopLeading := context.WithCancel(ctx)
+	defer stopLeading()
+	controller.Lead(leaderCtx)
+	assert.Eventually(func() bool {
+		condition := getCondition(t, client, PiperRepositoryResource, "team-a", "my-repo")
+		return condition != nil && condition.Status == metav1.ConditionTrue
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.NotNil(webhooks.GetRepoWebhook("my-repo"))
+}
@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+)
+
+const (
+	Group   = "piper.quickube.com"
+	Version = "v1alpha1"
+
+	KindPiperWorkflowConfig = "PiperWorkflowConfig"
+	KindPiperRepository     = "PiperRepository"
+
+	// WEBHOOK_FINALIZER keeps a PiperRepository until its webhook is deleted.
+	WEBHOOK_FINALIZER = "piper.quickube.com/webhook"
+
+	ConditionReady        = "Ready"
+	ConditionWebhookReady = "WebhookReady"
+)
+
+var (
+	PiperWorkflowConfigResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "piperworkflowconfigs"}
+	PiperRepositoryResource     = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "piperrepositories"}
+)
+
+// PiperWorkflowConfig is a workflows config, named after the resource. It takes precedence over the
+// config of the same name in the workflows ConfigMap.
+type PiperWorkflowConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PiperWorkflowConfigSpec `json:"spec"`
+	Status ResourceStatus          `json:"status,omitempty"`
+}
+
+type PiperWorkflowConfigSpec struct {
+	Spec                     v1alpha1.WorkflowSpec `json:"spec,omitempty"`
+	OnExit                   []v1alpha1.DAGTask    `json:"onExit,omitempty"`
+	WorkflowTemplates        []string              `json:"workflowTemplates,omitempty"`
+	ClusterWorkflowTemplates []string              `json:"clusterWorkflowTemplates,omitempty"`
+}
+
+// PiperRepository declares a repo to hook, with the namespace, service account and configs of its workflows,
+// and overrides of its triggers.
+type PiperRepository struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PiperRepositorySpec `json:"spec"`
+	Status ResourceStatus      `json:"status,omitempty"`
+}
+
+type PiperRepositorySpec struct {
+	Repo             string                 `json:"repo"`
+	Namespace        string                 `json:"namespace,omitempty"`
+	ServiceAccount   string                 `json:"serviceAccount,omitempty"`
+	AllowedConfigs   []string               `json:"allowedConfigs,omitempty"`
+	TriggerOverrides []conf.TriggerOverride `json:"triggerOverrides,omitempty"`
+}
+
+type ResourceStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Repo and HookID identify the webhook created for a PiperRepository.
+	Repo       string             `json:"repo,omitempty"`
+	HookID     int64              `json:"hookID,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// WebhookReconciler creates and deletes the webhooks of the PiperRepository resources.
+type WebhookReconciler interface {
+	GetRepoWebhook(repo string) *git_provider.HookWithStatus
+	SetRepoWebhook(ctx context.Context, repo string) (*git_provider.HookWithStatus, error)
+	UnsetRepoWebhook(ctx context.Context, repo string, hookID int64) error
+}
+
+type Controller interface {
+	Start(ctx context.Context)
+	Lead(ctx context.Context)
+}
+
+func (in *ResourceStatus) DeepCopy() *ResourceStatus {
+	out := *in
+	out.Conditions = append([]metav1.Condition(nil), in.Conditions...)
+	return &out
+}
@@ -6,45 +6,178 @@ import (
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
+	"github.com/quickube/piper/pkg/tracing"
 	"github.com/quickube/piper/pkg/utils"
+	"github.com/quickube/piper/pkg/workflow_handler"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
 )
 
 type eventNotifier struct {
 	cfg     *conf.GlobalConfig
 	clients *clients.Clients
+	metrics metrics.Recorder
+	logger  *slog.Logger
+	gitSink NotificationSink
+	sinks   map[string]NotificationSink
+	// commenter keeps the summary comment of the pull requests, when enabled.
+	commenter *pullRequestCommenter
 }
 
 func NewEventNotifier(cfg *conf.GlobalConfig, clients *clients.Clients) EventNotifier {
-	return &eventNotifier{
+	en := &eventNotifier{
 		cfg:     cfg,
 		clients: clients,
+		metrics: metrics.OrNoop(clients.Metrics),
+		logger:  logging.OrDefault(clients.Logger).With("component", "event_notifier"),
+		sinks:   make(map[string]NotificationSink),
 	}
+	if !cfg.NotificationsConfig.Notifications.DisableGitStatus {
+		en.gitSink = &gitStatusSink{clients: clients}
+	}
+	for i := range cfg.NotificationsConfig.Notifications.Sinks {
+		sinkConfig := &cfg.NotificationsConfig.Notifications.Sinks[i]
+		sink, err := NewNotificationSink(sinkConfig)
+		if err != nil {
+			en.logger.Error("skipping notification sink", "sink", sinkConfig.Name, "error", err)
+			continue
+		}
+		en.sinks[sinkConfig.Name] = sink
+	}
+	if cfg.NotificationsConfig.Notifications.PullRequestComment.Enabled {
+		en.commenter = newPullRequestCommenter(cfg, clients)
+	}
+	return en
 }
 
-func (en *eventNotifier) Notify(ctx context.Context, workflow *v1alpha1.Workflow) error {
-	fmt.Printf("Notifing workflow, %s\n", workflow.GetName())
+// Notify sets the commit status of the workflow, then sends it to the sinks of the matching rules.
+// Completed workflows of pull requests also update the summary comment of the pull request.
+// Only a commit status failure is returned, to retry it. The other sinks and the comment are sent at most once.
+// Each notification is a span linked to the span that submitted the workflow.
+func (en *eventNotifier) Notify(ctx context.Context, workflow *v1alpha1.Workflow) (err error) {
+	options := []trace.SpanStartOption{trace.WithAttributes(attribute.String("workflow", workflow.GetName()),
+		attribute.String("repo", workflow.GetLabels()["repo"]), attribute.String("phase", string(workflow.Status.Phase)))}
+	if link, ok := tracing.LinkFromAnnotations(workflow.GetAnnotations()); ok {
+		options = append(options, trace.WithLinks(link))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "Notify", options...)
+	defer func() { tracing.End(span, err) }()
+	en.logger.DebugContext(ctx, "notifying workflow", "phase", workflow.Status.Phase)
+
+	notification, err := en.newNotification(ctx, workflow)
+	if err != nil {
+		return err
+	}
+
+	if en.gitSink != nil {
+		err = en.gitSink.Send(ctx, notification)
+		en.metrics.NotificationSent("git", err)
+		if err != nil {
+			return fmt.Errorf("failed to set status for workflow %s: %s", workflow.GetName(), err)
+		}
+	}
+
+	en.notifySinks(ctx, notification)
+
+	if en.commenter != nil && workflow.Status.Fulfilled() {
+		err = en.commenter.Comment(ctx, workflow)
+		if err != nil {
+			en.logger.ErrorContext(ctx, "failed to comment workflow on its pull request", "error", err)
+		}
+	}
+	return nil
+}
 
+func (en *eventNotifier) newNotification(ctx context.Context, workflow *v1alpha1.Workflow) (*Notification, error) {
 	repo, ok := workflow.GetLabels()["repo"]
 	if !ok {
-		return fmt.Errorf("failed get repo label for workflow: %s", workflow.GetName())
+		return nil, fmt.Errorf("failed get repo label for workflow: %s", workflow.GetName())
 	}
 	commit, ok := workflow.GetLabels()["commit"]
 	if !ok {
-		return fmt.Errorf("failed get commit label for workflow: %s", workflow.GetName())
+		return nil, fmt.Errorf("failed get commit label for workflow: %s", workflow.GetName())
 	}
 
-	workflowLink := fmt.Sprintf("%s/workflows/%s/%s", en.cfg.WorkflowServerConfig.ArgoAddress, en.cfg.Namespace, workflow.GetName())
+	namespace := workflow.GetNamespace()
+	if namespace == "" {
+		namespace = en.cfg.Namespace
+	}
+	workflowLink := fmt.Sprintf("%s/workflows/%s/%s", en.cfg.WorkflowServerConfig.ArgoAddress, namespace, workflow.GetName())
 
 	status, err := en.clients.GitProvider.GetCorrelatingEvent(ctx, &workflow.Status.Phase)
 	if err != nil {
-		return fmt.Errorf("failed to translate workflow status for phase: %s status: %s", string(workflow.Status.Phase), status)
+		return nil, fmt.Errorf("failed to translate workflow status for phase: %s status: %s", string(workflow.Status.Phase), status)
 	}
 
 	message := utils.TrimString(workflow.Status.Message, 140) // Max length of message is 140 characters
-	err = en.clients.GitProvider.SetStatus(ctx, &repo, &commit, &workflowLink, &status, &message)
-	if err != nil {
-		return fmt.Errorf("failed to set status for workflow %s: %s", workflow.GetName(), err)
+	if warnings, ok := workflow.GetAnnotations()[workflow_handler.POLICY_WARNINGS_ANNOTATION]; ok && message == "" {
+		message = utils.TrimString("policy warnings: "+warnings, 140)
+	}
+	if supersededBy, ok := workflow.GetLabels()[workflow_handler.SUPERSEDED_BY_LABEL]; ok && workflow.Status.Fulfilled() {
+		message = fmt.Sprintf("cancelled — superseded by %s", supersededBy)
 	}
 
-	return nil
+	// The rules match the repo and branch as received, the labels of workflows submitted before the annotations are
+	// only used as a fallback
+	if rawRepo, ok := workflow.GetAnnotations()[workflow_handler.REPO_ANNOTATION]; ok {
+		repo = rawRepo
+	}
+	branch, ok := workflow.GetAnnotations()[workflow_handler.BRANCH_ANNOTATION]
+	if !ok {
+		branch = workflow.GetLabels()["branch"]
+	}
+
+	return &Notification{
+		Workflow:    workflow.GetName(),
+		Namespace:   namespace,
+		Repo:        repo,
+		Branch:      branch,
+		Commit:      commit,
+		User:        workflow.GetLabels()["user"],
+		Phase:       string(workflow.Status.Phase),
+		Status:      status,
+		Message:     message,
+		Link:        workflowLink,
+		FailedNodes: failedNodes(workflow),
+	}, nil
+}
+
+// notifySinks sends the notification to the sinks of the matching rules. A sink used by several matching rules
+// receives the notification once, with the template of the first rule.
+func (en *eventNotifier) notifySinks(ctx context.Context, notification *Notification) {
+	sent := make(map[string]bool)
+	for i := range en.cfg.NotificationsConfig.Notifications.Rules {
+		rule := &en.cfg.NotificationsConfig.Notifications.Rules[i]
+		if !isRuleMatch(rule, notification) {
+			continue
+		}
+
+		text := rule.Template
+		if text == "" {
+			text = defaultNotificationTemplate
+		}
+		rendered, err := utils.RenderTemplate(fmt.Sprintf("rule-%d", i), text, notification)
+		if err != nil {
+			en.logger.ErrorContext(ctx, "failed to render the template of notification rule", "rule", i, "error", err)
+			continue
+		}
+		ruleNotification := *notification
+		ruleNotification.Text = rendered
+
+		for _, name := range rule.Sinks {
+			sink, ok := en.sinks[name]
+			if !ok || sent[name] {
+				continue
+			}
+			sent[name] = true
+			err = sink.Send(ctx, &ruleNotification)
+			en.metrics.NotificationSent(name, err)
+			if err != nil {
+				en.logger.ErrorContext(ctx, "failed to send workflow to sink", "sink", name, "error", err)
+			}
+		}
+	}
 }
@@ -43,6 +43,10 @@ func (m *mockGitProvider) HandlePayload(ctx context.Context, request *http.Reque
 func (m *mockGitProvider) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
 	return nil
 }
+
+func (m *mockGitProvider) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
+	return nil
+}
 func (m *mockGitProvider) GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error) {
 	return "", nil
 }
@@ -50,6 +54,18 @@ func (m *mockGitProvider) PingHook(ctx context.Context, hook *git_provider.HookW
 	return nil
 }
 
+func (m *mockGitProvider) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockGitProvider) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	return nil
+}
+
+func (m *mockGitProvider) IsCollaborator(ctx context.Context, repo string, user string) (bool, error) {
+	return false, nil
+}
+
 func (m *mockGitProvider) GetHooks() []*git_provider.HookWithStatus {
 	return nil
 }
@@ -2,48 +2,30 @@ package event_handler
 
 import (
 	"context"
+
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"log"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
 )
 
-func Start(ctx context.Context, stop context.CancelFunc, cfg *conf.GlobalConfig, clients *clients.Clients) {
-	labelSelector := &metav1.LabelSelector{
-		MatchExpressions: []metav1.LabelSelectorRequirement{
-			{Key: "piper.quickube.com/notified",
-				Operator: metav1.LabelSelectorOpExists},
-		},
-	}
-	watcher, err := clients.Workflows.Watch(ctx, labelSelector)
-	if err != nil {
-		log.Printf("[event handler] Failed to watch workflow error:%s", err)
-		return
-	}
-
+// Start reports the workflows phases to the git provider until ctx is done. Watch failures are retried by the
+// informer, they never stop Piper.
+func Start(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients) {
+	logger := logging.OrDefault(clients.Logger).With("component", "event_handler")
 	notifier := NewEventNotifier(cfg, clients)
 	handler := &workflowEventHandler{
-		Clients:  clients,
-		Notifier: notifier,
+		Clients:      clients,
+		Notifier:     notifier,
+		Metrics:      metrics.OrNoop(clients.Metrics),
+		Logger:       logger,
+		StuckTimeout: cfg.EventHandlerConfig.StuckTimeout,
+	}
+	if !cfg.NotificationsConfig.Notifications.DisableGitStatus {
+		handler.NodeStatuses = newNodeStatusReporter(cfg, clients)
 	}
-	go func() {
 
-		for {
-			select {
-			case <-ctx.Done():
-				log.Print("[event handler] context canceled, exiting")
-				watcher.Stop()
-				return
-			case event, ok := <-watcher.ResultChan():
-				if !ok {
-					log.Print("[event handler] result channel closed")
-					stop()
-					return
-				}
-				if err2 := handler.Handle(ctx, &event); err2 != nil {
-					log.Printf("[event handler] failed to handle workflow event: %v", err2)
-				}
-			}
-		}
-	}()
+	informer := newWorkflowInformer(cfg, logger, workflowsListWatch(ctx, clients.Workflows), handler)
+	informer.Start(ctx)
+	logger.Info("event handler started")
 }
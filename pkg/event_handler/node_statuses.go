@@ -0,0 +1,167 @@
+package event_handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+const NODE_STATUS_PREFIX = "piper/"
+
+// nodeStatusReporter sets a commit status per top-level DAG task of the workflows created with nodeStatuses.
+// The reported statuses are kept in memory, so only the changed tasks are posted. A new leader posts them again.
+type nodeStatusReporter struct {
+	cfg     *conf.GlobalConfig
+	clients *clients.Clients
+	// reported maps each workflow key to the status reported for each of its tasks.
+	reported map[string]map[string]string
+	mu       sync.Mutex
+}
+
+func newNodeStatusReporter(cfg *conf.GlobalConfig, clients *clients.Clients) *nodeStatusReporter {
+	return &nodeStatusReporter{
+		cfg:      cfg,
+		clients:  clients,
+		reported: make(map[string]map[string]string),
+	}
+}
+
+func reportsNodeStatuses(workflow *v1alpha1.Workflow) bool {
+	return workflow.GetAnnotations()[workflow_handler.NODE_STATUSES_ANNOTATION] == "true"
+}
+
+// Report sets the commit status of every top-level DAG task whose status changed since the last report.
+func (r *nodeStatusReporter) Report(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	repo, ok := workflow.GetLabels()["repo"]
+	if !ok {
+		return fmt.Errorf("failed get repo label for workflow: %s", workflow.GetName())
+	}
+	commit, ok := workflow.GetLabels()["commit"]
+	if !ok {
+		return fmt.Errorf("failed get commit label for workflow: %s", workflow.GetName())
+	}
+	namespace := workflow.GetNamespace()
+	if namespace == "" {
+		namespace = r.cfg.Namespace
+	}
+	workflowLink := fmt.Sprintf("%s/workflows/%s/%s", r.cfg.WorkflowServerConfig.ArgoAddress, namespace, workflow.GetName())
+	key := namespace + "/" + workflow.GetName()
+
+	for _, node := range topLevelTasks(workflow) {
+		phase, ok := nodeWorkflowPhase(node.Phase)
+		if !ok {
+			continue
+		}
+		status, err := r.clients.GitProvider.GetCorrelatingEvent(ctx, &phase)
+		if err != nil {
+			return fmt.Errorf("failed to translate node status for phase: %s", node.Phase)
+		}
+		if r.reportedStatus(key, node.DisplayName) == status {
+			continue
+		}
+
+		name := NODE_STATUS_PREFIX + node.DisplayName
+		link := workflowLink + "?nodeId=" + node.ID
+		message := utils.TrimString(node.Message, 140)
+		if node.Phase == v1alpha1.NodeSkipped {
+			message = "skipped"
+		}
+		err = r.clients.GitProvider.SetNamedStatus(ctx, &repo, &commit, name, &link, &status, &message)
+		if err != nil {
+			return fmt.Errorf("failed to set status %s for workflow %s: %s", name, workflow.GetName(), err)
+		}
+		r.setReportedStatus(key, node.DisplayName, status)
+	}
+
+	return nil
+}
+
+// Forget drops the reported statuses of a completed workflow.
+func (r *nodeStatusReporter) Forget(workflow *v1alpha1.Workflow) {
+	namespace := workflow.GetNamespace()
+	if namespace == "" {
+		namespace = r.cfg.Namespace
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.reported, namespace+"/"+workflow.GetName())
+}
+
+func (r *nodeStatusReporter) reportedStatus(key string, task string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reported[key][task]
+}
+
+func (r *nodeStatusReporter) setReportedStatus(key string, task string, status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.reported[key]; !ok {
+		r.reported[key] = make(map[string]string)
+	}
+	r.reported[key][task] = status
+}
+
+// topLevelTasks returns the nodes of the tasks of the entrypoint DAG, sorted by name. The exit handler tasks,
+// the tasks of nested DAGs and the attempts of retried tasks are left out.
+func topLevelTasks(workflow *v1alpha1.Workflow) []v1alpha1.NodeStatus {
+	var root *v1alpha1.NodeStatus
+	for id := range workflow.Status.Nodes {
+		node := workflow.Status.Nodes[id]
+		if node.Name == workflow.GetName() {
+			root = &node
+			break
+		}
+	}
+	if root == nil || root.Type != v1alpha1.NodeTypeDAG {
+		return nil
+	}
+
+	attempts := make(map[string]bool)
+	for _, node := range workflow.Status.Nodes {
+		if node.Type == v1alpha1.NodeTypeRetry {
+			for _, child := range node.Children {
+				attempts[child] = true
+			}
+		}
+	}
+
+	tasks := make([]v1alpha1.NodeStatus, 0)
+	for _, node := range workflow.Status.Nodes {
+		if node.BoundaryID != root.ID || attempts[node.ID] {
+			continue
+		}
+		if node.Name != root.Name+"."+node.DisplayName {
+			continue
+		}
+		tasks = append(tasks, node)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].DisplayName < tasks[j].DisplayName })
+	return tasks
+}
+
+// nodeWorkflowPhase maps a node phase to the workflow phase of its commit status. Omitted tasks, which never run,
+// are not reported.
+func nodeWorkflowPhase(phase v1alpha1.NodePhase) (v1alpha1.WorkflowPhase, bool) {
+	switch phase {
+	case v1alpha1.NodePending:
+		return v1alpha1.WorkflowPending, true
+	case v1alpha1.NodeRunning:
+		return v1alpha1.WorkflowRunning, true
+	case v1alpha1.NodeSucceeded, v1alpha1.NodeSkipped:
+		return v1alpha1.WorkflowSucceeded, true
+	case v1alpha1.NodeFailed:
+		return v1alpha1.WorkflowFailed, true
+	case v1alpha1.NodeError:
+		return v1alpha1.WorkflowError, true
+	}
+	return "", false
+}
@@ -0,0 +1,78 @@
+package event_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+type statusRecorder struct {
+	mockGitProvider
+	statuses []string
+}
+
+func (s *statusRecorder) GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error) {
+	return string(*workflowEvent), nil
+}
+
+func (s *statusRecorder) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
+	s.statuses = append(s.statuses, name+"="+*status)
+	return nil
+}
+
+func TestNodeStatusReporter(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	workflow := &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-repo-main-abc",
+			Namespace:   "workflows",
+			Labels:      map[string]string{"repo": "my-repo", "commit": "1234567"},
+			Annotations: map[string]string{workflow_handler.NODE_STATUSES_ANNOTATION: "true"},
+		},
+		Status: v1alpha1.WorkflowStatus{
+			Phase: v1alpha1.WorkflowRunning,
+			Nodes: v1alpha1.Nodes{
+				"root":       {ID: "root", Name: "my-repo-main-abc", DisplayName: "my-repo-main-abc", Type: v1alpha1.NodeTypeDAG, Phase: v1alpha1.NodeRunning},
+				"lint":       {ID: "lint", Name: "my-repo-main-abc.lint", DisplayName: "lint", Type: v1alpha1.NodeTypePod, BoundaryID: "root", Phase: v1alpha1.NodeSucceeded},
+				"unit":       {ID: "unit", Name: "my-repo-main-abc.unit-tests", DisplayName: "unit-tests", Type: v1alpha1.NodeTypeRetry, BoundaryID: "root", Phase: v1alpha1.NodeRunning, Children: []string{"unit-0"}},
+				"unit-0":     {ID: "unit-0", Name: "my-repo-main-abc.unit-tests(0)", DisplayName: "unit-tests(0)", Type: v1alpha1.NodeTypePod, BoundaryID: "root", Phase: v1alpha1.NodeFailed},
+				"e2e":        {ID: "e2e", Name: "my-repo-main-abc.e2e", DisplayName: "e2e", Type: v1alpha1.NodeTypeDAG, BoundaryID: "root", Phase: v1alpha1.NodePending},
+				"e2e-setup":  {ID: "e2e-setup", Name: "my-repo-main-abc.e2e.setup", DisplayName: "setup", Type: v1alpha1.NodeTypePod, BoundaryID: "e2e", Phase: v1alpha1.NodePending},
+				"deploy":     {ID: "deploy", Name: "my-repo-main-abc.deploy", DisplayName: "deploy", Type: v1alpha1.NodeTypeSkipped, BoundaryID: "root", Phase: v1alpha1.NodeOmitted},
+				"exit":       {ID: "exit", Name: "my-repo-main-abc.onExit", DisplayName: "my-repo-main-abc.onExit", Type: v1alpha1.NodeTypeDAG, Phase: v1alpha1.NodeRunning},
+				"exit-notif": {ID: "exit-notif", Name: "my-repo-main-abc.onExit.notify", DisplayName: "notify", Type: v1alpha1.NodeTypePod, BoundaryID: "exit", Phase: v1alpha1.NodeRunning},
+			},
+		},
+	}
+
+	recorder := &statusRecorder{}
+	cfg := &conf.GlobalConfig{WorkflowServerConfig: conf.WorkflowServerConfig{ArgoAddress: "https://argo.example.com"}}
+	reporter := newNodeStatusReporter(cfg, &clients.Clients{GitProvider: recorder})
+
+	assert.True(reportsNodeStatuses(workflow))
+	assert.Nil(reporter.Report(ctx, workflow))
+	assert.Equal([]string{"piper/e2e=Pending", "piper/lint=Succeeded", "piper/unit-tests=Running"}, recorder.statuses)
+
+	// Unchanged tasks are not posted again
+	recorder.statuses = nil
+	unit := workflow.Status.Nodes["unit"]
+	unit.Phase = v1alpha1.NodeFailed
+	workflow.Status.Nodes["unit"] = unit
+	assert.Nil(reporter.Report(ctx, workflow))
+	assert.Equal([]string{"piper/unit-tests=Failed"}, recorder.statuses)
+
+	// Completed workflows are reported again after being forgotten
+	recorder.statuses = nil
+	reporter.Forget(workflow)
+	assert.Nil(reporter.Report(ctx, workflow))
+	assert.Len(recorder.statuses, 3)
+}
@@ -0,0 +1,71 @@
+package event_handler
+
+import (
+	"path"
+	"sort"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+const defaultNotificationTemplate = "${{ .Repo }}@${{ .Branch }}: workflow ${{ .Workflow }} ${{ .Phase | lower }}" +
+	"${{ if .FailedNodes }}, failed nodes: ${{ .FailedNodes | join \", \" }}${{ end }}" +
+	"${{ if .Message }} (${{ .Message }})${{ end }} ${{ .Link }}"
+
+// Notification is the data of a workflow phase given to the sinks and to the rules templates. Text is the
+// rendered template of the rule.
+type Notification struct {
+	Workflow    string   `json:"workflow"`
+	Namespace   string   `json:"namespace"`
+	Repo        string   `json:"repo"`
+	Branch      string   `json:"branch"`
+	Commit      string   `json:"commit"`
+	User        string   `json:"user"`
+	Phase       string   `json:"phase"`
+	Status      string   `json:"status"`
+	Message     string   `json:"message"`
+	Link        string   `json:"link"`
+	FailedNodes []string `json:"failedNodes"`
+	Text        string   `json:"text"`
+}
+
+// failedNodes returns the display names of the failed pods of the workflow.
+func failedNodes(workflow *v1alpha1.Workflow) []string {
+	names := make([]string, 0)
+	for _, node := range workflow.Status.Nodes {
+		if node.Type != v1alpha1.NodeTypePod {
+			continue
+		}
+		if node.Phase == v1alpha1.NodeFailed || node.Phase == v1alpha1.NodeError {
+			names = append(names, node.DisplayName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isRuleMatch reports whether the notification matches every filter set on the rule.
+func isRuleMatch(rule *conf.NotificationRule, notification *Notification) bool {
+	if len(rule.Repos) > 0 && !isPatternMatch(rule.Repos, notification.Repo) {
+		return false
+	}
+	if len(rule.Branches) > 0 && !isPatternMatch(rule.Branches, notification.Branch) {
+		return false
+	}
+	if len(rule.Phases) > 0 && !utils.IsElementExists(rule.Phases, notification.Phase) {
+		return false
+	}
+	return true
+}
+
+func isPatternMatch(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		// A lone * matches every value, like the trigger branches, while path.Match would not match a /
+		if matched, _ := path.Match(pattern, value); matched || pattern == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package event_handler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/utils"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+const PULL_REQUEST_COMMENT_MARKER = "<!-- piper:pull-request-summary -->"
+
+// pullRequestCommenter keeps one summary comment per pull request, listing the completed workflows of its head
+// commit. The comment ID is kept in memory and in the PULL_REQUEST_COMMENT_LABEL of the workflows, so the comment
+// is updated in place by re-runs and by a new leader.
+type pullRequestCommenter struct {
+	cfg     *conf.GlobalConfig
+	clients *clients.Clients
+	// comments maps each repo and pull request to the ID of its comment.
+	comments map[string]int64
+	mu       sync.Mutex
+}
+
+func newPullRequestCommenter(cfg *conf.GlobalConfig, clients *clients.Clients) *pullRequestCommenter {
+	return &pullRequestCommenter{
+		cfg:      cfg,
+		clients:  clients,
+		comments: make(map[string]int64),
+	}
+}
+
+// Comment creates or updates the summary comment of the pull request that triggered the completed workflow.
+// Workflows not triggered by a pull request are ignored.
+func (c *pullRequestCommenter) Comment(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	pullRequest, ok := workflow.GetLabels()[workflow_handler.PULL_REQUEST_LABEL]
+	if !ok {
+		return nil
+	}
+	pullRequestID, err := strconv.Atoi(pullRequest)
+	if err != nil {
+		return fmt.Errorf("invalid pull request label %s for workflow %s", pullRequest, workflow.GetName())
+	}
+	repo, ok := workflow.GetLabels()["repo"]
+	if !ok {
+		return fmt.Errorf("failed get repo label for workflow: %s", workflow.GetName())
+	}
+
+	workflowList, err := c.clients.Workflows.List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(&metav1.LabelSelector{
+			MatchLabels: map[string]string{"repo": repo, workflow_handler.PULL_REQUEST_LABEL: pullRequest},
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list workflows of pull request %d of repo %s, error: %v", pullRequestID, repo, err)
+	}
+	workflows := workflowList.Items
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := repo + "#" + pullRequest
+	commentID, ok := c.comments[key]
+	if !ok {
+		commentID = commentIDOf(workflows)
+	}
+	body := c.render(workflow, workflows)
+
+	if commentID != 0 {
+		err = c.clients.GitProvider.UpdateComment(ctx, repo, pullRequestID, commentID, body)
+		if err != nil {
+			logging.OrDefault(c.clients.Logger).WarnContext(ctx, "failed to update comment, creating a new one", "comment", commentID, "error", err)
+			commentID = 0
+		}
+	}
+	if commentID == 0 {
+		commentID, err = c.clients.GitProvider.CommentOnPullRequest(ctx, repo, pullRequestID, body)
+		if err != nil {
+			return err
+		}
+	}
+	c.comments[key] = commentID
+
+	if workflow.GetLabels()[workflow_handler.PULL_REQUEST_COMMENT_LABEL] != strconv.FormatInt(commentID, 10) {
+		err = c.clients.Workflows.UpdatePiperWorkflowLabel(ctx, workflow.GetNamespace(), workflow.GetName(), "pull-request-comment", strconv.FormatInt(commentID, 10))
+		if err != nil {
+			return fmt.Errorf("failed to label workflow %s with comment %d, error: %v", workflow.GetName(), commentID, err)
+		}
+	}
+	return nil
+}
+
+// commentIDOf returns the comment ID labeled on the most recent of the workflows, or 0.
+func commentIDOf(workflows []v1alpha1.Workflow) int64 {
+	var commentID int64
+	var created time.Time
+	for i := range workflows {
+		id, err := strconv.ParseInt(workflows[i].GetLabels()[workflow_handler.PULL_REQUEST_COMMENT_LABEL], 10, 64)
+		if err != nil {
+			continue
+		}
+		if commentID == 0 || workflows[i].CreationTimestamp.After(created) {
+			commentID = id
+			created = workflows[i].CreationTimestamp.Time
+		}
+	}
+	return commentID
+}
+
+// render writes the comment body: a section per completed workflow of the commit of workflow, with the phase,
+// duration and message of its top-level DAG tasks, and the output parameters matching the configured patterns.
+func (c *pullRequestCommenter) render(workflow *v1alpha1.Workflow, workflows []v1alpha1.Workflow) string {
+	commit := workflow.GetLabels()["commit"]
+	sections := []*v1alpha1.Workflow{workflow}
+	for i := range workflows {
+		other := &workflows[i]
+		if other.GetName() == workflow.GetName() || other.GetLabels()["commit"] != commit || !other.Status.Fulfilled() {
+			continue
+		}
+		sections = append(sections, other)
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].GetName() < sections[j].GetName() })
+
+	var body strings.Builder
+	body.WriteString(PULL_REQUEST_COMMENT_MARKER + "\n")
+	fmt.Fprintf(&body, "### Piper workflows of %s\n", commit)
+	for _, section := range sections {
+		c.renderWorkflow(&body, section)
+	}
+	return body.String()
+}
+
+func (c *pullRequestCommenter) renderWorkflow(body *strings.Builder, workflow *v1alpha1.Workflow) {
+	namespace := workflow.GetNamespace()
+	if namespace == "" {
+		namespace = c.cfg.Namespace
+	}
+	workflowLink := fmt.Sprintf("%s/workflows/%s/%s", c.cfg.WorkflowServerConfig.ArgoAddress, namespace, workflow.GetName())
+
+	fmt.Fprintf(body, "\n#### [%s](%s): %s\n", workflow.GetName(), workflowLink, workflow.Status.Phase)
+	if workflow.Status.Message != "" {
+		fmt.Fprintf(body, "\n%s\n", markdownCell(utils.TrimString(workflow.Status.Message, 140)))
+	}
+
+	tasks := topLevelTasks(workflow)
+	if len(tasks) == 0 {
+		return
+	}
+	body.WriteString("\n| Task | Phase | Duration | Message |\n|------|-------|----------|---------|\n")
+	outputs := make([]string, 0)
+	for _, task := range tasks {
+		fmt.Fprintf(body, "| %s | %s | %s | %s |\n", markdownCell(task.DisplayName), task.Phase, nodeDuration(task),
+			markdownCell(utils.TrimString(task.Message, 140)))
+		if task.Outputs == nil {
+			continue
+		}
+		for _, parameter := range task.Outputs.Parameters {
+			if parameter.Value == nil || !isPatternMatch(c.cfg.NotificationsConfig.Notifications.PullRequestComment.Outputs, parameter.Name) {
+				continue
+			}
+			outputs = append(outputs, fmt.Sprintf("| %s | %s | %s |\n", markdownCell(task.DisplayName),
+				markdownCell(parameter.Name), markdownCell(utils.TrimString(parameter.Value.String(), 140))))
+		}
+	}
+	if len(outputs) > 0 {
+		body.WriteString("\n| Task | Output | Value |\n|------|--------|-------|\n")
+		body.WriteString(strings.Join(outputs, ""))
+	}
+}
+
+// nodeDuration returns the run time of a finished node, rounded to seconds.
+func nodeDuration(node v1alpha1.NodeStatus) string {
+	if node.StartedAt.IsZero() || node.FinishedAt.IsZero() {
+		return ""
+	}
+	return node.FinishedAt.Sub(node.StartedAt.Time).Round(time.Second).String()
+}
+
+// markdownCell keeps a value on one line of a markdown table.
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "\n", " ")
+	return strings.ReplaceAll(value, "|", "\\|")
+}
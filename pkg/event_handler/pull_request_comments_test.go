@@ -0,0 +1,137 @@
+package event_handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+type commentRecorder struct {
+	mockGitProvider
+	created   []string
+	updated   map[int64]string
+	updateErr error
+}
+
+func (r *commentRecorder) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	r.created = append(r.created, body)
+	return int64(100 + len(r.created)), nil
+}
+
+func (r *commentRecorder) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.updated[commentID] = body
+	return nil
+}
+
+type pullRequestWorkflows struct {
+	workflow_handler.WorkflowsClient
+	workflows []v1alpha1.Workflow
+	labels    map[string]string
+}
+
+func (p *pullRequestWorkflows) List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error) {
+	return &v1alpha1.WorkflowList{Items: p.workflows}, nil
+}
+
+func (p *pullRequestWorkflows) UpdatePiperWorkflowLabel(ctx context.Context, namespace string, workflowName string, label string, value string) error {
+	p.labels[workflowName+"/"+label] = value
+	return nil
+}
+
+func pullRequestWorkflow(name string, commit string, phase v1alpha1.WorkflowPhase) *v1alpha1.Workflow {
+	started := metav1.NewTime(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(started.Add(90 * time.Second))
+	return &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "workflows",
+			Labels: map[string]string{
+				"repo":                              "my-repo",
+				"commit":                            commit,
+				workflow_handler.PULL_REQUEST_LABEL: "7",
+			},
+		},
+		Status: v1alpha1.WorkflowStatus{
+			Phase: phase,
+			Nodes: v1alpha1.Nodes{
+				"root": {ID: "root", Name: name, DisplayName: name, Type: v1alpha1.NodeTypeDAG, Phase: v1alpha1.NodeSucceeded},
+				"build": {ID: "build", Name: name + ".build", DisplayName: "build", Type: v1alpha1.NodeTypePod, BoundaryID: "root",
+					Phase: v1alpha1.NodeSucceeded, StartedAt: started, FinishedAt: finished,
+					Outputs: &v1alpha1.Outputs{Parameters: []v1alpha1.Parameter{
+						{Name: "image-tag", Value: v1alpha1.AnyStringPtr("v1.2.3")},
+						{Name: "digest", Value: v1alpha1.AnyStringPtr("sha256:abc")},
+					}}},
+				"test": {ID: "test", Name: name + ".test", DisplayName: "test", Type: v1alpha1.NodeTypePod, BoundaryID: "root",
+					Phase: v1alpha1.NodeFailed, Message: "exit code 1", StartedAt: started, FinishedAt: finished},
+			},
+		},
+	}
+}
+
+func TestPullRequestCommenter(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	cfg := &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{ArgoAddress: "https://argo.example.com"},
+		NotificationsConfig: conf.NotificationsConfig{Notifications: conf.Notifications{
+			PullRequestComment: conf.PullRequestComment{Enabled: true, Outputs: []string{"image-*"}},
+		}},
+	}
+	recorder := &commentRecorder{updated: make(map[int64]string)}
+	workflows := &pullRequestWorkflows{labels: make(map[string]string)}
+	commenter := newPullRequestCommenter(cfg, &clients.Clients{GitProvider: recorder, Workflows: workflows})
+
+	first := pullRequestWorkflow("my-repo-pr-a", "1234567", v1alpha1.WorkflowFailed)
+	assert.Nil(commenter.Comment(ctx, first))
+	assert.Len(recorder.created, 1)
+	assert.Equal("101", workflows.labels["my-repo-pr-a/pull-request-comment"])
+	body := recorder.created[0]
+	assert.Contains(body, PULL_REQUEST_COMMENT_MARKER)
+	assert.Contains(body, "#### [my-repo-pr-a](https://argo.example.com/workflows/workflows/my-repo-pr-a): Failed")
+	assert.Contains(body, "| build | Succeeded | 1m30s |  |")
+	assert.Contains(body, "| test | Failed | 1m30s | exit code 1 |")
+	assert.Contains(body, "| build | image-tag | v1.2.3 |")
+	assert.NotContains(body, "digest")
+
+	// A re-run of the same commit updates the comment with both workflows
+	first.Labels[workflow_handler.PULL_REQUEST_COMMENT_LABEL] = "101"
+	workflows.workflows = []v1alpha1.Workflow{*first}
+	second := pullRequestWorkflow("my-repo-pr-b", "1234567", v1alpha1.WorkflowSucceeded)
+	assert.Nil(commenter.Comment(ctx, second))
+	assert.Len(recorder.created, 1)
+	assert.Contains(recorder.updated[101], "my-repo-pr-a")
+	assert.Contains(recorder.updated[101], "my-repo-pr-b")
+
+	// A new commit replaces the workflows of the previous one, the comment ID is found on the workflows labels
+	commenter = newPullRequestCommenter(cfg, &clients.Clients{GitProvider: recorder, Workflows: workflows})
+	third := pullRequestWorkflow("my-repo-pr-c", "89abcde", v1alpha1.WorkflowSucceeded)
+	assert.Nil(commenter.Comment(ctx, third))
+	assert.Len(recorder.created, 1)
+	assert.Contains(recorder.updated[101], "my-repo-pr-c")
+	assert.NotContains(recorder.updated[101], "my-repo-pr-a")
+
+	// A deleted comment is created again
+	recorder.updateErr = errors.New("not found")
+	assert.Nil(commenter.Comment(ctx, third))
+	assert.Len(recorder.created, 2)
+	assert.Equal("102", workflows.labels["my-repo-pr-c/pull-request-comment"])
+
+	// Workflows not triggered by a pull request are ignored
+	push := pullRequestWorkflow("my-repo-main-d", "89abcde", v1alpha1.WorkflowSucceeded)
+	delete(push.Labels, workflow_handler.PULL_REQUEST_LABEL)
+	assert.Nil(commenter.Comment(ctx, push))
+	assert.Len(recorder.created, 2)
+}
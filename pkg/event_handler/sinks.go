@@ -0,0 +1,110 @@
+package event_handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+)
+
+const (
+	SIGNATURE_HEADER = "X-Piper-Signature-256"
+	sinkTimeout      = 10 * time.Second
+)
+
+func NewNotificationSink(sink *conf.NotificationSink) (NotificationSink, error) {
+	client := &http.Client{Timeout: sinkTimeout}
+	switch sink.Type {
+	case conf.SinkTypeSlack:
+		return &slackSink{url: sink.URL, client: client}, nil
+	case conf.SinkTypeWebhook:
+		return &webhookSink{url: sink.URL, secret: []byte(sink.Secret), headers: sink.Headers, client: client}, nil
+	}
+	return nil, fmt.Errorf("unknown notification sink type %s", sink.Type)
+}
+
+// gitStatusSink sets the commit status of the workflow.
+type gitStatusSink struct {
+	clients *clients.Clients
+}
+
+func (s *gitStatusSink) Send(ctx context.Context, notification *Notification) error {
+	return s.clients.GitProvider.SetStatus(ctx, &notification.Repo, &notification.Commit, &notification.Link, &notification.Status, &notification.Message)
+}
+
+// slackSink posts the text of the notification to a Slack incoming webhook.
+type slackSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *slackSink) Send(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(map[string]string{"text": notification.Text})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.client, s.url, body, nil)
+}
+
+// webhookSink posts the notification as JSON. With a secret, the body is signed with HMAC-SHA256, hex encoded
+// in the X-Piper-Signature-256 header as sha256=<signature>.
+type webhookSink struct {
+	url     string
+	secret  []byte
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *webhookSink) Send(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	headers := make(map[string]string, len(s.headers)+1)
+	for k, v := range s.headers {
+		headers[k] = v
+	}
+	if len(s.secret) > 0 {
+		headers[SIGNATURE_HEADER] = "sha256=" + Sign(s.secret, body)
+	}
+	return post(ctx, s.client, s.url, body, headers)
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, respBody)
+	}
+	return nil
+}
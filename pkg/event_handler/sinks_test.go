@@ -0,0 +1,163 @@
+package event_handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	r := &receiver{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		r.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func TestWebhookSinkSignature(t *testing.T) {
+	assert := assertion.New(t)
+	r, server := newReceiver(t)
+
+	sink, err := NewNotificationSink(&conf.NotificationSink{
+		Name:    "tracker",
+		Type:    conf.SinkTypeWebhook,
+		URL:     server.URL,
+		Secret:  "s3cr3t",
+		Headers: map[string]string{"X-Team": "platform"},
+	})
+	assert.Nil(err)
+	err = sink.Send(context.Background(), &Notification{Workflow: "release-abc", Phase: "Succeeded", Text: "done"})
+	assert.Nil(err)
+
+	requests := r.received()
+	assert.Len(requests, 1)
+	assert.Equal("sha256="+Sign([]byte("s3cr3t"), requests[0].body), requests[0].header.Get(SIGNATURE_HEADER))
+	assert.Equal("platform", requests[0].header.Get("X-Team"))
+	notification := &Notification{}
+	assert.Nil(json.Unmarshal(requests[0].body, notification))
+	assert.Equal("release-abc", notification.Workflow)
+	assert.Equal("done", notification.Text)
+}
+
+func TestNotifySinks(t *testing.T) {
+	assert := assertion.New(t)
+	slack, slackServer := newReceiver(t)
+	tracker, trackerServer := newReceiver(t)
+
+	cfg := &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{ArgoAddress: "https://argo.example.com", Namespace: "workflows"},
+		NotificationsConfig: conf.NotificationsConfig{Notifications: conf.Notifications{
+			DisableGitStatus: true,
+			Sinks: []conf.NotificationSink{
+				{Name: "slack", Type: conf.SinkTypeSlack, URL: slackServer.URL},
+				{Name: "tracker", Type: conf.SinkTypeWebhook, URL: trackerServer.URL},
+			},
+			Rules: []conf.NotificationRule{
+				{Sinks: []string{"slack"}, Branches: []string{"main"}, Phases: []string{"Failed", "Error"}},
+				{Sinks: []string{"tracker"}, Repos: []string{"release-*"}, Phases: []string{"Succeeded"}, Template: "released ${{ .Commit }}"},
+				{Sinks: []string{"tracker"}, Branches: []string{"release/*"}, Phases: []string{"Running"}, Template: "releasing ${{ .Branch }}"},
+			},
+		}},
+	}
+	notifier := NewEventNotifier(cfg, &clients.Clients{GitProvider: &mockGitProvider{}})
+
+	newWorkflow := func(repo string, branch string, phase v1alpha1.WorkflowPhase) *v1alpha1.Workflow {
+		return &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      repo + "-abc",
+				Namespace: "workflows",
+				Labels:    map[string]string{"repo": repo, "branch": branch, "commit": "1234567"},
+			},
+			Status: v1alpha1.WorkflowStatus{
+				Phase: phase,
+				Nodes: v1alpha1.Nodes{
+					"dag":  {DisplayName: "my-repo-abc", Type: v1alpha1.NodeTypeDAG, Phase: v1alpha1.NodeFailed},
+					"test": {DisplayName: "test", Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeFailed},
+					"lint": {DisplayName: "lint", Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeError},
+					"make": {DisplayName: "build", Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeSucceeded},
+				},
+			},
+		}
+	}
+
+	ctx := context.Background()
+	assert.Nil(notifier.Notify(ctx, newWorkflow("my-repo", "feature", v1alpha1.WorkflowFailed)))
+	assert.Nil(notifier.Notify(ctx, newWorkflow("my-repo", "main", v1alpha1.WorkflowFailed)))
+	assert.Nil(notifier.Notify(ctx, newWorkflow("release-tool", "main", v1alpha1.WorkflowSucceeded)))
+	// The rules match the branch as received, not its sanitized label
+	releasing := newWorkflow("my-repo", "release1.0", v1alpha1.WorkflowRunning)
+	releasing.Annotations = map[string]string{workflow_handler.REPO_ANNOTATION: "my-repo", workflow_handler.BRANCH_ANNOTATION: "release/1.0"}
+	assert.Nil(notifier.Notify(ctx, releasing))
+
+	slackRequests := slack.received()
+	assert.Len(slackRequests, 1)
+	message := map[string]string{}
+	assert.Nil(json.Unmarshal(slackRequests[0].body, &message))
+	assert.Equal("my-repo@main: workflow my-repo-abc failed, failed nodes: lint, test https://argo.example.com/workflows/workflows/my-repo-abc", message["text"])
+
+	trackerRequests := tracker.received()
+	assert.Len(trackerRequests, 2)
+	notification := &Notification{}
+	assert.Nil(json.Unmarshal(trackerRequests[0].body, notification))
+	assert.Equal("released 1234567", notification.Text)
+	assert.Equal("release-tool", notification.Repo)
+	assert.Empty(trackerRequests[0].header.Get(SIGNATURE_HEADER))
+	assert.Nil(json.Unmarshal(trackerRequests[1].body, notification))
+	assert.Equal("releasing release/1.0", notification.Text)
+}
+
+func TestIsRuleMatch(t *testing.T) {
+	assert := assertion.New(t)
+	notification := &Notification{Repo: "my-repo", Branch: "feature/new-ui", Phase: "Failed"}
+
+	tests := []struct {
+		name     string
+		rule     conf.NotificationRule
+		expected bool
+	}{
+		{name: "No filters", rule: conf.NotificationRule{}, expected: true},
+		{name: "Any branch", rule: conf.NotificationRule{Branches: []string{"*"}}, expected: true},
+		{name: "Branch prefix", rule: conf.NotificationRule{Branches: []string{"feature/*"}}, expected: true},
+		{name: "Sanitized branch", rule: conf.NotificationRule{Branches: []string{"featurenew-ui"}}, expected: false},
+		{name: "Other repo", rule: conf.NotificationRule{Repos: []string{"release-*"}}, expected: false},
+		{name: "Other phase", rule: conf.NotificationRule{Phases: []string{"Succeeded"}}, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(test.expected, isRuleMatch(&test.rule, notification))
+		})
+	}
+}
@@ -3,13 +3,19 @@ package event_handler
 import (
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"golang.org/x/net/context"
-	"k8s.io/apimachinery/pkg/watch"
 )
 
 type EventHandler interface {
-	Handle(ctx context.Context, event *watch.Event) error
+	Handle(ctx context.Context, workflow *v1alpha1.Workflow) error
+	// HandleDeleted reports a workflow deleted before its final phase was notified.
+	HandleDeleted(ctx context.Context, workflow *v1alpha1.Workflow) error
 }
 
 type EventNotifier interface {
 	Notify(ctx context.Context, workflow *v1alpha1.Workflow) error
 }
+
+// NotificationSink delivers the notification of a workflow phase.
+type NotificationSink interface {
+	Send(ctx context.Context, notification *Notification) error
+}
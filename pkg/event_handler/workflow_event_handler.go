@@ -4,39 +4,49 @@ import (
 	"fmt"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
 	"golang.org/x/net/context"
-	"k8s.io/apimachinery/pkg/watch"
-	"log"
+	"log/slog"
+	"time"
 )
 
+const DELETED_MESSAGE = "workflow was deleted before completion"
+
 type workflowEventHandler struct {
-	Clients  *clients.Clients
-	Notifier EventNotifier
+	Clients      *clients.Clients
+	Notifier     EventNotifier
+	NodeStatuses *nodeStatusReporter
+	Metrics      metrics.Recorder
+	// Logger may be nil, the default logger is used then.
+	Logger *slog.Logger
+	// StuckTimeout reports the workflows pending or running for longer as errors, 0 disables it.
+	StuckTimeout time.Duration
 }
 
-func (weh *workflowEventHandler) Handle(ctx context.Context, event *watch.Event) error {
-	workflow, ok := event.Object.(*v1alpha1.Workflow)
+func (weh *workflowEventHandler) Handle(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	currentPiperNotifyLabelStatus, ok := workflow.GetLabels()[NOTIFIED_LABEL]
 	if !ok {
 		return fmt.Errorf(
-			"event object is not a Workflow object, got: %v\n",
-			event.DeepCopy().Object,
+			"workflow %s missing %s label\n",
+			workflow.GetName(),
+			NOTIFIED_LABEL,
 		)
 	}
 
-	currentPiperNotifyLabelStatus, ok := workflow.GetLabels()["piper.quickube.com/notified"]
-	if !ok {
-		return fmt.Errorf(
-			"workflow %s missing piper.quickube.com/notified label\n",
-			workflow.GetName(),
-		)
+	if weh.NodeStatuses != nil && reportsNodeStatuses(workflow) &&
+		(currentPiperNotifyLabelStatus != string(workflow.Status.Phase) || !workflow.Status.Fulfilled()) {
+		err := weh.NodeStatuses.Report(ctx, workflow)
+		if err != nil {
+			return fmt.Errorf("failed to report node statuses of workflow %s, error: %v", workflow.GetName(), err)
+		}
 	}
 
 	if currentPiperNotifyLabelStatus == string(workflow.Status.Phase) {
-		log.Printf(
-			"workflow %s already informed for %s status. skiping... \n",
-			workflow.GetName(),
-			workflow.Status.Phase,
-		) //INFO
+		if isStuck(workflow, weh.StuckTimeout, time.Now()) {
+			return weh.handleStuck(ctx, workflow)
+		}
+		logging.OrDefault(weh.Logger).DebugContext(ctx, "workflow phase already notified, skipping", "phase", workflow.Status.Phase)
 		return nil
 	}
 
@@ -45,16 +55,94 @@ func (weh *workflowEventHandler) Handle(ctx context.Context, event *watch.Event)
 		return fmt.Errorf("failed to Notify workflow to git provider, error:%s\n", err)
 	}
 
-	err = weh.Clients.Workflows.UpdatePiperWorkflowLabel(ctx, workflow.GetName(), "notified", string(workflow.Status.Phase))
+	err = weh.Clients.Workflows.UpdatePiperWorkflowLabel(ctx, workflow.GetNamespace(), workflow.GetName(), "notified", string(workflow.Status.Phase))
 	if err != nil {
 		return fmt.Errorf("error in workflow %s status patch: %s", workflow.GetName(), err)
 	}
-	log.Printf(
-		"[event handler] done with event of type: %s for worklfow: %s phase: %s message: %s\n",
-		event.Type,
-		workflow.GetName(),
-		workflow.Status.Phase,
-		workflow.Status.Message) //INFO
+	if weh.NodeStatuses != nil && workflow.Status.Fulfilled() {
+		weh.NodeStatuses.Forget(workflow)
+	}
+	if changed := phaseChangedAt(workflow); !changed.IsZero() {
+		metrics.OrNoop(weh.Metrics).EventHandlerLag(time.Since(changed))
+	}
+	logging.OrDefault(weh.Logger).InfoContext(ctx, "workflow phase notified", "phase", workflow.Status.Phase, "message", workflow.Status.Message)
+
+	return nil
+}
+
+// HandleDeleted notifies the final phase of a completed workflow, or an error for a workflow deleted before
+// completion, with its unfinished tasks. The notified label is not updated, as the workflow is gone.
+func (weh *workflowEventHandler) HandleDeleted(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	if !workflow.Status.Fulfilled() {
+		workflow.Status.Phase = v1alpha1.WorkflowError
+		workflow.Status.Message = DELETED_MESSAGE
+		for id, node := range workflow.Status.Nodes {
+			if !node.Fulfilled() {
+				node.Phase = v1alpha1.NodeError
+				node.Message = DELETED_MESSAGE
+				workflow.Status.Nodes[id] = node
+			}
+		}
+	}
+
+	if weh.NodeStatuses != nil && reportsNodeStatuses(workflow) {
+		err := weh.NodeStatuses.Report(ctx, workflow)
+		if err != nil {
+			return fmt.Errorf("failed to report node statuses of deleted workflow %s, error: %v", workflow.GetName(), err)
+		}
+	}
+
+	err := weh.Notifier.Notify(ctx, workflow)
+	if err != nil {
+		return fmt.Errorf("failed to Notify deleted workflow to git provider, error:%s\n", err)
+	}
+	if weh.NodeStatuses != nil {
+		weh.NodeStatuses.Forget(workflow)
+	}
+	logging.OrDefault(weh.Logger).InfoContext(ctx, "deleted workflow notified", "phase", workflow.Status.Phase)
+	return nil
+}
 
+// handleStuck notifies an error for a workflow pending or running for longer than StuckTimeout. The stuck label
+// keeps the reported phase, so the workflow is reported once per phase. Its next phase is notified as usual.
+func (weh *workflowEventHandler) handleStuck(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	phase := workflow.Status.Phase
+	stuck := workflow.DeepCopy()
+	stuck.Status.Phase = v1alpha1.WorkflowError
+	stuck.Status.Message = fmt.Sprintf("workflow stuck in %s for more than %s", phase, weh.StuckTimeout)
+
+	err := weh.Notifier.Notify(ctx, stuck)
+	if err != nil {
+		return fmt.Errorf("failed to Notify stuck workflow to git provider, error:%s\n", err)
+	}
+	err = weh.Clients.Workflows.UpdatePiperWorkflowLabel(ctx, workflow.GetNamespace(), workflow.GetName(), "stuck", string(phase))
+	if err != nil {
+		return fmt.Errorf("error in workflow %s stuck patch: %s", workflow.GetName(), err)
+	}
+	logging.OrDefault(weh.Logger).WarnContext(ctx, "stuck workflow notified", "phase", phase, "timeout", weh.StuckTimeout)
 	return nil
 }
+
+// phaseChangedAt approximates when the workflow reached its phase, from its finish, start or creation time.
+func phaseChangedAt(workflow *v1alpha1.Workflow) time.Time {
+	if workflow.Status.Fulfilled() && !workflow.Status.FinishedAt.IsZero() {
+		return workflow.Status.FinishedAt.Time
+	}
+	if workflow.Status.Phase == v1alpha1.WorkflowRunning && !workflow.Status.StartedAt.IsZero() {
+		return workflow.Status.StartedAt.Time
+	}
+	return workflow.GetCreationTimestamp().Time
+}
+
+// isStuck reports whether the workflow is pending or running for longer than timeout, since it started or was
+// created, and was not reported stuck in its phase yet.
+func isStuck(workflow *v1alpha1.Workflow, timeout time.Duration, now time.Time) bool {
+	if timeout <= 0 || workflow.Status.Fulfilled() || workflow.GetLabels()[STUCK_LABEL] == string(workflow.Status.Phase) {
+		return false
+	}
+	started := workflow.Status.StartedAt.Time
+	if started.IsZero() {
+		started = workflow.GetCreationTimestamp().Time
+	}
+	return !started.IsZero() && now.Sub(started) > timeout
+}
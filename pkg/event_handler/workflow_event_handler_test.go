@@ -0,0 +1,88 @@
+package event_handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/clients"
+)
+
+type notificationRecorder struct {
+	notified []*v1alpha1.Workflow
+}
+
+func (n *notificationRecorder) Notify(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	n.notified = append(n.notified, workflow.DeepCopy())
+	return nil
+}
+
+func TestHandleDeleted(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		workflow        *v1alpha1.Workflow
+		expectedPhase   v1alpha1.WorkflowPhase
+		expectedMessage string
+	}{
+		{
+			name:            "Running workflow",
+			workflow:        newWorkflow("running", "1", "Running", v1alpha1.WorkflowRunning),
+			expectedPhase:   v1alpha1.WorkflowError,
+			expectedMessage: DELETED_MESSAGE,
+		},
+		{
+			name:          "Completed workflow not notified",
+			workflow:      newWorkflow("succeeded", "1", "Running", v1alpha1.WorkflowSucceeded),
+			expectedPhase: v1alpha1.WorkflowSucceeded,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			notifier := &notificationRecorder{}
+			handler := &workflowEventHandler{Notifier: notifier}
+			assert.Nil(handler.HandleDeleted(ctx, test.workflow))
+			assert.Len(notifier.notified, 1)
+			assert.Equal(test.expectedPhase, notifier.notified[0].Status.Phase)
+			assert.Equal(test.expectedMessage, notifier.notified[0].Status.Message)
+		})
+	}
+}
+
+func TestHandleStuck(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	workflow := newWorkflow("slow", "1", "Running", v1alpha1.WorkflowRunning)
+	workflow.Status.StartedAt = metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	notifier := &notificationRecorder{}
+	workflows := &pullRequestWorkflows{labels: make(map[string]string)}
+	handler := &workflowEventHandler{
+		Clients:      &clients.Clients{Workflows: workflows},
+		Notifier:     notifier,
+		StuckTimeout: time.Hour,
+	}
+
+	assert.True(isStuck(workflow, time.Hour, time.Now()))
+	assert.False(isStuck(workflow, 0, time.Now()))
+	assert.False(isStuck(workflow, 3*time.Hour, time.Now()))
+
+	assert.Nil(handler.Handle(ctx, workflow))
+	assert.Len(notifier.notified, 1)
+	assert.Equal(v1alpha1.WorkflowError, notifier.notified[0].Status.Phase)
+	assert.Equal("workflow stuck in Running for more than 1h0m0s", notifier.notified[0].Status.Message)
+	assert.Equal("Running", workflows.labels["slow/stuck"])
+
+	// Reported once per phase
+	workflow.Labels[STUCK_LABEL] = "Running"
+	assert.False(isStuck(workflow, time.Hour, time.Now()))
+	assert.Nil(handler.Handle(ctx, workflow))
+	assert.Len(notifier.notified, 1)
+}
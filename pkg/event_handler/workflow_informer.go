@@ -0,0 +1,216 @@
+package event_handler
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+const (
+	NOTIFIED_LABEL = "piper.quickube.com/notified"
+	// STUCK_LABEL holds the phase a workflow was reported stuck in.
+	STUCK_LABEL = "piper.quickube.com/stuck"
+)
+
+// workflowInformer caches the workflows created by Piper, and queues every workflow whose notified label
+// differs from its phase, stuck workflows and workflows deleted before their final phase was notified.
+type workflowInformer struct {
+	cfg      *conf.GlobalConfig
+	logger   *slog.Logger
+	handler  EventHandler
+	informer cache.SharedIndexInformer
+	queue    workqueue.RateLimitingInterface
+	// deleted keeps the last state of the queued deleted workflows, which are no longer in the cache.
+	deleted map[string]*v1alpha1.Workflow
+	mu      sync.Mutex
+}
+
+// workflowsListWatch lists and watches the workflows created by Piper.
+func workflowsListWatch(ctx context.Context, workflows workflow_handler.WorkflowsClient) cache.ListerWatcher {
+	return workflow_handler.NewListWatch(ctx, workflows, &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: NOTIFIED_LABEL,
+				Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+}
+
+func newWorkflowInformer(cfg *conf.GlobalConfig, logger *slog.Logger, listWatch cache.ListerWatcher, handler EventHandler) *workflowInformer {
+	wi := &workflowInformer{
+		cfg:      cfg,
+		logger:   logger,
+		handler:  handler,
+		informer: cache.NewSharedIndexInformer(listWatch, &v1alpha1.Workflow{}, cfg.EventHandlerConfig.ResyncPeriod, cache.Indexers{}),
+		queue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "piper-workflows"),
+		deleted:  make(map[string]*v1alpha1.Workflow),
+	}
+	wi.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    wi.enqueue,
+		UpdateFunc: func(_, newObj interface{}) { wi.enqueue(newObj) },
+		DeleteFunc: wi.enqueueDeleted,
+	})
+
+	return wi
+}
+
+// Start syncs the workflows and handles them until ctx is done. The initial list queues the workflows whose phase
+// changed while Piper was down, and every EVENT_HANDLER_RESYNC_PERIOD the workflows that failed to be notified
+// and the stuck workflows.
+func (wi *workflowInformer) Start(ctx context.Context) {
+	go wi.informer.Run(ctx.Done())
+
+	go func() {
+		defer wi.queue.ShutDown()
+
+		wi.logger.Info("waiting for the workflows cache to sync")
+		if !cache.WaitForCacheSync(ctx.Done(), wi.informer.HasSynced) {
+			wi.logger.Error("failed to sync the workflows cache")
+			return
+		}
+
+		workers := wi.cfg.EventHandlerConfig.Workers
+		if workers < 1 {
+			workers = 1
+		}
+		for i := 0; i < workers; i++ {
+			go wait.UntilWithContext(ctx, wi.runWorker, time.Second)
+		}
+		wi.logger.Info("started workers", "workers", workers)
+
+		<-ctx.Done()
+		wi.logger.Info("context canceled, exiting")
+	}()
+}
+
+func (wi *workflowInformer) enqueue(obj interface{}) {
+	workflow, ok := obj.(*v1alpha1.Workflow)
+	if !ok {
+		return
+	}
+	// The tasks of running workflows reporting node statuses change without the workflow phase
+	if !needsNotify(workflow) && !(reportsNodeStatuses(workflow) && !workflow.Status.Fulfilled()) &&
+		!isStuck(workflow, wi.cfg.EventHandlerConfig.StuckTimeout, time.Now()) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(workflow)
+	if err != nil {
+		wi.logger.Error("failed to get the key of workflow", logging.WorkflowKey, workflow.GetName(), "error", err)
+		return
+	}
+	wi.queue.Add(key)
+}
+
+// enqueueDeleted queues a workflow deleted before its final phase was notified, keeping its last state.
+func (wi *workflowInformer) enqueueDeleted(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	workflow, ok := obj.(*v1alpha1.Workflow)
+	if !ok {
+		return
+	}
+	if _, ok = workflow.GetLabels()[NOTIFIED_LABEL]; !ok || (workflow.Status.Fulfilled() && !needsNotify(workflow)) {
+		return
+	}
+	key, err := cache.MetaNamespaceKeyFunc(workflow)
+	if err != nil {
+		wi.logger.Error("failed to get the key of workflow", logging.WorkflowKey, workflow.GetName(), "error", err)
+		return
+	}
+	wi.mu.Lock()
+	wi.deleted[key] = workflow.DeepCopy()
+	wi.mu.Unlock()
+	wi.queue.Add(key)
+}
+
+func (wi *workflowInformer) runWorker(ctx context.Context) {
+	for wi.processNextItem(ctx) {
+	}
+}
+
+func (wi *workflowInformer) processNextItem(ctx context.Context) bool {
+	item, shutdown := wi.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer wi.queue.Done(item)
+
+	key := item.(string)
+	err := wi.handle(ctx, key)
+	if err == nil {
+		wi.queue.Forget(item)
+		return true
+	}
+
+	if wi.queue.NumRequeues(item) < wi.cfg.EventHandlerConfig.MaxRetries {
+		wi.logger.WarnContext(ctx, "failed to handle workflow, retrying", "key", key, "error", err)
+		wi.queue.AddRateLimited(item)
+		return true
+	}
+	// The next resync queues the workflow again
+	wi.logger.ErrorContext(ctx, "failed to handle workflow, giving up until the next resync", "key", key, "error", err)
+	wi.queue.Forget(item)
+	wi.forgetDeleted(key)
+	return true
+}
+
+func (wi *workflowInformer) handle(ctx context.Context, key string) error {
+	obj, exists, err := wi.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		wi.mu.Lock()
+		deleted, ok := wi.deleted[key]
+		wi.mu.Unlock()
+		if !ok {
+			return nil
+		}
+		err = wi.handler.HandleDeleted(withWorkflowLogFields(ctx, deleted), deleted.DeepCopy())
+		if err != nil {
+			return err
+		}
+		wi.forgetDeleted(key)
+		return nil
+	}
+	wi.forgetDeleted(key)
+	workflow := obj.(*v1alpha1.Workflow)
+	return wi.handler.Handle(withWorkflowLogFields(ctx, workflow), workflow.DeepCopy())
+}
+
+// forgetDeleted drops the kept state of a deleted workflow.
+func (wi *workflowInformer) forgetDeleted(key string) {
+	wi.mu.Lock()
+	defer wi.mu.Unlock()
+	delete(wi.deleted, key)
+}
+
+// withWorkflowLogFields returns a copy of ctx carrying the name of the workflow, and the delivery ID, repo, branch
+// and commit it was created for, as log fields.
+func withWorkflowLogFields(ctx context.Context, workflow *v1alpha1.Workflow) context.Context {
+	labels := workflow.GetLabels()
+	return logging.WithFields(ctx,
+		logging.WorkflowKey, workflow.GetName(),
+		logging.DeliveryIDKey, workflow.GetAnnotations()[workflow_handler.DELIVERY_ID_ANNOTATION],
+		logging.RepoKey, labels["repo"],
+		logging.BranchKey, labels["branch"],
+		logging.CommitKey, labels["commit"],
+	)
+}
+
+// needsNotify reports whether the phase of the workflow was not notified yet.
+func needsNotify(workflow *v1alpha1.Workflow) bool {
+	notified, ok := workflow.GetLabels()[NOTIFIED_LABEL]
+	return ok && notified != string(workflow.Status.Phase)
+}
@@ -0,0 +1,137 @@
+package event_handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+type mockEventHandler struct {
+	mu       sync.Mutex
+	failures map[string]int
+	handled  map[string][]v1alpha1.WorkflowPhase
+}
+
+func (m *mockEventHandler) Handle(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failures[workflow.GetName()] > 0 {
+		m.failures[workflow.GetName()]--
+		return errors.New("git provider unavailable")
+	}
+	m.handled[workflow.GetName()] = append(m.handled[workflow.GetName()], workflow.Status.Phase)
+	return nil
+}
+
+func (m *mockEventHandler) HandleDeleted(ctx context.Context, workflow *v1alpha1.Workflow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handled["deleted/"+workflow.GetName()] = append(m.handled["deleted/"+workflow.GetName()], workflow.Status.Phase)
+	return nil
+}
+
+func (m *mockEventHandler) handledPhases(name string) []v1alpha1.WorkflowPhase {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]v1alpha1.WorkflowPhase(nil), m.handled[name]...)
+}
+
+func newWorkflow(name string, resourceVersion string, notified string, phase v1alpha1.WorkflowPhase) *v1alpha1.Workflow {
+	return &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "workflows",
+			ResourceVersion: resourceVersion,
+			Labels:          map[string]string{NOTIFIED_LABEL: notified},
+		},
+		Status: v1alpha1.WorkflowStatus{Phase: phase},
+	}
+}
+
+func TestWorkflowInformer(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watchers := make(chan *watch.FakeWatcher, 10)
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1alpha1.WorkflowList{Items: []v1alpha1.Workflow{
+				*newWorkflow("drifted", "1", "Running", v1alpha1.WorkflowSucceeded),
+				*newWorkflow("notified", "2", "Succeeded", v1alpha1.WorkflowSucceeded),
+				*newWorkflow("flaky", "3", "Pending", v1alpha1.WorkflowRunning),
+			}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			watcher := watch.NewFake()
+			watchers <- watcher
+			return watcher, nil
+		},
+	}
+	handler := &mockEventHandler{
+		failures: map[string]int{"flaky": 2},
+		handled:  make(map[string][]v1alpha1.WorkflowPhase),
+	}
+	cfg := &conf.GlobalConfig{EventHandlerConfig: conf.EventHandlerConfig{Workers: 1, MaxRetries: 5}}
+
+	informer := newWorkflowInformer(cfg, slog.Default(), listWatch, handler)
+	informer.Start(ctx)
+
+	t.Run("Drifted workflows are notified on start", func(t *testing.T) {
+		assert.Eventually(func() bool {
+			return len(handler.handledPhases("drifted")) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.Equal([]v1alpha1.WorkflowPhase{v1alpha1.WorkflowSucceeded}, handler.handledPhases("drifted"))
+		assert.Empty(handler.handledPhases("notified"))
+	})
+
+	t.Run("Failed notifications are retried", func(t *testing.T) {
+		assert.Eventually(func() bool {
+			return len(handler.handledPhases("flaky")) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	watcher := <-watchers
+	t.Run("Watch events are notified", func(t *testing.T) {
+		watcher.Modify(newWorkflow("notified", "4", "Succeeded", v1alpha1.WorkflowFailed))
+		assert.Eventually(func() bool {
+			return len(handler.handledPhases("notified")) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Closed watches are resumed", func(t *testing.T) {
+		watcher.Stop()
+
+		var resumed *watch.FakeWatcher
+		select {
+		case resumed = <-watchers:
+		case <-time.After(10 * time.Second):
+			t.Fatal("the watch was not resumed")
+		}
+		resumed.Add(newWorkflow("new", "5", "false", v1alpha1.WorkflowRunning))
+		assert.Eventually(func() bool {
+			return len(handler.handledPhases("new")) == 1
+		}, 5*time.Second, 50*time.Millisecond)
+
+		t.Run("Deleted workflows are reported", func(t *testing.T) {
+			resumed.Delete(newWorkflow("notified", "6", "Failed", v1alpha1.WorkflowFailed))
+			resumed.Delete(newWorkflow("new", "7", "Running", v1alpha1.WorkflowRunning))
+			assert.Eventually(func() bool {
+				return len(handler.handledPhases("deleted/new")) == 1
+			}, 5*time.Second, 50*time.Millisecond)
+			assert.Empty(handler.handledPhases("deleted/notified"))
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package event_store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/quickube/piper/pkg/conf"
+	bolt "go.etcd.io/bbolt"
+)
+
+var eventsBucket = []byte("events")
+
+// BoltEventStore persists the events to a BoltDB file, so they survive restarts when the file is on a persistent volume.
+type BoltEventStore struct {
+	maxEvents int
+	db        *bolt.DB
+}
+
+func NewBoltEventStore(cfg *conf.GlobalConfig) (*BoltEventStore, error) {
+	err := os.MkdirAll(filepath.Dir(cfg.EventStoreConfig.Path), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event store directory, error: %v", err)
+	}
+
+	db, err := bolt.Open(cfg.EventStoreConfig.Path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event store %s, error: %v", cfg.EventStoreConfig.Path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize event store, error: %v", err)
+	}
+
+	return &BoltEventStore{
+		maxEvents: cfg.EventStoreConfig.MaxEvents,
+		db:        db,
+	}, nil
+}
+
+func (b *BoltEventStore) Save(event *Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s, error: %v", event.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		err := bucket.Put([]byte(event.ID), value)
+		if err != nil {
+			return err
+		}
+		if b.maxEvents <= 0 {
+			return nil
+		}
+
+		count := 0
+		cursor := bucket.Cursor()
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			count++
+		}
+		// Event IDs sort by receive time, so the oldest events come first
+		for k, _ := cursor.First(); k != nil && count > b.maxEvents; k, _ = cursor.First() {
+			err = cursor.Delete()
+			if err != nil {
+				return err
+			}
+			count--
+		}
+		return nil
+	})
+}
+
+func (b *BoltEventStore) Get(id string) (*Event, error) {
+	event := &Event{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(eventsBucket).Get([]byte(id))
+		if value == nil {
+			return ErrEventNotFound
+		}
+		return json.Unmarshal(value, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+func (b *BoltEventStore) List(limit int) ([]*Event, error) {
+	events := make([]*Event, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(eventsBucket).Cursor()
+		for k, v := cursor.Last(); k != nil && (limit <= 0 || len(events) < limit); k, v = cursor.Prev() {
+			event := &Event{}
+			err := json.Unmarshal(v, event)
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal event %s, error: %v", k, err)
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (b *BoltEventStore) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,61 @@
+package event_store
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestEventStore(t *testing.T) {
+	newStores := map[string]func(cfg *conf.GlobalConfig) (EventStore, error){
+		"Memory": func(cfg *conf.GlobalConfig) (EventStore, error) {
+			return NewMemoryEventStore(cfg), nil
+		},
+		"Bolt": func(cfg *conf.GlobalConfig) (EventStore, error) {
+			cfg.EventStoreConfig.Path = filepath.Join(t.TempDir(), "events", "events.db")
+			return NewBoltEventStore(cfg)
+		},
+	}
+
+	for name, newStore := range newStores {
+		t.Run(name, func(t *testing.T) {
+			assert := assertion.New(t)
+			store, err := newStore(&conf.GlobalConfig{EventStoreConfig: conf.EventStoreConfig{MaxEvents: 2}})
+			assert.Nil(err)
+			defer store.Close()
+
+			ids := make([]string, 0)
+			for _, commit := range []string{"a", "b", "c"} {
+				event, err := NewEvent(&git_provider.WebhookPayload{Repo: "my-repo", Commit: commit})
+				assert.Nil(err)
+				assert.Nil(store.Save(event))
+				ids = append(ids, event.ID)
+			}
+
+			_, err = store.Get(ids[0])
+			assert.ErrorIs(err, ErrEventNotFound)
+
+			event, err := store.Get(ids[1])
+			assert.Nil(err)
+			assert.Equal(EventQueued, event.Status)
+			event.Status = EventSucceeded
+			event.Workflows = append(event.Workflows, "my-repo-main-abcde")
+			assert.Nil(store.Save(event))
+
+			events, err := store.List(0)
+			assert.Nil(err)
+			assert.Len(events, 2)
+			assert.Equal("c", events[0].Payload.Commit)
+			assert.Equal("b", events[1].Payload.Commit)
+			assert.Equal(EventSucceeded, events[1].Status)
+			assert.Equal([]string{"my-repo-main-abcde"}, events[1].Workflows)
+
+			events, err = store.List(1)
+			assert.Nil(err)
+			assert.Len(events, 1)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package event_store
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+)
+
+// NewEventStore returns a BoltDB store when EVENT_STORE_PATH is configured, and an in-memory store otherwise.
+func NewEventStore(cfg *conf.GlobalConfig) (EventStore, error) {
+	if cfg.EventStoreConfig.Path == "" {
+		return NewMemoryEventStore(cfg), nil
+	}
+	return NewBoltEventStore(cfg)
+}
+
+// NewEvent creates a queued event for the payload, with an ID that sorts by the receive time.
+func NewEvent(payload *git_provider.WebhookPayload) (*Event, error) {
+	random := make([]byte, 4)
+	_, err := rand.Read(random)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate event id, error: %v", err)
+	}
+
+	now := time.Now().UTC()
+	return &Event{
+		ID:         fmt.Sprintf("%016x%08x", now.UnixNano(), binary.BigEndian.Uint32(random)),
+		ReceivedAt: now,
+		UpdatedAt:  now,
+		Status:     EventQueued,
+		Payload:    payload,
+		Triggers:   make([]int, 0),
+		Workflows:  make([]string, 0),
+		Errors:     make([]string, 0),
+	}, nil
+}
@@ -0,0 +1,81 @@
+package event_store
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+// MemoryEventStore keeps the events in memory, so they are lost on restart.
+type MemoryEventStore struct {
+	maxEvents int
+	events    map[string]*Event
+	mu        sync.Mutex
+}
+
+func NewMemoryEventStore(cfg *conf.GlobalConfig) *MemoryEventStore {
+	return &MemoryEventStore{
+		maxEvents: cfg.EventStoreConfig.MaxEvents,
+		events:    make(map[string]*Event),
+	}
+}
+
+func (m *MemoryEventStore) Save(event *Event) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events[event.ID] = event.clone()
+
+	if m.maxEvents > 0 && len(m.events) > m.maxEvents {
+		ids := m.sortedIDs()
+		for _, id := range ids[:len(ids)-m.maxEvents] {
+			delete(m.events, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryEventStore) Get(id string) (*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	event, ok := m.events[id]
+	if !ok {
+		return nil, ErrEventNotFound
+	}
+	return event.clone(), nil
+}
+
+func (m *MemoryEventStore) List(limit int) ([]*Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := m.sortedIDs()
+	events := make([]*Event, 0)
+	for i := len(ids) - 1; i >= 0 && (limit <= 0 || len(events) < limit); i-- {
+		events = append(events, m.events[ids[i]].clone())
+	}
+	return events, nil
+}
+
+func (m *MemoryEventStore) Close() error {
+	return nil
+}
+
+func (m *MemoryEventStore) sortedIDs() []string {
+	ids := make([]string, 0, len(m.events))
+	for id := range m.events {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (e *Event) clone() *Event {
+	cloned := *e
+	cloned.Triggers = append(make([]int, 0, len(e.Triggers)), e.Triggers...)
+	cloned.Workflows = append(make([]string, 0, len(e.Workflows)), e.Workflows...)
+	cloned.Errors = append(make([]string, 0, len(e.Errors)), e.Errors...)
+	return &cloned
+}
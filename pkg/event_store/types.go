@@ -0,0 +1,40 @@
+package event_store
+
+import (
+	"errors"
+	"time"
+
+	"github.com/quickube/piper/pkg/git_provider"
+)
+
+const (
+	EventQueued     = "queued"
+	EventProcessing = "processing"
+	EventSucceeded  = "succeeded"
+	EventFailed     = "failed"
+)
+
+var ErrEventNotFound = errors.New("event not found")
+
+// Event is a validated webhook delivery with the outcome of its processing.
+type Event struct {
+	ID         string                       `json:"id"`
+	ReplayOf   string                       `json:"replayOf,omitempty"`
+	ReceivedAt time.Time                    `json:"receivedAt"`
+	UpdatedAt  time.Time                    `json:"updatedAt"`
+	Status     string                       `json:"status"`
+	Attempts   int                          `json:"attempts"`
+	Payload    *git_provider.WebhookPayload `json:"payload"`
+	Triggers   []int                        `json:"triggers"`
+	Workflows  []string                     `json:"workflows"`
+	Errors     []string                     `json:"errors"`
+}
+
+type EventStore interface {
+	// Save creates or updates the event, evicting the oldest events above the configured capacity.
+	Save(event *Event) error
+	Get(id string) (*Event, error)
+	// List returns up to limit events, newest first.
+	List(limit int) ([]*Event, error)
+	Close() error
+}
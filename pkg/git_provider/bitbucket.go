@@ -8,11 +8,13 @@ import (
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/ktrysmt/go-bitbucket"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 	"github.com/quickube/piper/pkg/utils"
 	"github.com/tidwall/gjson"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -20,12 +22,15 @@ type BitbucketClientImpl struct {
 	client         *bitbucket.Client
 	cfg            *conf.GlobalConfig
 	HooksHashTable map[string]int64
+	// logger may be nil, logging.OrDefault uses the default logger then.
+	logger *slog.Logger
 }
 
-func NewBitbucketServerClient(cfg *conf.GlobalConfig) (Client, error) {
+func NewBitbucketServerClient(cfg *conf.GlobalConfig, logger *slog.Logger) (Client, error) {
+	ctx := context2.Background()
 	client := bitbucket.NewOAuthbearerToken(cfg.GitProviderConfig.Token)
 
-	err := ValidateBitbucketPermissions(client, cfg)
+	err := ValidateBitbucketPermissions(ctx, client, cfg, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -34,6 +39,7 @@ func NewBitbucketServerClient(cfg *conf.GlobalConfig) (Client, error) {
 		client:         client,
 		cfg:            cfg,
 		HooksHashTable: make(map[string]int64),
+		logger:         logger,
 	}, err
 }
 
@@ -87,7 +93,7 @@ func (b BitbucketClientImpl) GetFiles(ctx context2.Context, repo string, branch
 			return nil, err
 		}
 		if file == nil {
-			log.Printf("file %s not found in repo %s branch %s", path, repo, branch)
+			logging.OrDefault(b.logger).DebugContext(ctx, "file not found", "path", path, "repo", repo, "branch", branch)
 			continue
 		}
 		commitFiles = append(commitFiles, file)
@@ -103,12 +109,12 @@ func (b BitbucketClientImpl) SetWebhook(ctx context2.Context, repo *string) (*Ho
 		Description: "Piper",
 		Url:         b.cfg.GitProviderConfig.WebhookURL,
 		Active:      true,
-		Events:      []string{"repo:push", "pullrequest:created", "pullrequest:updated", "pullrequest:fulfilled", "pullrequest:approved"},
+		Events:      []string{"repo:push", "pullrequest:created", "pullrequest:updated", "pullrequest:fulfilled", "pullrequest:approved", "pullrequest:comment_created"},
 	}
 
-	hook, exists := b.isRepoWebhookExists(*repo)
+	hook, exists := b.isRepoWebhookExists(ctx, *repo)
 	if exists {
-		log.Printf("webhook already exists for repository %s, skipping creation... \n", *repo)
+		logging.OrDefault(b.logger).InfoContext(ctx, "repo webhook already exists, skipping creation", "repo", *repo)
 		addHookToHashTable(utils.RemoveBraces(hook.Uuid), b.HooksHashTable)
 		hookID, err := getHookByUUID(utils.RemoveBraces(hook.Uuid), b.HooksHashTable)
 		if err != nil {
@@ -125,7 +131,7 @@ func (b BitbucketClientImpl) SetWebhook(ctx context2.Context, repo *string) (*Ho
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("created webhook for repository %s \n", *repo)
+	logging.OrDefault(b.logger).InfoContext(ctx, "created repo webhook", "repo", *repo)
 
 	addHookToHashTable(utils.RemoveBraces(hook.Uuid), b.HooksHashTable)
 	hookID, err := getHookByUUID(utils.RemoveBraces(hook.Uuid), b.HooksHashTable)
@@ -141,8 +147,27 @@ func (b BitbucketClientImpl) SetWebhook(ctx context2.Context, repo *string) (*Ho
 }
 
 func (b BitbucketClientImpl) UnsetWebhook(ctx context2.Context, hook *HookWithStatus) error {
-	//TODO implement me
-	panic("implement me")
+	if hook.RepoName == nil {
+		return fmt.Errorf("bitbucket webhooks are repo level, missing repo name for hook %d", hook.HookID)
+	}
+
+	existingHook, exists := b.isRepoWebhookExists(ctx, *hook.RepoName)
+	if !exists {
+		logging.OrDefault(b.logger).InfoContext(ctx, "repo webhook does not exist, skipping deletion", "repo", *hook.RepoName)
+		return nil
+	}
+
+	_, err := b.client.Repositories.Webhooks.Delete(&bitbucket.WebhooksOptions{
+		Owner:    b.cfg.GitProviderConfig.OrgName,
+		RepoSlug: *hook.RepoName,
+		Uuid:     existingHook.Uuid,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook for repository %s, error: %v", *hook.RepoName, err)
+	}
+	delete(b.HooksHashTable, utils.RemoveBraces(existingHook.Uuid))
+	logging.OrDefault(b.logger).InfoContext(ctx, "removed repo webhook", "repo", *hook.RepoName)
+	return nil
 }
 
 func (b BitbucketClientImpl) HandlePayload(ctx context2.Context, request *http.Request, secret []byte) (*WebhookPayload, error) {
@@ -199,21 +224,59 @@ func (b BitbucketClientImpl) HandlePayload(ctx context2.Context, request *http.R
 			User:             gjson.GetBytes(buf.Bytes(), "pullrequest.author.display_name").Value().(string),
 			PullRequestURL:   gjson.GetBytes(buf.Bytes(), "pullrequest.links.html.href").Value().(string),
 			PullRequestTitle: gjson.GetBytes(buf.Bytes(), "pullrequest.title").Value().(string),
+			PullRequestID:    int(gjson.GetBytes(buf.Bytes(), "pullrequest.id").Int()),
 			DestBranch:       gjson.GetBytes(buf.Bytes(), "pullrequest.destination.branch.name").Value().(string),
 			HookID:           hookID,
 		}
+
+	case "pullrequest:comment_created":
+		webhookPayload = &WebhookPayload{
+			Event:            "pull_request_comment",
+			Action:           "created",
+			Repo:             utils.SanitizeString(gjson.GetBytes(buf.Bytes(), "repository.name").String()),
+			Branch:           gjson.GetBytes(buf.Bytes(), "pullrequest.source.branch.name").String(),
+			Commit:           gjson.GetBytes(buf.Bytes(), "pullrequest.source.commit.hash").String(),
+			User:             gjson.GetBytes(buf.Bytes(), "actor.display_name").String(),
+			PullRequestURL:   gjson.GetBytes(buf.Bytes(), "pullrequest.links.html.href").String(),
+			PullRequestTitle: gjson.GetBytes(buf.Bytes(), "pullrequest.title").String(),
+			PullRequestID:    int(gjson.GetBytes(buf.Bytes(), "pullrequest.id").Int()),
+			DestBranch:       gjson.GetBytes(buf.Bytes(), "pullrequest.destination.branch.name").String(),
+			HookID:           hookID,
+			Comment:          gjson.GetBytes(buf.Bytes(), "comment.content.raw").String(),
+			CommentAuthor:    gjson.GetBytes(buf.Bytes(), "actor.uuid").String(),
+		}
+	}
+	if webhookPayload != nil {
+		webhookPayload.DeliveryID = request.Header.Get("X-Request-UUID")
 	}
 	return webhookPayload, nil
 }
 
+func (b BitbucketClientImpl) IsCollaborator(ctx context2.Context, repo string, user string) (bool, error) {
+	permission, err := b.client.Repositories.Repository.GetUserPermissions(&bitbucket.RepositoryUserPermissionsOptions{
+		Owner:    b.cfg.GitProviderConfig.OrgName,
+		RepoSlug: repo,
+		User:     user,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get permissions of %s on repo %s: %v", user, repo, err)
+	}
+	return permission.Permission == "write" || permission.Permission == "admin", nil
+}
+
 func (b BitbucketClientImpl) SetStatus(ctx context2.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
+	return b.SetNamedStatus(ctx, repo, commit, BITBUCKET_STATUS_KEY, linkURL, status, message)
+}
+
+// SetNamedStatus sets a commit status with its own key, next to the status of the workflow.
+func (b BitbucketClientImpl) SetNamedStatus(ctx context2.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
 	commitOptions := bitbucket.CommitsOptions{
 		Owner:    b.cfg.GitProviderConfig.OrgName,
 		RepoSlug: *repo,
 		Revision: *commit,
 	}
 	commitStatusOptions := bitbucket.CommitStatusOptions{
-		Key:         "build",
+		Key:         name,
 		Url:         *linkURL,
 		State:       *status,
 		Description: *message,
@@ -222,7 +285,7 @@ func (b BitbucketClientImpl) SetStatus(ctx context2.Context, repo *string, commi
 	if err != nil {
 		return err
 	}
-	log.Printf("set status of commit %s in repo %s to %s", *commit, *repo, *status)
+	logging.OrDefault(b.logger).DebugContext(ctx, "set commit status", "context", name, "repo", *repo, "commit", *commit, "status", *status)
 	return nil
 }
 
@@ -248,12 +311,48 @@ func (b BitbucketClientImpl) GetCorrelatingEvent(ctx context2.Context, workflowE
 	return event, nil
 }
 
+// CommentOnPullRequest adds a comment to the pull request and returns its ID.
+func (b BitbucketClientImpl) CommentOnPullRequest(ctx context2.Context, repo string, pullRequestID int, body string) (int64, error) {
+	response, err := b.client.Repositories.PullRequests.AddComment(&bitbucket.PullRequestCommentOptions{
+		Owner:         b.cfg.GitProviderConfig.OrgName,
+		RepoSlug:      repo,
+		PullRequestID: strconv.Itoa(pullRequestID),
+		Content:       body,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to comment on pull request %d of repo %s: %v", pullRequestID, repo, err)
+	}
+	comment, ok := response.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("unexpected comment response on pull request %d of repo %s", pullRequestID, repo)
+	}
+	id, ok := comment["id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("comment response on pull request %d of repo %s has no id", pullRequestID, repo)
+	}
+	return int64(id), nil
+}
+
+func (b BitbucketClientImpl) UpdateComment(ctx context2.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	_, err := b.client.Repositories.PullRequests.UpdateComment(&bitbucket.PullRequestCommentOptions{
+		Owner:         b.cfg.GitProviderConfig.OrgName,
+		RepoSlug:      repo,
+		PullRequestID: strconv.Itoa(pullRequestID),
+		Content:       body,
+		CommentId:     strconv.FormatInt(commentID, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update comment %d on pull request %d of repo %s: %v", commentID, pullRequestID, repo, err)
+	}
+	return nil
+}
+
 func (b BitbucketClientImpl) PingHook(ctx context2.Context, hook *HookWithStatus) error {
 	//TODO implement me
 	panic("implement me")
 }
 
-func (b BitbucketClientImpl) isRepoWebhookExists(repo string) (*bitbucket.Webhook, bool) {
+func (b BitbucketClientImpl) isRepoWebhookExists(ctx context2.Context, repo string) (*bitbucket.Webhook, bool) {
 	emptyHook := bitbucket.Webhook{}
 
 	webhookOptions := bitbucket.WebhooksOptions{
@@ -263,7 +362,7 @@ func (b BitbucketClientImpl) isRepoWebhookExists(repo string) (*bitbucket.Webhoo
 	hooks, err := b.client.Repositories.Webhooks.List(&webhookOptions)
 
 	if err != nil {
-		log.Printf("failed to list existing hooks for repository %s. error:%s", repo, err)
+		logging.OrDefault(b.logger).ErrorContext(ctx, "failed to list existing hooks", "repo", repo, "error", err)
 		return &emptyHook, false
 	}
 
@@ -1,21 +1,23 @@
 package git_provider
 
 import (
+	"context"
 	"fmt"
 	bitbucket "github.com/ktrysmt/go-bitbucket"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 	"github.com/quickube/piper/pkg/utils"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 )
 
-func ValidateBitbucketPermissions(client *bitbucket.Client, cfg *conf.GlobalConfig) error {
+func ValidateBitbucketPermissions(ctx context.Context, client *bitbucket.Client, cfg *conf.GlobalConfig, logger *slog.Logger) error {
 
 	repoAdminScopes := []string{"webhook", "repository:admin", "pullrequest:write"}
 	repoGranularScopes := []string{"webhook", "repository", "pullrequest"}
 
-	scopes, err := GetBitbucketTokenScopes(client, cfg)
+	scopes, err := GetBitbucketTokenScopes(ctx, client, cfg, logger)
 
 	if err != nil {
 		return fmt.Errorf("failed to get scopes: %v", err)
@@ -34,11 +36,11 @@ func ValidateBitbucketPermissions(client *bitbucket.Client, cfg *conf.GlobalConf
 	return fmt.Errorf("permissions error: %v is not a valid scopes", scopes)
 }
 
-func GetBitbucketTokenScopes(client *bitbucket.Client, cfg *conf.GlobalConfig) ([]string, error) {
+func GetBitbucketTokenScopes(ctx context.Context, client *bitbucket.Client, cfg *conf.GlobalConfig, logger *slog.Logger) ([]string, error) {
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/repositories/%s", client.GetApiBaseURL(), cfg.GitProviderConfig.OrgName), nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/repositories/%s", client.GetApiBaseURL(), cfg.GitProviderConfig.OrgName), nil)
 	if err != nil {
-		log.Println("Error creating request:", err)
+		logging.OrDefault(logger).ErrorContext(ctx, "failed to create token scopes request", "error", err)
 		return nil, err
 	}
 	req.Header.Set("Accept", "application/json")
@@ -46,7 +48,7 @@ func GetBitbucketTokenScopes(client *bitbucket.Client, cfg *conf.GlobalConfig) (
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		log.Println("Error making request:", err)
+		logging.OrDefault(logger).ErrorContext(ctx, "failed to request token scopes", "error", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -58,7 +60,7 @@ func GetBitbucketTokenScopes(client *bitbucket.Client, cfg *conf.GlobalConfig) (
 	// Check the "X-OAuth-Scopes" header to get the token scopes
 	acceptedScopes := resp.Header.Get("X-Accepted-OAuth-Scopes")
 	scopes := resp.Header.Get("X-OAuth-Scopes")
-	log.Println("Bitbucket Token Scopes are:", scopes, acceptedScopes)
+	logging.OrDefault(logger).DebugContext(ctx, "bitbucket token scopes", "scopes", scopes, "accepted_scopes", acceptedScopes)
 
 	scopes = strings.ReplaceAll(scopes, " ", "")
 	return append(strings.Split(scopes, ","), acceptedScopes), nil
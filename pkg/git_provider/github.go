@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/utils"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 
 	"github.com/google/go-github/v52/github"
 )
@@ -17,14 +18,16 @@ import (
 type GithubClientImpl struct {
 	client *github.Client
 	cfg    *conf.GlobalConfig
+	// logger may be nil, logging.OrDefault uses the default logger then.
+	logger *slog.Logger
 }
 
-func NewGithubClient(cfg *conf.GlobalConfig) (Client, error) {
+func NewGithubClient(cfg *conf.GlobalConfig, logger *slog.Logger) (Client, error) {
 	ctx := context.Background()
 
 	client := github.NewTokenClient(ctx, cfg.GitProviderConfig.Token)
 
-	err := ValidatePermissions(ctx, client, cfg)
+	err := ValidatePermissions(ctx, client, cfg, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate permissions: %v", err)
 	}
@@ -40,11 +43,12 @@ func NewGithubClient(cfg *conf.GlobalConfig) (Client, error) {
 
 	cfg.OrgID = user.GetID()
 
-	log.Printf("Org ID is: %d\n", cfg.OrgID)
+	logging.OrDefault(logger).DebugContext(ctx, "resolved organization", "org", cfg.GitProviderConfig.OrgName, "org_id", cfg.OrgID)
 
 	return &GithubClientImpl{
 		client: client,
 		cfg:    cfg,
+		logger: logger,
 	}, err
 }
 
@@ -77,7 +81,7 @@ func (c *GithubClientImpl) GetFile(ctx context.Context, repo string, branch stri
 		return &commitFile, err
 	}
 	if resp.StatusCode == 404 {
-		log.Printf("File %s not found in repo %s branch %s", path, repo, branch)
+		logging.OrDefault(c.logger).DebugContext(ctx, "file not found", "path", path, "repo", repo, "branch", branch)
 		return nil, nil
 	}
 	if resp.StatusCode != 200 {
@@ -105,7 +109,7 @@ func (c *GithubClientImpl) GetFiles(ctx context.Context, repo string, branch str
 			return nil, err
 		}
 		if file == nil {
-			log.Printf("file %s not found in repo %s branch %s", path, repo, branch)
+			logging.OrDefault(c.logger).DebugContext(ctx, "file not found", "path", path, "repo", repo, "branch", branch)
 			continue
 		}
 		commitFiles = append(commitFiles, file)
@@ -124,7 +128,7 @@ func (c *GithubClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 			"content_type": "json",
 			"secret":       c.cfg.GitProviderConfig.WebhookSecret,
 		},
-		Events: []string{"push", "pull_request", "create", "release"},
+		Events: []string{"push", "pull_request", "create", "release", "issue_comment"},
 		Active: github.Bool(true),
 	}
 
@@ -142,7 +146,7 @@ func (c *GithubClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 			if resp.StatusCode != 201 {
 				return nil, fmt.Errorf("failed to create org level webhhok, API returned %d", resp.StatusCode)
 			}
-			log.Printf("edited webhook of type %s for %s name: %s\n", createdHook.GetType(), c.cfg.GitProviderConfig.OrgName, createdHook.Config["url"])
+			logging.OrDefault(c.logger).InfoContext(ctx, "created org webhook", "org", c.cfg.GitProviderConfig.OrgName, "hook_id", createdHook.GetID(), "url", createdHook.Config["url"])
 			hookID := createdHook.GetID()
 			return &HookWithStatus{HookID: hookID, HealthStatus: true, RepoName: repo}, nil
 		} else {
@@ -162,7 +166,7 @@ func (c *GithubClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 					resp.StatusCode,
 				)
 			}
-			log.Printf("edited webhook of type %s for %s: %s\n", updatedHook.GetType(), c.cfg.GitProviderConfig.OrgName, updatedHook.Config["url"])
+			logging.OrDefault(c.logger).InfoContext(ctx, "edited org webhook", "org", c.cfg.GitProviderConfig.OrgName, "hook_id", updatedHook.GetID(), "url", updatedHook.Config["url"])
 			hookID := updatedHook.GetID()
 			return &HookWithStatus{HookID: hookID, HealthStatus: true, RepoName: repo}, nil
 		}
@@ -177,7 +181,7 @@ func (c *GithubClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 			if resp.StatusCode != 201 {
 				return nil, fmt.Errorf("failed to create repo level webhhok for %s, API returned %d", *repo, resp.StatusCode)
 			}
-			log.Printf("created webhook of type %s for %s: %s\n", createdHook.GetType(), *repo, createdHook.Config["url"])
+			logging.OrDefault(c.logger).InfoContext(ctx, "created repo webhook", "repo", *repo, "hook_id", createdHook.GetID(), "url", createdHook.Config["url"])
 			hookID := createdHook.GetID()
 			return &HookWithStatus{HookID: hookID, HealthStatus: true, RepoName: repo}, nil
 		} else {
@@ -188,7 +192,7 @@ func (c *GithubClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 			if resp.StatusCode != http.StatusOK {
 				return nil, fmt.Errorf("failed to update repo level webhhok for %s, API returned %d", *repo, resp.StatusCode)
 			}
-			log.Printf("edited webhook of type %s for %s: %s\n", updatedHook.GetType(), *repo, updatedHook.Config["url"])
+			logging.OrDefault(c.logger).InfoContext(ctx, "edited repo webhook", "repo", *repo, "hook_id", updatedHook.GetID(), "url", updatedHook.Config["url"])
 			hookID := updatedHook.GetID()
 			return &HookWithStatus{HookID: hookID, HealthStatus: true, RepoName: repo}, nil
 		}
@@ -209,7 +213,7 @@ func (c *GithubClientImpl) UnsetWebhook(ctx context.Context, hook *HookWithStatu
 		if resp.StatusCode != 204 {
 			return fmt.Errorf("failed to delete org level webhhok, API call returned %d", resp.StatusCode)
 		}
-		log.Printf("removed org webhook, hookID :%d\n", hook.HookID) // INFO
+		logging.OrDefault(c.logger).InfoContext(ctx, "removed org webhook", "hook_id", hook.HookID)
 	} else {
 		resp, err := c.client.Repositories.DeleteHook(ctx, c.cfg.GitProviderConfig.OrgName, *hook.RepoName, hook.HookID)
 
@@ -220,7 +224,7 @@ func (c *GithubClientImpl) UnsetWebhook(ctx context.Context, hook *HookWithStatu
 		if resp.StatusCode != 204 {
 			return fmt.Errorf("failed to delete repo level webhhok for %s, API call returned %d", *hook.RepoName, resp.StatusCode)
 		}
-		log.Printf("removed repo webhook, repo:%s hookID :%d\n", *hook.RepoName, hook.HookID) // INFO
+		logging.OrDefault(c.logger).InfoContext(ctx, "removed repo webhook", "repo", *hook.RepoName, "hook_id", hook.HookID)
 	}
 
 	return nil
@@ -269,6 +273,7 @@ func (c *GithubClientImpl) HandlePayload(ctx context.Context, request *http.Requ
 			UserEmail:        e.GetSender().GetEmail(), // e.GetPullRequest().GetUser().GetEmail() Not working. GitHub missing email for PR events in payload.
 			PullRequestTitle: e.GetPullRequest().GetTitle(),
 			PullRequestURL:   e.GetPullRequest().GetHTMLURL(),
+			PullRequestID:    e.GetNumber(),
 			DestBranch:       e.GetPullRequest().GetBase().GetRef(),
 			Labels:           c.extractLabelNames(e.GetPullRequest().Labels),
 			OwnerID:          e.GetSender().GetID(),
@@ -299,16 +304,46 @@ func (c *GithubClientImpl) HandlePayload(ctx context.Context, request *http.Requ
 			UserEmail: e.GetSender().GetEmail(),
 			OwnerID:   e.GetSender().GetID(),
 		}
+	case *github.IssueCommentEvent:
+		webhookPayload = &WebhookPayload{
+			Event:         "issue_comment",
+			Action:        e.GetAction(), // "created", "edited", "deleted".
+			Repo:          e.GetRepo().GetName(),
+			User:          e.GetComment().GetUser().GetLogin(),
+			OwnerID:       e.GetSender().GetID(),
+			Comment:       e.GetComment().GetBody(),
+			CommentAuthor: e.GetComment().GetUser().GetLogin(),
+		}
+		if e.GetIssue().IsPullRequest() {
+			pullRequest, _, _err := c.client.PullRequests.Get(ctx, c.cfg.OrgName, e.GetRepo().GetName(), e.GetIssue().GetNumber())
+			if _err != nil {
+				return nil, fmt.Errorf("failed to get pull request %d of repo %s: %v", e.GetIssue().GetNumber(), e.GetRepo().GetName(), _err)
+			}
+			webhookPayload.Event = "pull_request_comment"
+			webhookPayload.Branch = pullRequest.GetHead().GetRef()
+			webhookPayload.Commit = pullRequest.GetHead().GetSHA()
+			webhookPayload.PullRequestTitle = pullRequest.GetTitle()
+			webhookPayload.PullRequestURL = pullRequest.GetHTMLURL()
+			webhookPayload.PullRequestID = pullRequest.GetNumber()
+			webhookPayload.DestBranch = pullRequest.GetBase().GetRef()
+			webhookPayload.Labels = c.extractLabelNames(pullRequest.Labels)
+		}
 	}
 
 	if c.cfg.EnforceOrgBelonging && (webhookPayload.OwnerID == 0 || webhookPayload.OwnerID != c.cfg.OrgID) {
 		return nil, fmt.Errorf("webhook send from non organizational member")
 	}
+	webhookPayload.DeliveryID = github.DeliveryID(request)
 	return webhookPayload, nil
 
 }
 
 func (c *GithubClientImpl) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
+	return c.SetNamedStatus(ctx, repo, commit, STATUS_NAME, linkURL, status, message)
+}
+
+// SetNamedStatus sets a commit status with its own context, next to the status of the workflow.
+func (c *GithubClientImpl) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
 	if !utils.ValidateHTTPFormat(*linkURL) {
 		return fmt.Errorf("invalid linkURL")
 	}
@@ -316,7 +351,7 @@ func (c *GithubClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 		State:       status, // pending, success, error, or failure.
 		TargetURL:   linkURL,
 		Description: utils.SPtr(fmt.Sprintf("Workflow %s %s", *status, *message)),
-		Context:     utils.SPtr("Piper/ArgoWorkflows"),
+		Context:     utils.SPtr(name),
 		AvatarURL:   utils.SPtr("https://argoproj.github.io/argo-workflows/assets/logo.png"),
 	}
 
@@ -329,7 +364,7 @@ func (c *GithubClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 		return fmt.Errorf("failed to set status on repo:%s, commit:%s, API call returned %d", *repo, *commit, resp.StatusCode)
 	}
 
-	log.Printf("successfully set status on repo:%s commit: %s to status: %s\n", *repo, *commit, *status)
+	logging.OrDefault(c.logger).DebugContext(ctx, "set commit status", "context", name, "repo", *repo, "commit", *commit, "status", *status)
 	return nil
 }
 
@@ -357,6 +392,31 @@ func (c *GithubClientImpl) GetCorrelatingEvent(ctx context.Context, workflowEven
 	return event, nil
 }
 
+func (c *GithubClientImpl) IsCollaborator(ctx context.Context, repo string, user string) (bool, error) {
+	isCollaborator, _, err := c.client.Repositories.IsCollaborator(ctx, c.cfg.OrgName, repo, user)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if %s is a collaborator of repo %s: %v", user, repo, err)
+	}
+	return isCollaborator, nil
+}
+
+// CommentOnPullRequest adds a comment to the pull request and returns its ID.
+func (c *GithubClientImpl) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	comment, _, err := c.client.Issues.CreateComment(ctx, c.cfg.OrgName, repo, pullRequestID, &github.IssueComment{Body: &body})
+	if err != nil {
+		return 0, fmt.Errorf("failed to comment on pull request %d of repo %s: %v", pullRequestID, repo, err)
+	}
+	return comment.GetID(), nil
+}
+
+func (c *GithubClientImpl) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	_, _, err := c.client.Issues.EditComment(ctx, c.cfg.OrgName, repo, commentID, &github.IssueComment{Body: &body})
+	if err != nil {
+		return fmt.Errorf("failed to update comment %d on pull request %d of repo %s: %v", commentID, pullRequestID, repo, err)
+	}
+	return nil
+}
+
 func (c *GithubClientImpl) PingHook(ctx context.Context, hook *HookWithStatus) error {
 	if c.cfg.OrgLevelWebhook && hook.RepoName != nil {
 		return fmt.Errorf("trying to ping repo scope webhook while configured for org level webhook. repo: %s", *hook.RepoName)
@@ -394,7 +454,7 @@ func (c *GithubClientImpl) refToSHA(ctx context.Context, ref string, repo string
 		return nil, fmt.Errorf("failed to set status on repo:%s, commit:%s, API call returned %d", repo, ref, resp.StatusCode)
 	}
 
-	log.Printf("resolved ref: %s to SHA: %s", ref, respSHA)
+	logging.OrDefault(c.logger).DebugContext(ctx, "resolved ref", "ref", ref, "sha", respSHA)
 	return &respSHA, nil
 }
 
@@ -3,6 +3,7 @@ package git_provider
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 
@@ -10,6 +11,7 @@ import (
 
 	"github.com/google/go-github/v52/github"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 )
 
 func isOrgWebhookEnabled(ctx context.Context, c *GithubClientImpl) (*github.Hook, bool) {
@@ -54,36 +56,36 @@ func isRepoWebhookEnabled(ctx context.Context, c *GithubClientImpl, repo string)
 	return &emptyHook, false
 }
 
-func GetScopes(ctx context.Context, client *github.Client) ([]string, error) {
+func GetScopes(ctx context.Context, client *github.Client, logger *slog.Logger) ([]string, error) {
 	// Make a request to the "Get the authenticated user" endpoint
 	req, err := http.NewRequest("GET", "https://api.github.com/user", nil)
 	if err != nil {
-		fmt.Println("Error creating request:", err)
+		logging.OrDefault(logger).ErrorContext(ctx, "failed to create token scopes request", "error", err)
 		return nil, err
 	}
 	resp, err := client.Do(ctx, req, nil)
 	if err != nil {
-		fmt.Println("Error making request:", err)
+		logging.OrDefault(logger).ErrorContext(ctx, "failed to request token scopes", "error", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Check the "X-OAuth-Scopes" header to get the token scopes
 	scopes := resp.Header.Get("X-OAuth-Scopes")
-	fmt.Println("Github Token Scopes are:", scopes)
+	logging.OrDefault(logger).DebugContext(ctx, "github token scopes", "scopes", scopes)
 
 	scopes = strings.ReplaceAll(scopes, " ", "")
 	return strings.Split(scopes, ","), nil
 
 }
 
-func ValidatePermissions(ctx context.Context, client *github.Client, cfg *conf.GlobalConfig) error {
+func ValidatePermissions(ctx context.Context, client *github.Client, cfg *conf.GlobalConfig, logger *slog.Logger) error {
 
 	orgScopes := []string{"admin:org_hook"}
 	repoAdminScopes := []string{"admin:repo_hook"}
 	repoGranularScopes := []string{"write:repo_hook", "read:repo_hook"}
 
-	scopes, err := GetScopes(ctx, client)
+	scopes, err := GetScopes(ctx, client, logger)
 
 	if err != nil {
 		return fmt.Errorf("failed to get scopes: %v", err)
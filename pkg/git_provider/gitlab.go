@@ -5,11 +5,12 @@ import (
 	"fmt"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 	"github.com/quickube/piper/pkg/utils"
 
 	"github.com/xanzy/go-gitlab"
@@ -18,9 +19,11 @@ import (
 type GitlabClientImpl struct {
 	client *gitlab.Client
 	cfg    *conf.GlobalConfig
+	// logger may be nil, logging.OrDefault uses the default logger then.
+	logger *slog.Logger
 }
 
-func NewGitlabClient(cfg *conf.GlobalConfig) (Client, error) {
+func NewGitlabClient(cfg *conf.GlobalConfig, logger *slog.Logger) (Client, error) {
 	var options []gitlab.ClientOptionFunc
 	ctx := context.Background()
 
@@ -48,16 +51,17 @@ func NewGitlabClient(cfg *conf.GlobalConfig) (Client, error) {
 
 	cfg.GitProviderConfig.OrgID = int64(group.ID)
 
-	log.Printf("Group ID is: %d\n", cfg.OrgID)
+	logging.OrDefault(logger).DebugContext(ctx, "resolved group", "group", cfg.GitProviderConfig.OrgName, "group_id", cfg.OrgID)
 
 	return &GitlabClientImpl{
 		client: client,
 		cfg:    cfg,
+		logger: logger,
 	}, err
 }
 
 func (c *GitlabClientImpl) ListFiles(ctx context.Context, repo string, branch string, path string) ([]string, error) {
-	log.Printf("Listing files for repo: %s", repo)
+	logging.OrDefault(c.logger).DebugContext(ctx, "listing files", "repo", repo, "branch", branch, "path", path)
 	var files []string
 	opt := &gitlab.ListTreeOptions{
 		Ref:  &branch,
@@ -81,13 +85,13 @@ func (c *GitlabClientImpl) ListFiles(ctx context.Context, repo string, branch st
 }
 
 func (c *GitlabClientImpl) GetFile(ctx context.Context, repo string, branch string, path string) (*CommitFile, error) {
-	log.Printf("Getting file: %s", path)
+	logging.OrDefault(c.logger).DebugContext(ctx, "getting file", "repo", repo, "branch", branch, "path", path)
 	commitFile := &CommitFile{}
 	projectId, err := GetProjectId(ctx, c, &repo)
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("got project id: ", *projectId)
+	logging.OrDefault(c.logger).DebugContext(ctx, "resolved project", "repo", repo, "project_id", *projectId)
 	fileContent, resp, err := c.client.RepositoryFiles.GetFile(*projectId, path, &gitlab.GetFileOptions{Ref: &branch}, gitlab.WithContext(ctx))
 	if err != nil {
 		return nil, err
@@ -108,7 +112,6 @@ func (c *GitlabClientImpl) GetFile(ctx context.Context, repo string, branch stri
 }
 
 func (c *GitlabClientImpl) GetFiles(ctx context.Context, repo string, branch string, paths []string) ([]*CommitFile, error) {
-	log.Println("Getting multiple files")
 	var commitFiles []*CommitFile
 	for _, path := range paths {
 		file, err := c.GetFile(ctx, repo, branch, path)
@@ -116,25 +119,24 @@ func (c *GitlabClientImpl) GetFiles(ctx context.Context, repo string, branch str
 			return nil, err
 		}
 		if file == nil {
-			log.Printf("file %s not found in repo %s branch %s", path, repo, branch)
+			logging.OrDefault(c.logger).DebugContext(ctx, "file not found", "path", path, "repo", repo, "branch", branch)
 			continue
 		}
 		commitFiles = append(commitFiles, file)
 	}
-	log.Println("commit file", commitFiles)
 	return commitFiles, nil
 }
 
 func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookWithStatus, error) {
 	var gitlabHookId *int
 	if *repo == "" {
-		log.Println("starting with group level hooks")
 		respHook, ok := IsGroupWebhookEnabled(ctx, c)
 		if !ok {
 			groupHookOptions := gitlab.AddGroupHookOptions{
 				URL:                 &c.cfg.GitProviderConfig.WebhookURL,
 				Token:               &c.cfg.GitProviderConfig.WebhookSecret,
 				MergeRequestsEvents: gitlab.Ptr(true),
+				NoteEvents:          gitlab.Ptr(true),
 				PushEvents:          gitlab.Ptr(true),
 				ReleasesEvents:      gitlab.Ptr(true),
 			}
@@ -151,12 +153,13 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				return nil, err
 			}
 			gitlabHookId = &gitlabHook.ID
-			log.Printf("added webhook: %d for %s name: %s\n", gitlabHook.ID, c.cfg.GitProviderConfig.OrgName, gitlabHook.URL)
+			logging.OrDefault(c.logger).InfoContext(ctx, "created group webhook", "group", c.cfg.GitProviderConfig.OrgName, "hook_id", gitlabHook.ID, "url", gitlabHook.URL)
 		} else {
 			editedGroupHookOpt := gitlab.EditGroupHookOptions{
 				URL:                 gitlab.Ptr(c.cfg.GitProviderConfig.WebhookURL),
 				Token:               gitlab.Ptr(c.cfg.GitProviderConfig.WebhookSecret),
 				MergeRequestsEvents: gitlab.Ptr(true),
+				NoteEvents:          gitlab.Ptr(true),
 				PushEvents:          gitlab.Ptr(true),
 				ReleasesEvents:      gitlab.Ptr(true),
 			}
@@ -165,7 +168,6 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				if resp.StatusCode == http.StatusForbidden {
 					return nil, fmt.Errorf("for org level webhook, group token must be Owner level")
 				} else if resp.StatusCode != http.StatusOK {
-					fmt.Println(resp.Request.URL, err)
 					return nil, fmt.Errorf(
 						"failed to update group level webhook for %s, API returned %d",
 						c.cfg.GitProviderConfig.OrgName,
@@ -177,14 +179,14 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				return nil, err
 			}
 			gitlabHookId = &gitlabHook.ID
-			log.Printf("edited webhook for %s: %s\n", c.cfg.GitProviderConfig.OrgName, gitlabHook.URL)
+			logging.OrDefault(c.logger).InfoContext(ctx, "edited group webhook", "group", c.cfg.GitProviderConfig.OrgName, "hook_id", gitlabHook.ID, "url", gitlabHook.URL)
 		}
 	} else {
 		projectId, err := GetProjectId(ctx, c, repo)
 		if err != nil {
 			return nil, err
 		}
-		log.Printf("project id is: %d\n", *projectId)
+		logging.OrDefault(c.logger).DebugContext(ctx, "resolved project", "repo", *repo, "project_id", *projectId)
 		respHook, ok := IsProjectWebhookEnabled(ctx, c, *projectId)
 
 		if !ok {
@@ -192,6 +194,7 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				URL:                 &c.cfg.GitProviderConfig.WebhookURL,
 				Token:               &c.cfg.GitProviderConfig.WebhookSecret,
 				MergeRequestsEvents: gitlab.Ptr(true),
+				NoteEvents:          gitlab.Ptr(true),
 				PushEvents:          gitlab.Ptr(true),
 				ReleasesEvents:      gitlab.Ptr(true),
 			}
@@ -204,16 +207,16 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				}
 			}
 			if err != nil {
-				log.Println("url", *addProjectHookOpts.URL, *projectId, *addProjectHookOpts.Token)
 				return nil, fmt.Errorf("failed to add project hook ,%d", err)
 			}
 			gitlabHookId = &gitlabHook.ID
-			log.Printf("created webhook: %d for %s: %s\n", gitlabHook.ID, *repo, gitlabHook.URL)
+			logging.OrDefault(c.logger).InfoContext(ctx, "created repo webhook", "repo", *repo, "hook_id", gitlabHook.ID, "url", gitlabHook.URL)
 		} else {
 			editProjectHookOpts := gitlab.EditProjectHookOptions{
 				URL:                 gitlab.Ptr(c.cfg.GitProviderConfig.WebhookURL),
 				Token:               gitlab.Ptr(c.cfg.GitProviderConfig.WebhookSecret),
 				MergeRequestsEvents: gitlab.Ptr(true),
+				NoteEvents:          gitlab.Ptr(true),
 				PushEvents:          gitlab.Ptr(true),
 				ReleasesEvents:      gitlab.Ptr(true),
 			}
@@ -229,7 +232,7 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 				return nil, err
 			}
 			gitlabHookId = &gitlabHook.ID
-			log.Printf("edited webhook: %d for %s: %s\n", *gitlabHookId, *repo, gitlabHook.URL)
+			logging.OrDefault(c.logger).InfoContext(ctx, "edited repo webhook", "repo", *repo, "hook_id", *gitlabHookId, "url", gitlabHook.URL)
 		}
 	}
 
@@ -238,7 +241,6 @@ func (c *GitlabClientImpl) SetWebhook(ctx context.Context, repo *string) (*HookW
 }
 
 func (c *GitlabClientImpl) UnsetWebhook(ctx context.Context, hook *HookWithStatus) error {
-	log.Println("unsetting webhook", *hook.RepoName)
 	if *hook.RepoName == "" {
 		resp, err := c.client.Groups.DeleteGroupHook(c.cfg.GitProviderConfig.OrgName, int(hook.HookID), gitlab.WithContext(ctx))
 		if resp != nil {
@@ -249,7 +251,7 @@ func (c *GitlabClientImpl) UnsetWebhook(ctx context.Context, hook *HookWithStatu
 		if err != nil {
 			return err
 		}
-		log.Printf("removed group webhook, hookID :%d\n", hook.HookID)
+		logging.OrDefault(c.logger).InfoContext(ctx, "removed group webhook", "hook_id", hook.HookID)
 	} else {
 		projectId, err := GetProjectId(ctx, c, hook.RepoName)
 		if err != nil {
@@ -258,22 +260,21 @@ func (c *GitlabClientImpl) UnsetWebhook(ctx context.Context, hook *HookWithStatu
 		resp, err := c.client.Projects.DeleteProjectHook(*projectId, int(hook.HookID), gitlab.WithContext(ctx))
 		if resp != nil {
 			if resp.StatusCode != http.StatusNoContent {
-				log.Printf("failed to delete project level webhhok for %s, API call returned %d", *hook.RepoName, resp.StatusCode)
+				logging.OrDefault(c.logger).ErrorContext(ctx, "failed to delete repo webhook", "repo", *hook.RepoName, "status_code", resp.StatusCode)
 				return fmt.Errorf("failed to delete project level webhhok for %s, API call returned %d", *hook.RepoName, resp.StatusCode)
 			}
 		}
 		if err != nil {
-			log.Printf("failed to delete project level webhhok for %s, API call returned %s", *hook.RepoName, err)
+			logging.OrDefault(c.logger).ErrorContext(ctx, "failed to delete repo webhook", "repo", *hook.RepoName, "error", err)
 			return err
 		}
-		log.Printf("removed project webhook, project:%s hookID :%d\n", *hook.RepoName, hook.HookID) // INFO
+		logging.OrDefault(c.logger).InfoContext(ctx, "removed repo webhook", "repo", *hook.RepoName, "hook_id", hook.HookID)
 	}
 
 	return nil
 }
 
 func (c *GitlabClientImpl) HandlePayload(ctx context.Context, request *http.Request, secret []byte) (*WebhookPayload, error) {
-	log.Printf("starting with payload")
 	var webhookPayload WebhookPayload
 	payload, err := io.ReadAll(request.Body)
 	if err != nil {
@@ -305,6 +306,7 @@ func (c *GitlabClientImpl) HandlePayload(ctx context.Context, request *http.Requ
 			UserEmail:        e.User.Email,
 			PullRequestTitle: e.ObjectAttributes.Title,
 			PullRequestURL:   e.ObjectAttributes.URL,
+			PullRequestID:    e.ObjectAttributes.IID,
 			DestBranch:       e.ObjectAttributes.TargetBranch,
 			Labels:           ExtractLabelsId(e.Labels),
 			OwnerID:          int64(e.User.ID),
@@ -319,14 +321,55 @@ func (c *GitlabClientImpl) HandlePayload(ctx context.Context, request *http.Requ
 			User:      e.Commit.Author.Name,
 			UserEmail: e.Commit.Author.Email,
 		}
+	case *gitlab.MergeCommentEvent:
+		webhookPayload = WebhookPayload{
+			Event:            "pull_request_comment",
+			Action:           "created",
+			Repo:             e.Project.Name,
+			Branch:           e.MergeRequest.SourceBranch,
+			Commit:           e.MergeRequest.LastCommit.ID,
+			User:             e.User.Name,
+			UserEmail:        e.User.Email,
+			PullRequestTitle: e.MergeRequest.Title,
+			PullRequestURL:   fmt.Sprintf("%s/-/merge_requests/%d", e.Project.WebURL, e.MergeRequest.IID),
+			PullRequestID:    e.MergeRequest.IID,
+			DestBranch:       e.MergeRequest.TargetBranch,
+			OwnerID:          int64(e.User.ID),
+			Comment:          e.ObjectAttributes.Note,
+			CommentAuthor:    e.User.Username,
+		}
 	}
-	log.Printf("sending payload: %s, %s", webhookPayload.Repo, webhookPayload.User)
+	webhookPayload.DeliveryID = request.Header.Get("X-Gitlab-Event-UUID")
+	logging.OrDefault(c.logger).DebugContext(ctx, "parsed payload", "repo", webhookPayload.Repo, "user", webhookPayload.User)
 	return &webhookPayload, nil
 }
 
+func (c *GitlabClientImpl) IsCollaborator(ctx context.Context, repo string, user string) (bool, error) {
+	projectId, err := GetProjectId(ctx, c, &repo)
+	if err != nil {
+		return false, err
+	}
+
+	members, _, err := c.client.ProjectMembers.ListAllProjectMembers(*projectId, &gitlab.ListProjectMembersOptions{Query: gitlab.Ptr(user)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to list members of project %s: %v", repo, err)
+	}
+	for _, member := range members {
+		if member.Username == user && member.AccessLevel >= gitlab.DeveloperPermissions {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (c *GitlabClientImpl) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
+	return c.SetNamedStatus(ctx, repo, commit, STATUS_NAME, linkURL, status, message)
+}
+
+// SetNamedStatus sets a commit status with its own name, next to the status of the workflow.
+func (c *GitlabClientImpl) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
 	if !utils.ValidateHTTPFormat(*linkURL) {
-		log.Println("invalid link URL", *linkURL)
+		logging.OrDefault(c.logger).WarnContext(ctx, "invalid link URL", "url", *linkURL)
 		return fmt.Errorf("invalid linkURL")
 	}
 	projectId, err := GetProjectId(ctx, c, repo)
@@ -334,7 +377,7 @@ func (c *GitlabClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 		return err
 	}
 
-	currCommit, resp, err := c.client.Commits.GetCommitStatuses(*projectId, *commit, nil, gitlab.WithContext(ctx))
+	currCommit, resp, err := c.client.Commits.GetCommitStatuses(*projectId, *commit, &gitlab.GetCommitStatusesOptions{Name: gitlab.Ptr(name)}, gitlab.WithContext(ctx))
 	if err != nil {
 		return err
 	}
@@ -345,7 +388,7 @@ func (c *GitlabClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 	if len(currCommit) != 0 {
 		if currCommit[0].Status == *status {
 			// https://forum.gitlab.com/t/cannot-transition-status-via-run-from-running-reason-s-status-cannot-transition-via-run/42588/6
-			log.Printf("cannot change commit description without status also, status stays: %s", *status)
+			logging.OrDefault(c.logger).DebugContext(ctx, "cannot change commit description without the status, keeping the status", "status", *status)
 			return nil
 		}
 	}
@@ -354,7 +397,7 @@ func (c *GitlabClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 		State:       gitlab.BuildStateValue(*status), // pending, success, error, or failure.
 		TargetURL:   linkURL,
 		Description: gitlab.Ptr(fmt.Sprintf("Workflow %s %s", *status, *message)),
-		Context:     gitlab.Ptr("Piper/ArgoWorkflows"),
+		Context:     gitlab.Ptr(name),
 	}
 	_, resp, err = c.client.Commits.SetCommitStatus(*projectId, *commit, &repoStatus, gitlab.WithContext(ctx))
 	if err != nil {
@@ -365,7 +408,7 @@ func (c *GitlabClientImpl) SetStatus(ctx context.Context, repo *string, commit *
 		return fmt.Errorf("failed to set status on repo:%s, commit:%s, API call returned %d", *repo, *commit, resp.StatusCode)
 	}
 
-	log.Printf("successfully set status on repo:%s commit: %s to status: %s\n", *repo, *commit, *status)
+	logging.OrDefault(c.logger).DebugContext(ctx, "set commit status", "context", name, "repo", *repo, "commit", *commit, "status", *status)
 	return nil
 }
 
@@ -393,6 +436,31 @@ func (c *GitlabClientImpl) GetCorrelatingEvent(ctx context.Context, workflowEven
 	return event, nil
 }
 
+// CommentOnPullRequest adds a note to the merge request and returns its ID.
+func (c *GitlabClientImpl) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	projectId, err := GetProjectId(ctx, c, &repo)
+	if err != nil {
+		return 0, err
+	}
+	note, _, err := c.client.Notes.CreateMergeRequestNote(*projectId, pullRequestID, &gitlab.CreateMergeRequestNoteOptions{Body: &body}, gitlab.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to comment on merge request %d of repo %s: %v", pullRequestID, repo, err)
+	}
+	return int64(note.ID), nil
+}
+
+func (c *GitlabClientImpl) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	projectId, err := GetProjectId(ctx, c, &repo)
+	if err != nil {
+		return err
+	}
+	_, _, err = c.client.Notes.UpdateMergeRequestNote(*projectId, pullRequestID, int(commentID), &gitlab.UpdateMergeRequestNoteOptions{Body: &body}, gitlab.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to update note %d on merge request %d of repo %s: %v", commentID, pullRequestID, repo, err)
+	}
+	return nil
+}
+
 func (c *GitlabClientImpl) PingHook(ctx context.Context, hook *HookWithStatus) error {
 	//TODO implement me
 	panic("implement me")
@@ -7,11 +7,11 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strings"
 
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 	"github.com/quickube/piper/pkg/utils"
 	"github.com/xanzy/go-gitlab"
 	"golang.org/x/net/context"
@@ -97,7 +97,7 @@ func GetProjectId(ctx context.Context, c *GitlabClientImpl, repo *string) (*int,
 	projectFullName := fmt.Sprintf("%s/%s", c.cfg.GitProviderConfig.OrgName, *repo)
 	IProject, _, err := c.client.Projects.GetProject(projectFullName, nil, gitlab.WithContext(ctx))
 	if err != nil {
-		log.Printf("Failed to get project (%s): %v", *repo, err)
+		logging.OrDefault(c.logger).ErrorContext(ctx, "failed to get project", "repo", *repo, "error", err)
 		return nil, err
 	}
 	return &IProject.ID, nil
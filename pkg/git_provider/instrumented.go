@@ -0,0 +1,127 @@
+package git_provider
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/metrics"
+	"github.com/quickube/piper/pkg/tracing"
+)
+
+// instrumentedClient records the count and latency of the git provider API calls of a client, and a span per call.
+type instrumentedClient struct {
+	client   Client
+	recorder metrics.Recorder
+}
+
+func NewInstrumentedClient(client Client, recorder metrics.Recorder) Client {
+	return &instrumentedClient{client: client, recorder: recorder}
+}
+
+func (c *instrumentedClient) start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span, time.Time) {
+	ctx, span := tracing.Tracer().Start(ctx, "git."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+	return ctx, span, time.Now()
+}
+
+func (c *instrumentedClient) observe(span trace.Span, method string, start time.Time, err error) {
+	c.recorder.GitAPICall(method, time.Since(start), err)
+	tracing.End(span, err)
+}
+
+func (c *instrumentedClient) ListFiles(ctx context.Context, repo string, branch string, path string) ([]string, error) {
+	ctx, span, start := c.start(ctx, "ListFiles", attribute.String("repo", repo), attribute.String("branch", branch), attribute.String("path", path))
+	files, err := c.client.ListFiles(ctx, repo, branch, path)
+	c.observe(span, "ListFiles", start, err)
+	return files, err
+}
+
+func (c *instrumentedClient) GetFile(ctx context.Context, repo string, branch string, path string) (*CommitFile, error) {
+	ctx, span, start := c.start(ctx, "GetFile", attribute.String("repo", repo), attribute.String("branch", branch), attribute.String("path", path))
+	file, err := c.client.GetFile(ctx, repo, branch, path)
+	c.observe(span, "GetFile", start, err)
+	return file, err
+}
+
+func (c *instrumentedClient) GetFiles(ctx context.Context, repo string, branch string, paths []string) ([]*CommitFile, error) {
+	ctx, span, start := c.start(ctx, "GetFiles", attribute.String("repo", repo), attribute.String("branch", branch), attribute.StringSlice("paths", paths))
+	files, err := c.client.GetFiles(ctx, repo, branch, paths)
+	c.observe(span, "GetFiles", start, err)
+	return files, err
+}
+
+func (c *instrumentedClient) SetWebhook(ctx context.Context, repo *string) (*HookWithStatus, error) {
+	ctx, span, start := c.start(ctx, "SetWebhook")
+	hook, err := c.client.SetWebhook(ctx, repo)
+	c.observe(span, "SetWebhook", start, err)
+	return hook, err
+}
+
+func (c *instrumentedClient) UnsetWebhook(ctx context.Context, hook *HookWithStatus) error {
+	ctx, span, start := c.start(ctx, "UnsetWebhook")
+	err := c.client.UnsetWebhook(ctx, hook)
+	c.observe(span, "UnsetWebhook", start, err)
+	return err
+}
+
+// HandlePayload parses and validates a webhook, it is traced but not observed as an API call.
+func (c *instrumentedClient) HandlePayload(ctx context.Context, request *http.Request, secret []byte) (*WebhookPayload, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "git.HandlePayload")
+	payload, err := c.client.HandlePayload(ctx, request, secret)
+	if payload != nil {
+		span.SetAttributes(attribute.String("repo", payload.Repo), attribute.String("event", payload.Event))
+	}
+	tracing.End(span, err)
+	return payload, err
+}
+
+func (c *instrumentedClient) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
+	ctx, span, start := c.start(ctx, "SetStatus")
+	err := c.client.SetStatus(ctx, repo, commit, linkURL, status, message)
+	c.observe(span, "SetStatus", start, err)
+	return err
+}
+
+func (c *instrumentedClient) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
+	ctx, span, start := c.start(ctx, "SetNamedStatus")
+	err := c.client.SetNamedStatus(ctx, repo, commit, name, linkURL, status, message)
+	c.observe(span, "SetNamedStatus", start, err)
+	return err
+}
+
+func (c *instrumentedClient) PingHook(ctx context.Context, hook *HookWithStatus) error {
+	ctx, span, start := c.start(ctx, "PingHook")
+	err := c.client.PingHook(ctx, hook)
+	c.observe(span, "PingHook", start, err)
+	return err
+}
+
+// GetCorrelatingEvent maps a workflow phase to a commit status, it is not observed as an API call.
+func (c *instrumentedClient) GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error) {
+	return c.client.GetCorrelatingEvent(ctx, workflowEvent)
+}
+
+func (c *instrumentedClient) IsCollaborator(ctx context.Context, repo string, user string) (bool, error) {
+	ctx, span, start := c.start(ctx, "IsCollaborator", attribute.String("repo", repo))
+	isCollaborator, err := c.client.IsCollaborator(ctx, repo, user)
+	c.observe(span, "IsCollaborator", start, err)
+	return isCollaborator, err
+}
+
+func (c *instrumentedClient) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	ctx, span, start := c.start(ctx, "CommentOnPullRequest", attribute.String("repo", repo), attribute.Int("pull_request", pullRequestID))
+	commentID, err := c.client.CommentOnPullRequest(ctx, repo, pullRequestID, body)
+	c.observe(span, "CommentOnPullRequest", start, err)
+	return commentID, err
+}
+
+func (c *instrumentedClient) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	ctx, span, start := c.start(ctx, "UpdateComment", attribute.String("repo", repo), attribute.Int("pull_request", pullRequestID))
+	err := c.client.UpdateComment(ctx, repo, pullRequestID, commentID, body)
+	c.observe(span, "UpdateComment", start, err)
+	return err
+}
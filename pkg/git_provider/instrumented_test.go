@@ -0,0 +1,51 @@
+package git_provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/quickube/piper/pkg/metrics"
+)
+
+type stubClient struct {
+	Client
+	statusErr error
+}
+
+func (s *stubClient) ListFiles(ctx context.Context, repo string, branch string, path string) ([]string, error) {
+	return []string{"main.yaml"}, nil
+}
+
+func (s *stubClient) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
+	return s.statusErr
+}
+
+func TestInstrumentedClient(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+	client := NewInstrumentedClient(&stubClient{statusErr: errors.New("rate limited")}, metrics.NewRecorder(registry))
+
+	files, err := client.ListFiles(ctx, "my-repo", "main", ".workflows")
+	assert.Nil(err)
+	assert.Equal([]string{"main.yaml"}, files)
+	repo, commit, link, status, message := "my-repo", "1234567", "https://argo.example.com", "success", ""
+	assert.NotNil(client.SetStatus(ctx, &repo, &commit, &link, &status, &message))
+
+	expected := `
+# HELP piper_git_api_calls_total Calls to the git provider API, by method and outcome.
+# TYPE piper_git_api_calls_total counter
+piper_git_api_calls_total{method="ListFiles",outcome="success"} 1
+piper_git_api_calls_total{method="SetStatus",outcome="failure"} 1
+`
+	assert.Nil(testutil.GatherAndCompare(registry, strings.NewReader(expected), "piper_git_api_calls_total"))
+	count, err := testutil.GatherAndCount(registry, "piper_git_api_call_duration_seconds")
+	assert.Nil(err)
+	assert.Equal(2, count)
+}
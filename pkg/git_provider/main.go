@@ -1,27 +1,31 @@
 package git_provider
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 )
 
-func NewGitProviderClient(cfg *conf.GlobalConfig) (Client, error) {
+func NewGitProviderClient(cfg *conf.GlobalConfig, logger *slog.Logger) (Client, error) {
 
 	switch cfg.GitProviderConfig.Provider {
 	case "github":
-		gitClient, err := NewGithubClient(cfg)
+		gitClient, err := NewGithubClient(cfg, logger)
 		if err != nil {
 			return nil, err
 		}
 		return gitClient, nil
 	case "bitbucket":
-		gitClient, err := NewBitbucketServerClient(cfg)
+		gitClient, err := NewBitbucketServerClient(cfg, logger)
 		if err != nil {
 			return nil, err
 		}
 		return gitClient, nil
 	case "gitlab":
-		gitClient, err := NewGitlabClient(cfg)
+		gitClient, err := NewGitlabClient(cfg, logger)
 		if err != nil {
 			return nil, err
 		}
@@ -30,3 +34,13 @@ func NewGitProviderClient(cfg *conf.GlobalConfig) (Client, error) {
 
 	return nil, fmt.Errorf("didn't find matching git provider %s", cfg.GitProviderConfig.Provider)
 }
+
+// WithLogFields returns a copy of ctx carrying the delivery ID, repo, branch and commit of the payload as log fields.
+func WithLogFields(ctx context.Context, payload *WebhookPayload) context.Context {
+	return logging.WithFields(ctx,
+		logging.DeliveryIDKey, payload.DeliveryID,
+		logging.RepoKey, payload.Repo,
+		logging.BranchKey, payload.Branch,
+		logging.CommitKey, payload.Commit,
+	)
+}
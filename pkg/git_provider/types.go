@@ -6,6 +6,12 @@ import (
 	"net/http"
 )
 
+const (
+	// STATUS_NAME is the commit status context of the workflows, BITBUCKET_STATUS_KEY its Bitbucket key.
+	STATUS_NAME          = "Piper/ArgoWorkflows"
+	BITBUCKET_STATUS_KEY = "build"
+)
+
 type HookWithStatus struct {
 	HookID       int64
 	Uuid         string
@@ -28,10 +34,14 @@ type WebhookPayload struct {
 	UserEmail        string   `json:"user_email"`
 	PullRequestURL   string   `json:"pull_request_url"`
 	PullRequestTitle string   `json:"pull_request_title"`
+	PullRequestID    int      `json:"pull_request_id"`
 	DestBranch       string   `json:"dest_branch"`
 	Labels           []string `json:"labels"`
 	HookID           int64    `json:"hookID"`
 	OwnerID          int64    `json:"ownerID"`
+	DeliveryID       string   `json:"deliveryID"`
+	Comment          string   `json:"comment"`
+	CommentAuthor    string   `json:"comment_author"`
 }
 
 type Client interface {
@@ -42,6 +52,10 @@ type Client interface {
 	UnsetWebhook(ctx context.Context, hook *HookWithStatus) error
 	HandlePayload(ctx context.Context, request *http.Request, secret []byte) (*WebhookPayload, error)
 	SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error
+	SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error
 	PingHook(ctx context.Context, hook *HookWithStatus) error
 	GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error)
+	IsCollaborator(ctx context.Context, repo string, user string) (bool, error)
+	CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error)
+	UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error
 }
@@ -0,0 +1,140 @@
+package leader_election
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+func NewElector(cfg *conf.GlobalConfig, logger *slog.Logger) (Elector, error) {
+	if !cfg.LeaderElectionConfig.Enabled {
+		return &singleReplica{}, nil
+	}
+
+	restClientConfig, err := utils.GetClientConfig(cfg.WorkflowServerConfig.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := coordinationv1.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLeaseElector(cfg, client, logger)
+}
+
+// singleReplica leads as long as it runs.
+type singleReplica struct {
+	leading atomic.Bool
+}
+
+func (s *singleReplica) Start(ctx context.Context, lead func(ctx context.Context)) {
+	s.leading.Store(true)
+	lead(ctx)
+	go func() {
+		<-ctx.Done()
+		s.leading.Store(false)
+	}()
+}
+
+func (s *singleReplica) IsLeader() bool {
+	return s.leading.Load()
+}
+
+// leaseElector holds the leadership with a Lease. The Lease is released on shutdown, so another replica takes
+// over within LEADER_ELECTION_RETRY_PERIOD.
+type leaseElector struct {
+	identity string
+	elector  *leaderelection.LeaderElector
+	lead     func(ctx context.Context)
+	leading  atomic.Bool
+	logger   *slog.Logger
+}
+
+func newLeaseElector(cfg *conf.GlobalConfig, client coordinationv1.LeasesGetter, logger *slog.Logger) (*leaseElector, error) {
+	identity := cfg.LeaderElectionConfig.Identity
+	if identity == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the leader election identity, error: %v", err)
+		}
+		identity = hostname
+	}
+	namespace := cfg.LeaderElectionConfig.LeaseNamespace
+	if namespace == "" {
+		namespace = cfg.WorkflowServerConfig.Namespace
+	}
+
+	e := &leaseElector{identity: identity, logger: logger.With("component", "leader_election", "identity", identity)}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      cfg.LeaderElectionConfig.LeaseName,
+				Namespace: namespace,
+			},
+			Client:     client,
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		Name:            cfg.LeaderElectionConfig.LeaseName,
+		LeaseDuration:   cfg.LeaderElectionConfig.LeaseDuration,
+		RenewDeadline:   cfg.LeaderElectionConfig.RenewDeadline,
+		RetryPeriod:     cfg.LeaderElectionConfig.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: e.startedLeading,
+			OnStoppedLeading: e.stoppedLeading,
+			OnNewLeader:      e.newLeader,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader election configuration, error: %v", err)
+	}
+	e.elector = elector
+
+	return e, nil
+}
+
+func (e *leaseElector) Start(ctx context.Context, lead func(ctx context.Context)) {
+	e.lead = lead
+	go func() {
+		// Run returns when the leadership is lost, the replica then campaigns again
+		for ctx.Err() == nil {
+			e.elector.Run(ctx)
+		}
+		e.logger.Info("context canceled, exiting")
+	}()
+}
+
+func (e *leaseElector) IsLeader() bool {
+	return e.leading.Load()
+}
+
+// startedLeading runs the work of the leader. leaderCtx is canceled as soon as the Lease is not renewed,
+// before another replica can acquire it.
+func (e *leaseElector) startedLeading(leaderCtx context.Context) {
+	e.leading.Store(true)
+	e.logger.Info("started leading")
+	e.lead(leaderCtx)
+}
+
+func (e *leaseElector) stoppedLeading() {
+	if e.leading.Swap(false) {
+		e.logger.Info("stopped leading")
+	}
+}
+
+func (e *leaseElector) newLeader(identity string) {
+	if identity != e.identity {
+		e.logger.Info("new leader elected", "leader", identity)
+	}
+}
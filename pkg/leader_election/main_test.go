@@ -0,0 +1,88 @@
+package leader_election
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+func newTestConfig(identity string) *conf.GlobalConfig {
+	return &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "piper"},
+		LeaderElectionConfig: conf.LeaderElectionConfig{
+			Enabled:       true,
+			LeaseName:     "piper-leader",
+			Identity:      identity,
+			LeaseDuration: 2 * time.Second,
+			RenewDeadline: 1 * time.Second,
+			RetryPeriod:   100 * time.Millisecond,
+		},
+	}
+}
+
+func TestSingleReplica(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	elector, err := NewElector(&conf.GlobalConfig{}, slog.Default())
+	assert.Nil(err)
+
+	led := false
+	elector.Start(ctx, func(ctx context.Context) { led = true })
+	assert.True(led)
+	assert.True(elector.IsLeader())
+
+	cancel()
+	assert.Eventually(func() bool { return !elector.IsLeader() }, time.Second, 10*time.Millisecond)
+}
+
+func TestLeaseElectorHandover(t *testing.T) {
+	assert := assertion.New(t)
+	client := fake.NewSimpleClientset().CoordinationV1()
+
+	first, err := newLeaseElector(newTestConfig("piper-0"), client, slog.Default())
+	assert.Nil(err)
+	second, err := newLeaseElector(newTestConfig("piper-1"), client, slog.Default())
+	assert.Nil(err)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	defer stopFirst()
+	firstLead := make(chan context.Context, 1)
+	first.Start(firstCtx, func(ctx context.Context) { firstLead <- ctx })
+
+	var leaderCtx context.Context
+	select {
+	case leaderCtx = <-firstLead:
+	case <-time.After(5 * time.Second):
+		t.Fatal("piper-0 did not acquire the lease")
+	}
+	assert.True(first.IsLeader())
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+	secondLead := make(chan context.Context, 1)
+	second.Start(secondCtx, func(ctx context.Context) { secondLead <- ctx })
+	time.Sleep(500 * time.Millisecond)
+	assert.False(second.IsLeader())
+
+	// The lease is released on shutdown, the other replica takes over before it expires
+	stopFirst()
+	select {
+	case <-leaderCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the leader context of piper-0 was not canceled")
+	}
+	select {
+	case <-secondLead:
+	case <-time.After(time.Second):
+		t.Fatal("piper-1 did not take over the lease")
+	}
+	assert.True(second.IsLeader())
+	assert.Eventually(func() bool { return !first.IsLeader() }, time.Second, 10*time.Millisecond)
+}
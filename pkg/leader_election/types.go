@@ -0,0 +1,13 @@
+package leader_election
+
+import (
+	"context"
+)
+
+// Elector runs the work of a single replica, the leader, such as reporting the workflows phases and
+// reconciling the webhooks.
+type Elector interface {
+	// Start campaigns until ctx is done, and calls lead with a context canceled when the leadership is lost.
+	Start(ctx context.Context, lead func(ctx context.Context))
+	IsLeader() bool
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+// The request-scoped fields carried through the context.
+const (
+	DeliveryIDKey = "delivery_id"
+	EventIDKey    = "event_id"
+	RepoKey       = "repo"
+	BranchKey     = "branch"
+	CommitKey     = "commit"
+	WorkflowKey   = "workflow"
+	TraceIDKey    = "trace_id"
+)
+
+type fieldsKey struct{}
+
+// New returns a logger writing to w with the configured level and format. The fields set on the context with
+// WithFields and the trace ID of its span are added to the records logged with a context.
+func New(w io.Writer, cfg *conf.GlobalConfig) (*slog.Logger, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(cfg.LogConfig.Level))
+	if err != nil {
+		return nil, fmt.Errorf("invalid log level %s, error: %v", cfg.LogConfig.Level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.LogConfig.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unsupported log format %s, supported formats are text and json", cfg.LogConfig.Format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// OrDefault returns logger, or the default logger when it is nil.
+func OrDefault(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
+
+// WithFields returns a copy of ctx carrying the key-value pairs of args, replacing the fields of the same keys.
+// Empty values are skipped.
+func WithFields(ctx context.Context, args ...any) context.Context {
+	current, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	added := make([]slog.Attr, 0, len(args)/2)
+	keys := make(map[string]bool)
+	for _, attr := range slog.Group("", args...).Value.Group() {
+		if attr.Value.Kind() == slog.KindString && attr.Value.String() == "" {
+			continue
+		}
+		added = append(added, attr)
+		keys[attr.Key] = true
+	}
+
+	fields := make([]slog.Attr, 0, len(current)+len(added))
+	for _, attr := range current {
+		if !keys[attr.Key] {
+			fields = append(fields, attr)
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, append(fields, added...))
+}
+
+// contextHandler adds the fields of the context of a record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if fields, ok := ctx.Value(fieldsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(fields...)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String(TraceIDKey, spanContext.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
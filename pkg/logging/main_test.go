@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+func TestNew(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{name: "Default format", level: "info", format: ""},
+		{name: "Text format", level: "debug", format: "text"},
+		{name: "JSON format", level: "warn", format: "JSON"},
+		{name: "Invalid level", level: "verbose", format: "text", wantErr: true},
+		{name: "Unsupported format", level: "info", format: "xml", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &conf.GlobalConfig{LogConfig: conf.LogConfig{Level: test.level, Format: test.format}}
+			logger, err := New(&bytes.Buffer{}, cfg)
+			if test.wantErr {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.NotNil(logger)
+		})
+	}
+}
+
+func TestContextFields(t *testing.T) {
+	assert := assertion.New(t)
+	buf := &bytes.Buffer{}
+	cfg := &conf.GlobalConfig{LogConfig: conf.LogConfig{Level: "info", Format: "json"}}
+	logger, err := New(buf, cfg)
+	assert.Nil(err)
+
+	ctx := WithFields(context.Background(), DeliveryIDKey, "1234", RepoKey, "test-repo", BranchKey, "main")
+	ctx = WithFields(ctx, BranchKey, "feature", CommitKey, "", WorkflowKey, "test-workflow")
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(ctx, "test")
+	defer span.End()
+
+	logger.DebugContext(ctx, "skipped")
+	assert.Zero(buf.Len())
+
+	logger.InfoContext(ctx, "handled")
+	record := make(map[string]any)
+	assert.Nil(json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal("handled", record["msg"])
+	assert.Equal("1234", record[DeliveryIDKey])
+	assert.Equal("test-repo", record[RepoKey])
+	assert.Equal("feature", record[BranchKey])
+	assert.Equal("test-workflow", record[WorkflowKey])
+	assert.NotContains(record, CommitKey)
+	assert.Equal(span.SpanContext().TraceID().String(), record[TraceIDKey])
+}
+
+func TestOrDefault(t *testing.T) {
+	assert := assertion.New(t)
+	assert.NotNil(OrDefault(nil))
+
+	logger, err := New(&bytes.Buffer{}, &conf.GlobalConfig{LogConfig: conf.LogConfig{Level: "info"}})
+	assert.Nil(err)
+	assert.Same(logger, OrDefault(logger))
+}
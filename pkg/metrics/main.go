@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+type prometheusRecorder struct {
+	webhooksReceived   *prometheus.CounterVec
+	triggerMatches     *prometheus.CounterVec
+	workflowsSubmitted *prometheus.CounterVec
+	workflowsFailed    *prometheus.CounterVec
+	gitAPICalls        *prometheus.CounterVec
+	gitAPIDuration     *prometheus.HistogramVec
+	notificationsSent  *prometheus.CounterVec
+	hookHealth         *prometheus.GaugeVec
+	eventHandlerLag    prometheus.Histogram
+}
+
+// NewRecorder registers the metrics of Piper in registerer.
+func NewRecorder(registerer prometheus.Registerer) Recorder {
+	r := &prometheusRecorder{
+		webhooksReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_webhooks_received_total",
+			Help: "Webhook deliveries received, by git provider, event and outcome.",
+		}, []string{"provider", "event", "outcome"}),
+		triggerMatches: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_trigger_matches_total",
+			Help: "Triggers matching a webhook event, by repo and event.",
+		}, []string{"repo", "event"}),
+		workflowsSubmitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_workflows_submitted_total",
+			Help: "Workflows submitted, by repo and config.",
+		}, []string{"repo", "config"}),
+		workflowsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_workflows_failed_total",
+			Help: "Workflows that failed to be rendered or submitted, by repo and config.",
+		}, []string{"repo", "config"}),
+		gitAPICalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_git_api_calls_total",
+			Help: "Calls to the git provider API, by method and outcome.",
+		}, []string{"method", "outcome"}),
+		gitAPIDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "piper_git_api_call_duration_seconds",
+			Help:    "Latency of the calls to the git provider API, by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method"}),
+		notificationsSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "piper_notifications_total",
+			Help: "Workflow phases sent to the notification sinks, by sink and outcome. The git sink is the commit status.",
+		}, []string{"sink", "outcome"}),
+		hookHealth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "piper_webhook_healthy",
+			Help: "Health of the webhook of each repo, 1 when healthy.",
+		}, []string{"repo"}),
+		eventHandlerLag: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "piper_event_handler_lag_seconds",
+			Help:    "Time from a workflow phase change to its notification.",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 30, 60, 300, 900},
+		}),
+	}
+	registerer.MustRegister(
+		r.webhooksReceived,
+		r.triggerMatches,
+		r.workflowsSubmitted,
+		r.workflowsFailed,
+		r.gitAPICalls,
+		r.gitAPIDuration,
+		r.notificationsSent,
+		r.hookHealth,
+		r.eventHandlerLag,
+	)
+	return r
+}
+
+// Handler serves the metrics of gatherer in the Prometheus format.
+func Handler(gatherer prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})
+}
+
+func (r *prometheusRecorder) WebhookReceived(provider string, event string, outcome string) {
+	r.webhooksReceived.WithLabelValues(provider, event, outcome).Inc()
+}
+
+func (r *prometheusRecorder) TriggerMatched(repo string, event string) {
+	r.triggerMatches.WithLabelValues(repo, event).Inc()
+}
+
+func (r *prometheusRecorder) WorkflowSubmitted(repo string, config string, err error) {
+	if err != nil {
+		r.workflowsFailed.WithLabelValues(repo, config).Inc()
+		return
+	}
+	r.workflowsSubmitted.WithLabelValues(repo, config).Inc()
+}
+
+func (r *prometheusRecorder) GitAPICall(method string, duration time.Duration, err error) {
+	r.gitAPICalls.WithLabelValues(method, outcome(err)).Inc()
+	r.gitAPIDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+func (r *prometheusRecorder) NotificationSent(sink string, err error) {
+	r.notificationsSent.WithLabelValues(sink, outcome(err)).Inc()
+}
+
+func (r *prometheusRecorder) HookHealth(repo string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	r.hookHealth.WithLabelValues(repo).Set(value)
+}
+
+func (r *prometheusRecorder) HookRemoved(repo string) {
+	r.hookHealth.DeleteLabelValues(repo)
+}
+
+func (r *prometheusRecorder) EventHandlerLag(lag time.Duration) {
+	r.eventHandlerLag.Observe(lag.Seconds())
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// OrNoop returns recorder, or a recorder dropping the metrics when it is nil.
+func OrNoop(recorder Recorder) Recorder {
+	if recorder == nil {
+		return noopRecorder{}
+	}
+	return recorder
+}
+
+type noopRecorder struct{}
+
+func (noopRecorder) WebhookReceived(provider string, event string, outcome string) {}
+func (noopRecorder) TriggerMatched(repo string, event string)                      {}
+func (noopRecorder) WorkflowSubmitted(repo string, config string, err error)       {}
+func (noopRecorder) GitAPICall(method string, duration time.Duration, err error)   {}
+func (noopRecorder) NotificationSent(sink string, err error)                       {}
+func (noopRecorder) HookHealth(repo string, healthy bool)                          {}
+func (noopRecorder) HookRemoved(repo string)                                       {}
+func (noopRecorder) EventHandlerLag(lag time.Duration)                             {}
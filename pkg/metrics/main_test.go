@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	assert := assertion.New(t)
+	registry := prometheus.NewRegistry()
+	recorder := NewRecorder(registry).(*prometheusRecorder)
+
+	recorder.WebhookReceived("github", "push", "accepted")
+	recorder.WebhookReceived("github", "push", "accepted")
+	recorder.WebhookReceived("github", "", "invalid")
+	recorder.TriggerMatched("my-repo", "pull_request")
+	recorder.WorkflowSubmitted("my-repo", "default", nil)
+	recorder.WorkflowSubmitted("my-repo", "default", errors.New("lint failed"))
+	recorder.GitAPICall("GetFile", 200*time.Millisecond, nil)
+	recorder.GitAPICall("GetFile", time.Second, errors.New("rate limited"))
+	recorder.NotificationSent("git", nil)
+	recorder.HookHealth("my-repo", true)
+	recorder.HookHealth("other-repo", false)
+	recorder.HookRemoved("other-repo")
+	recorder.EventHandlerLag(3 * time.Second)
+
+	assert.Equal(2.0, testutil.ToFloat64(recorder.webhooksReceived.WithLabelValues("github", "push", "accepted")))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.webhooksReceived.WithLabelValues("github", "", "invalid")))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.triggerMatches.WithLabelValues("my-repo", "pull_request")))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.workflowsSubmitted.WithLabelValues("my-repo", "default")))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.workflowsFailed.WithLabelValues("my-repo", "default")))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.gitAPICalls.WithLabelValues("GetFile", OutcomeSuccess)))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.gitAPICalls.WithLabelValues("GetFile", OutcomeFailure)))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.notificationsSent.WithLabelValues("git", OutcomeSuccess)))
+	assert.Equal(1.0, testutil.ToFloat64(recorder.hookHealth.WithLabelValues("my-repo")))
+	assert.Equal(1, testutil.CollectAndCount(recorder.hookHealth))
+	assert.Equal(1, testutil.CollectAndCount(recorder.eventHandlerLag))
+
+	response := httptest.NewRecorder()
+	Handler(registry).ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(http.StatusOK, response.Code)
+	assert.True(strings.Contains(response.Body.String(), `piper_webhooks_received_total{event="push",outcome="accepted",provider="github"} 2`))
+}
+
+func TestOrNoop(t *testing.T) {
+	assert := assertion.New(t)
+	assert.Equal(noopRecorder{}, OrNoop(nil))
+	recorder := NewRecorder(prometheus.NewRegistry())
+	assert.Equal(recorder, OrNoop(recorder))
+}
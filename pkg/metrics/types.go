@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Recorder records the metrics of Piper. The packages record through it, so tests can use their own registry.
+type Recorder interface {
+	// WebhookReceived counts a webhook delivery by git provider, event and outcome.
+	WebhookReceived(provider string, event string, outcome string)
+	// TriggerMatched counts a trigger of the repo matching a webhook event.
+	TriggerMatched(repo string, event string)
+	// WorkflowSubmitted counts a submitted workflow, or a failed submission when err is set.
+	WorkflowSubmitted(repo string, config string, err error)
+	// GitAPICall observes a call to the git provider API.
+	GitAPICall(method string, duration time.Duration, err error)
+	// NotificationSent counts a workflow phase sent to a sink, "git" being the commit status.
+	NotificationSent(sink string, err error)
+	// HookHealth sets the health of the webhook of the repo, HookRemoved drops it.
+	HookHealth(repo string, healthy bool)
+	HookRemoved(repo string)
+	// EventHandlerLag observes the time from a workflow phase change to its notification.
+	EventHandlerLag(lag time.Duration)
+}
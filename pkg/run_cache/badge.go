@@ -0,0 +1,85 @@
+package run_cache
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+const (
+	badgeLabel = "piper"
+	// badgeCharWidth approximates the width of a character of the 11px Verdana text of the badge
+	badgeCharWidth = 7
+	badgePadding   = 10
+)
+
+var badgeColors = map[v1alpha1.WorkflowPhase]string{
+	v1alpha1.WorkflowSucceeded: "#4c1",
+	v1alpha1.WorkflowFailed:    "#e05d44",
+	v1alpha1.WorkflowError:     "#e05d44",
+	v1alpha1.WorkflowRunning:   "#007ec6",
+	v1alpha1.WorkflowPending:   "#dfb317",
+	v1alpha1.WorkflowUnknown:   "#dfb317",
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(
+	`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">` +
+		`<title>{{.Label}}: {{.Message}}</title>` +
+		`<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+		`<clipPath id="r"><rect width="{{.Width}}" height="20" rx="3" fill="#fff"/></clipPath>` +
+		`<g clip-path="url(#r)"><rect width="{{.LabelWidth}}" height="20" fill="#555"/>` +
+		`<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>` +
+		`<rect width="{{.Width}}" height="20" fill="url(#s)"/></g>` +
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+		`<text x="{{.LabelX}}" y="15" fill="#010101" fill-opacity=".3">{{.Label}}</text><text x="{{.LabelX}}" y="14">{{.Label}}</text>` +
+		`<text x="{{.MessageX}}" y="15" fill="#010101" fill-opacity=".3">{{.Message}}</text><text x="{{.MessageX}}" y="14">{{.Message}}</text>` +
+		`</g></svg>`,
+))
+
+type badge struct {
+	Label        string
+	Message      string
+	Color        string
+	LabelWidth   int
+	MessageWidth int
+	Width        int
+	LabelX       int
+	MessageX     int
+}
+
+// Badge renders an SVG badge of the phase of the run, or of unknown when run is nil.
+func Badge(run *Run) ([]byte, error) {
+	message := "unknown"
+	color := "#9f9f9f"
+	if run != nil {
+		// Workflows not picked up by the Argo Workflows controller yet have no phase
+		phase := run.Phase
+		if phase == "" {
+			phase = v1alpha1.WorkflowPending
+		}
+		message = strings.ToLower(string(phase))
+		if phaseColor, ok := badgeColors[phase]; ok {
+			color = phaseColor
+		}
+	}
+
+	b := badge{
+		Label:        badgeLabel,
+		Message:      message,
+		Color:        color,
+		LabelWidth:   len(badgeLabel)*badgeCharWidth + badgePadding,
+		MessageWidth: len(message)*badgeCharWidth + badgePadding,
+	}
+	b.Width = b.LabelWidth + b.MessageWidth
+	b.LabelX = b.LabelWidth / 2
+	b.MessageX = b.LabelWidth + b.MessageWidth/2
+
+	var buf bytes.Buffer
+	err := badgeTemplate.Execute(&buf, b)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
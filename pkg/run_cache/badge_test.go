@@ -0,0 +1,34 @@
+package run_cache
+
+import (
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestBadge(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		name        string
+		run         *Run
+		wantMessage string
+		wantColor   string
+	}{
+		{name: "Succeeded run", run: &Run{Phase: v1alpha1.WorkflowSucceeded}, wantMessage: "succeeded", wantColor: "#4c1"},
+		{name: "Failed run", run: &Run{Phase: v1alpha1.WorkflowFailed}, wantMessage: "failed", wantColor: "#e05d44"},
+		{name: "Running run", run: &Run{Phase: v1alpha1.WorkflowRunning}, wantMessage: "running", wantColor: "#007ec6"},
+		{name: "Run without phase", run: &Run{}, wantMessage: "pending", wantColor: "#dfb317"},
+		{name: "No run", run: nil, wantMessage: "unknown", wantColor: "#9f9f9f"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			badge, err := Badge(test.run)
+			assert.Nil(err)
+			assert.Contains(string(badge), `aria-label="piper: `+test.wantMessage+`"`)
+			assert.Contains(string(badge), `fill="`+test.wantColor+`"`)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package run_cache
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/event_handler"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/workflow_handler"
+)
+
+const repoIndex = "repo"
+
+// runCache keeps the labels and status of the workflows created by Piper, indexed by repo, so the runs are
+// served without requests to the Kubernetes API.
+type runCache struct {
+	logger   *slog.Logger
+	informer cache.SharedIndexInformer
+}
+
+// NewRunCache returns a cache of the workflows created by Piper. Unlike the event handler, it runs on every replica.
+func NewRunCache(clients *clients.Clients) RunCache {
+	// The list and watch requests are stopped by the informer, with its stop channel
+	listWatch := workflow_handler.NewListWatch(context.Background(), clients.Workflows, &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: event_handler.NOTIFIED_LABEL,
+				Operator: metav1.LabelSelectorOpExists},
+		},
+	})
+	return newRunCache(logging.OrDefault(clients.Logger), listWatch)
+}
+
+func newRunCache(logger *slog.Logger, listWatch cache.ListerWatcher) *runCache {
+	informer := cache.NewSharedIndexInformer(listWatch, &v1alpha1.Workflow{}, 0, cache.Indexers{
+		repoIndex: func(obj interface{}) ([]string, error) {
+			return []string{obj.(*v1alpha1.Workflow).GetLabels()["repo"]}, nil
+		},
+	})
+	// The informer is not started yet, so setting the transform doesn't fail
+	_ = informer.SetTransform(stripWorkflow)
+
+	return &runCache{
+		logger:   logger.With("component", "run_cache"),
+		informer: informer,
+	}
+}
+
+func (rc *runCache) Start(ctx context.Context) {
+	go rc.informer.Run(ctx.Done())
+
+	go func() {
+		if !cache.WaitForCacheSync(ctx.Done(), rc.informer.HasSynced) {
+			rc.logger.Error("failed to sync the runs cache")
+			return
+		}
+		rc.logger.Info("runs cache synced")
+	}()
+}
+
+func (rc *runCache) HasSynced() bool {
+	return rc.informer.HasSynced()
+}
+
+func (rc *runCache) List(filter Filter, limit int) []*Run {
+	repo := workflow_handler.ConvertToValidString(filter.Repo)
+	branch := workflow_handler.ConvertToValidString(filter.Branch)
+	event := workflow_handler.ConvertToValidString(filter.Event)
+
+	objs, err := rc.informer.GetIndexer().ByIndex(repoIndex, repo)
+	if err != nil {
+		rc.logger.Error("failed to list the cached runs", logging.RepoKey, repo, "error", err)
+		return []*Run{}
+	}
+
+	runs := make([]*Run, 0, len(objs))
+	for _, obj := range objs {
+		workflow := obj.(*v1alpha1.Workflow)
+		labels := workflow.GetLabels()
+		if (branch != "" && labels["branch"] != branch) || (event != "" && labels["event"] != event) ||
+			(filter.Phase != "" && !strings.EqualFold(string(workflow.Status.Phase), filter.Phase)) {
+			continue
+		}
+		runs = append(runs, newRun(workflow))
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		if runs[i].CreatedAt.Equal(runs[j].CreatedAt) {
+			return runs[i].Name > runs[j].Name
+		}
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	if limit > 0 && len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs
+}
+
+func (rc *runCache) Latest(repo string, branch string) *Run {
+	runs := rc.List(Filter{Repo: repo, Branch: branch}, 1)
+	if len(runs) == 0 {
+		return nil
+	}
+	return runs[0]
+}
+
+// stripWorkflow keeps the fields of the cached workflows the runs are made of, since workflows with their
+// templates and node statuses are large.
+func stripWorkflow(obj interface{}) (interface{}, error) {
+	workflow, ok := obj.(*v1alpha1.Workflow)
+	if !ok {
+		return obj, nil
+	}
+	return &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              workflow.GetName(),
+			Namespace:         workflow.GetNamespace(),
+			UID:               workflow.GetUID(),
+			ResourceVersion:   workflow.GetResourceVersion(),
+			CreationTimestamp: workflow.GetCreationTimestamp(),
+			Labels:            workflow.GetLabels(),
+		},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:      workflow.Status.Phase,
+			Message:    workflow.Status.Message,
+			StartedAt:  workflow.Status.StartedAt,
+			FinishedAt: workflow.Status.FinishedAt,
+		},
+	}, nil
+}
+
+func newRun(workflow *v1alpha1.Workflow) *Run {
+	labels := workflow.GetLabels()
+	return &Run{
+		Name:       workflow.GetName(),
+		Namespace:  workflow.GetNamespace(),
+		Repo:       labels["repo"],
+		Branch:     labels["branch"],
+		Commit:     labels["commit"],
+		User:       labels["user"],
+		Event:      labels["event"],
+		Phase:      workflow.Status.Phase,
+		Message:    workflow.Status.Message,
+		CreatedAt:  workflow.GetCreationTimestamp().Time,
+		StartedAt:  timeOrNil(workflow.Status.StartedAt),
+		FinishedAt: timeOrNil(workflow.Status.FinishedAt),
+	}
+}
+
+func timeOrNil(t metav1.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,125 @@
+package run_cache
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+var now = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newWorkflow(name string, repo string, branch string, event string, phase v1alpha1.WorkflowPhase, age time.Duration) *v1alpha1.Workflow {
+	return &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "workflows",
+			ResourceVersion:   "1",
+			CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			Labels: map[string]string{
+				"piper.quickube.com/notified": string(phase),
+				"repo":                        repo,
+				"branch":                      branch,
+				"commit":                      "1234567",
+				"user":                        "my-user",
+				"event":                       event,
+			},
+		},
+		Spec:   v1alpha1.WorkflowSpec{Entrypoint: "main"},
+		Status: v1alpha1.WorkflowStatus{Phase: phase},
+	}
+}
+
+func TestRunCache(t *testing.T) {
+	assert := assertion.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	watcher := watch.NewFake()
+	listWatch := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return &v1alpha1.WorkflowList{Items: []v1alpha1.Workflow{
+				*newWorkflow("main-1", "my-repo", "main", "push", v1alpha1.WorkflowFailed, 3*time.Hour),
+				*newWorkflow("main-2", "my-repo", "main", "push", v1alpha1.WorkflowSucceeded, 2*time.Hour),
+				*newWorkflow("feature-1", "my-repo", "featuretest", "pull-request", v1alpha1.WorkflowSucceeded, time.Hour),
+				*newWorkflow("other-1", "other-repo", "main", "push", v1alpha1.WorkflowRunning, time.Minute),
+			}}, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}
+
+	runs := newRunCache(slog.Default(), listWatch)
+	assert.False(runs.HasSynced())
+	runs.Start(ctx)
+	assert.Eventually(runs.HasSynced, 5*time.Second, 50*time.Millisecond)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		limit  int
+		want   []string
+	}{
+		{name: "Runs of a repo, newest first", filter: Filter{Repo: "my-repo"}, want: []string{"feature-1", "main-2", "main-1"}},
+		{name: "Limit", filter: Filter{Repo: "my-repo"}, limit: 1, want: []string{"feature-1"}},
+		{name: "Branch names are converted like the labels", filter: Filter{Repo: "My-Repo", Branch: "feature/test"}, want: []string{"feature-1"}},
+		{name: "Event", filter: Filter{Repo: "my-repo", Event: "pull_request"}, want: []string{"feature-1"}},
+		{name: "Phase", filter: Filter{Repo: "my-repo", Phase: "failed"}, want: []string{"main-1"}},
+		{name: "Unknown repo", filter: Filter{Repo: "unknown"}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			names := make([]string, 0)
+			for _, run := range runs.List(test.filter, test.limit) {
+				names = append(names, run.Name)
+			}
+			assert.Equal(test.want, names)
+		})
+	}
+
+	t.Run("Runs are described by the workflow labels", func(t *testing.T) {
+		run := runs.Latest("my-repo", "main")
+		assert.NotNil(run)
+		assert.Equal(&Run{
+			Name:      "main-2",
+			Namespace: "workflows",
+			Repo:      "my-repo",
+			Branch:    "main",
+			Commit:    "1234567",
+			User:      "my-user",
+			Event:     "push",
+			Phase:     v1alpha1.WorkflowSucceeded,
+			CreatedAt: now.Add(-2 * time.Hour),
+		}, run)
+		assert.Nil(runs.Latest("my-repo", "unknown"))
+	})
+
+	t.Run("Watch events update the runs", func(t *testing.T) {
+		watcher.Add(newWorkflow("main-3", "my-repo", "main", "push", v1alpha1.WorkflowRunning, 0))
+		assert.Eventually(func() bool {
+			latest := runs.Latest("my-repo", "main")
+			return latest != nil && latest.Name == "main-3"
+		}, 5*time.Second, 50*time.Millisecond)
+
+		watcher.Delete(newWorkflow("main-3", "my-repo", "main", "push", v1alpha1.WorkflowRunning, 0))
+		assert.Eventually(func() bool {
+			return runs.Latest("my-repo", "main").Name == "main-2"
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("Only the run fields are cached", func(t *testing.T) {
+		obj, exists, err := runs.informer.GetIndexer().GetByKey("workflows/main-1")
+		assert.Nil(err)
+		assert.True(exists)
+		assert.Empty(obj.(*v1alpha1.Workflow).Spec.Entrypoint)
+	})
+}
@@ -0,0 +1,43 @@
+package run_cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+)
+
+// Run is a workflow submitted by Piper, described by the labels it was created with and its phase.
+type Run struct {
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace"`
+	Repo       string                 `json:"repo"`
+	Branch     string                 `json:"branch"`
+	Commit     string                 `json:"commit"`
+	User       string                 `json:"user"`
+	Event      string                 `json:"event,omitempty"`
+	Phase      v1alpha1.WorkflowPhase `json:"phase"`
+	Message    string                 `json:"message,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	StartedAt  *time.Time             `json:"startedAt,omitempty"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+}
+
+// Filter selects the runs of a repo. Empty fields match every run.
+type Filter struct {
+	Repo   string
+	Branch string
+	Event  string
+	Phase  string
+}
+
+type RunCache interface {
+	// Start caches the workflows until ctx is done.
+	Start(ctx context.Context)
+	// HasSynced reports whether the existing workflows were listed.
+	HasSynced() bool
+	// List returns up to limit runs matching the filter, newest first. 0 returns all of them.
+	List(filter Filter, limit int) []*Run
+	// Latest returns the newest run of the branch of the repo, or nil when there is none.
+	Latest(repo string, branch string) *Run
+}
@@ -3,17 +3,23 @@ package server
 import (
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
 	"golang.org/x/net/context"
-	"log"
+	"os"
 )
 
 func Start(ctx context.Context, stop context.CancelFunc, cfg *conf.GlobalConfig, clients *clients.Clients) {
 
-	srv := NewServer(cfg, clients)
+	logger := logging.OrDefault(clients.Logger)
+	srv, err := NewServer(cfg, clients)
+	if err != nil {
+		logger.Error("failed to create the server", "error", err)
+		os.Exit(1)
+	}
 	gracefulShutdownHandler := NewGracefulShutdown(ctx, stop)
 	srv.Start(ctx)
 
 	gracefulShutdownHandler.Shutdown(srv)
 
-	log.Println("Server exiting")
+	logger.Info("server exiting")
 }
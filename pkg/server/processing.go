@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/event_store"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/tracing"
+	"github.com/quickube/piper/pkg/utils"
+	"github.com/quickube/piper/pkg/webhook_handler"
+	"github.com/quickube/piper/pkg/webhook_queue"
+)
+
+// processWebhook processes a queued webhook and records the outcome of every attempt in the event store.
+// Every attempt is a span in the trace of the webhook.
+func (s *Server) processWebhook(ctx context.Context, item *webhook_queue.Item) (err error) {
+	ctx, span := tracing.Tracer().Start(trace.ContextWithRemoteSpanContext(ctx, item.SpanContext), "ProcessWebhook",
+		trace.WithAttributes(attribute.String("repo", item.Payload.Repo), attribute.String("commit", item.Payload.Commit),
+			attribute.String("event_id", item.EventID), attribute.Int("attempt", item.Attempts)))
+	defer func() { tracing.End(span, err) }()
+	ctx = logging.WithFields(git_provider.WithLogFields(ctx, item.Payload), logging.EventIDKey, item.EventID)
+
+	event, err := s.eventStore.Get(item.EventID)
+	if err != nil {
+		// The event might have been evicted from the store, the webhook is processed anyway
+		s.logger.WarnContext(ctx, "failed to get event, processing the webhook anyway", "error", err)
+		event = nil
+	}
+	s.recordEvent(ctx, event, func(event *event_store.Event) {
+		event.Status = event_store.EventProcessing
+		event.Attempts = item.Attempts
+	})
+
+	result, err := webhook_handler.ProcessWebhook(ctx, s.config, s.clients, item.Payload)
+	s.recordEvent(ctx, event, func(event *event_store.Event) {
+		event.Triggers = result.Triggers
+		// Workflows submitted by previous attempts are skipped as duplicates, so they are kept
+		for _, name := range result.Workflows {
+			if !utils.IsElementExists(event.Workflows, name) {
+				event.Workflows = append(event.Workflows, name)
+			}
+		}
+		event.Errors = result.Errors
+		if err != nil {
+			event.Errors = append(event.Errors, err.Error())
+			event.Status = event_store.EventFailed
+		} else {
+			event.Status = event_store.EventSucceeded
+		}
+	})
+
+	// Retrying can't fix the triggers of the repo, unlike the failures of the git provider and Argo Workflows APIs
+	if errors.Is(err, webhook_handler.ErrInvalidTriggers) || errors.Is(err, webhook_handler.ErrNoTriggers) {
+		return webhook_queue.Permanent(err)
+	}
+	return err
+}
+
+func (s *Server) recordEvent(ctx context.Context, event *event_store.Event, update func(event *event_store.Event)) {
+	if event == nil {
+		return
+	}
+
+	update(event)
+	event.UpdatedAt = time.Now().UTC()
+	err := s.eventStore.Save(event)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to record event", logging.EventIDKey, event.ID, "error", err)
+	}
+}
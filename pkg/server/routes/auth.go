@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/conf"
+)
+
+// APITokenAuth guards the API routes with the PIPER_API_TOKEN bearer token. The API replays events and submits
+// workflows, so it is disabled when no token is configured.
+func APITokenAuth(cfg *conf.GlobalConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cfg.ApiConfig.Token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "the API is disabled, PIPER_API_TOKEN is not configured"})
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.ApiConfig.Token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+func TestAPITokenAuth(t *testing.T) {
+	assert := assertion.New(t)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "No token configured", token: "", authorization: "", wantStatus: http.StatusForbidden},
+		{name: "No token configured with a bearer", token: "", authorization: "Bearer ", wantStatus: http.StatusForbidden},
+		{name: "Missing token", token: "secret", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "Wrong token", token: "secret", authorization: "Bearer wrong", wantStatus: http.StatusUnauthorized},
+		{name: "Valid token", token: "secret", authorization: "Bearer secret", wantStatus: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			router := gin.New()
+			cfg := &conf.GlobalConfig{ApiConfig: conf.ApiConfig{Token: test.token}}
+			router.GET("/api/v1/queue", APITokenAuth(cfg), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			request := httptest.NewRequest(http.MethodGet, "/api/v1/queue", nil)
+			if test.authorization != "" {
+				request.Header.Set("Authorization", test.authorization)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+			assert.Equal(test.wantStatus, recorder.Code)
+		})
+	}
+}
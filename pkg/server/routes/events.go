@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/event_store"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/webhook_queue"
+)
+
+const defaultEventsLimit = 50
+
+func AddEventsRoutes(store event_store.EventStore, queue webhook_queue.WebhookQueue, rg *gin.RouterGroup, logger *slog.Logger) {
+	events := rg.Group("/events")
+
+	events.GET("", func(c *gin.Context) {
+		limit := defaultEventsLimit
+		if value := c.Query("limit"); value != "" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative number"})
+				return
+			}
+		}
+
+		list, err := store.List(limit)
+		if err != nil {
+			logger.ErrorContext(c.Request.Context(), "failed to list events", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"events": list})
+	})
+
+	events.GET("/:id", func(c *gin.Context) {
+		event, err := store.Get(c.Param("id"))
+		if err != nil {
+			abortWithEventError(c, logger, err)
+			return
+		}
+		c.JSON(http.StatusOK, event)
+	})
+
+	events.POST("/:id/replay", func(c *gin.Context) {
+		event, err := store.Get(c.Param("id"))
+		if err != nil {
+			abortWithEventError(c, logger, err)
+			return
+		}
+
+		replay, err := acceptWebhook(c.Request.Context(), logger, store, queue, event.Payload, event.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, replay)
+	})
+}
+
+// acceptWebhook records the payload in the event store and puts it on the queue.
+// Failing to record the event doesn't reject the webhook, so store failures don't lose deliveries.
+func acceptWebhook(ctx context.Context, logger *slog.Logger, store event_store.EventStore, queue webhook_queue.WebhookQueue, payload *git_provider.WebhookPayload, replayOf string) (*event_store.Event, error) {
+	event, err := event_store.NewEvent(payload)
+	if err != nil {
+		return nil, err
+	}
+	event.ReplayOf = replayOf
+
+	ctx = logging.WithFields(git_provider.WithLogFields(ctx, payload), logging.EventIDKey, event.ID)
+	err = store.Save(event)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to record event", "error", err)
+	}
+
+	err = queue.Enqueue(ctx, event.ID, payload)
+	if err != nil {
+		logger.ErrorContext(ctx, "failed to enqueue webhook", "error", err)
+		event.Status = event_store.EventFailed
+		event.Errors = append(event.Errors, err.Error())
+		if saveErr := store.Save(event); saveErr != nil {
+			logger.ErrorContext(ctx, "failed to record event", "error", saveErr)
+		}
+		return nil, err
+	}
+
+	return event, nil
+}
+
+func abortWithEventError(c *gin.Context, logger *slog.Logger, err error) {
+	if errors.Is(err, event_store.ErrEventNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	logger.ErrorContext(c.Request.Context(), "failed to get event", logging.EventIDKey, c.Param("id"), "error", err)
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
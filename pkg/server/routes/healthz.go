@@ -2,26 +2,28 @@ package routes
 
 import (
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/leader_election"
 	"github.com/quickube/piper/pkg/webhook_creator"
 	"golang.org/x/net/context"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AddHealthRoutes(rg *gin.RouterGroup, wc *webhook_creator.WebhookCreatorImpl, cfg *conf.GlobalConfig) {
+// AddHealthRoutes runs the webhook diagnosis on the leader only, the followers don't track the webhooks.
+func AddHealthRoutes(rg *gin.RouterGroup, wc *webhook_creator.WebhookCreatorImpl, elector leader_election.Elector, cfg *conf.GlobalConfig, logger *slog.Logger) {
 	health := rg.Group("/healthz")
 
 	health.GET("", func(c *gin.Context) {
-		if cfg.GitProviderConfig.FullHealthCheck {
+		if cfg.GitProviderConfig.FullHealthCheck && elector.IsLeader() {
 			ctx := c.Copy().Request.Context()
 			ctx2, cancel := context.WithTimeout(ctx, 5*time.Second)
 			defer cancel()
 			err := wc.RunDiagnosis(ctx2)
 			if err != nil {
-				log.Printf("error from healthz endpoint:%s\n", err)
+				logger.ErrorContext(ctx, "health check failed", "error", err)
 				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
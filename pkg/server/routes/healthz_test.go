@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	assertion "github.com/stretchr/testify/assert"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/webhook_creator"
+)
+
+type staticElector struct {
+	leader bool
+}
+
+func (e *staticElector) Start(ctx context.Context, lead func(ctx context.Context)) {}
+
+func (e *staticElector) IsLeader() bool {
+	return e.leader
+}
+
+func TestHealthRoutes(t *testing.T) {
+	assert := assertion.New(t)
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		leader     bool
+		wantStatus int
+	}{
+		{name: "Leader runs the diagnosis", leader: true, wantStatus: http.StatusInternalServerError},
+		{name: "Follower is healthy without hooks", leader: false, wantStatus: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &conf.GlobalConfig{GitProviderConfig: conf.GitProviderConfig{FullHealthCheck: true}}
+			wc := webhook_creator.NewWebhookCreator(cfg, &clients.Clients{GitProvider: &webhook_creator.MockGitProviderClient{
+				PingHookFunc: func(ctx context.Context, hook *git_provider.HookWithStatus) error {
+					return errors.New("ping failed")
+				},
+			}})
+			hooks := wc.GetHooks()
+			(*hooks)[1] = &git_provider.HookWithStatus{HookID: 1, HealthStatus: true, RepoName: new(string)}
+
+			router := gin.New()
+			AddHealthRoutes(router.Group("/"), wc, &staticElector{leader: test.leader}, cfg, slog.Default())
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(test.wantStatus, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/quickube/piper/pkg/metrics"
+)
+
+func AddMetricsRoutes(rg *gin.RouterGroup, gatherer prometheus.Gatherer) {
+	rg.GET("/metrics", gin.WrapH(metrics.Handler(gatherer)))
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/webhook_queue"
+)
+
+func AddQueueRoutes(queue webhook_queue.WebhookQueue, rg *gin.RouterGroup) {
+	rg.GET("/queue", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"stats":       queue.Stats(),
+			"deadLetters": queue.DeadLetters(),
+		})
+	})
+}
@@ -0,0 +1,87 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	webhookHandler "github.com/quickube/piper/pkg/webhook_handler"
+)
+
+type renderRequest struct {
+	Repo  string                       `json:"repo"`
+	Ref   string                       `json:"ref"`
+	Event *git_provider.WebhookPayload `json:"event"`
+}
+
+func AddRenderRoutes(cfg *conf.GlobalConfig, clients *clients.Clients, rg *gin.RouterGroup) {
+	render := rg.Group("/render")
+
+	render.POST("", func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var payload *git_provider.WebhookPayload
+		var err error
+
+		if isProviderDelivery(c.Request) {
+			payload, err = clients.GitProvider.HandlePayload(ctx, c.Request, []byte(cfg.GitProviderConfig.WebhookSecret))
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if repo := c.Query("repo"); repo != "" {
+				payload.Repo = repo
+			}
+			if ref := c.Query("ref"); ref != "" {
+				payload.Branch = ref
+			}
+		} else {
+			request := &renderRequest{}
+			if err = c.ShouldBindJSON(request); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			payload = request.Event
+			if payload == nil {
+				payload = &git_provider.WebhookPayload{}
+			}
+			if request.Repo != "" {
+				payload.Repo = request.Repo
+			}
+			if request.Ref != "" {
+				payload.Branch = request.Ref
+			}
+		}
+
+		if payload.Repo == "" || payload.Branch == "" || payload.Event == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "repo, ref and event are required"})
+			return
+		}
+
+		wh, err := webhookHandler.NewWebhookHandler(cfg, clients, payload)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := webhookHandler.RenderWebhook(ctx, wh)
+		if err != nil {
+			logging.OrDefault(clients.Logger).WarnContext(ctx, "failed to render webhook", "error", err)
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	})
+}
+
+func isProviderDelivery(request *http.Request) bool {
+	for _, header := range []string{"X-GitHub-Event", "X-Gitlab-Event", "X-Event-Key"} {
+		if request.Header.Get(header) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/run_cache"
+)
+
+const defaultRunsLimit = 50
+
+func AddRunsRoutes(runs run_cache.RunCache, rg *gin.RouterGroup) {
+	rg.GET("/repos/:repo/runs", func(c *gin.Context) {
+		limit := defaultRunsLimit
+		if value := c.Query("limit"); value != "" {
+			var err error
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit < 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative number"})
+				return
+			}
+		}
+		if !runs.HasSynced() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "runs are not cached yet"})
+			return
+		}
+
+		list := runs.List(run_cache.Filter{
+			Repo:   c.Param("repo"),
+			Branch: c.Query("branch"),
+			Event:  c.Query("event"),
+			Phase:  c.Query("phase"),
+		}, limit)
+		c.JSON(http.StatusOK, gin.H{"runs": list})
+	})
+}
+
+// AddBadgeRoutes serves the phase of the latest run of a branch as an SVG badge, for READMEs. Badges are not
+// authenticated, like the images they are embedded in.
+func AddBadgeRoutes(runs run_cache.RunCache, rg *gin.RouterGroup) {
+	// Branches may contain slashes
+	rg.GET("/badge/:repo/*branch", func(c *gin.Context) {
+		branch := strings.TrimPrefix(c.Param("branch"), "/")
+		if !strings.HasSuffix(branch, ".svg") {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		branch = strings.TrimSuffix(branch, ".svg")
+
+		var latest *run_cache.Run
+		if runs.HasSynced() {
+			latest = runs.Latest(c.Param("repo"), branch)
+		}
+		badge, err := run_cache.Badge(latest)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		// Image proxies, like the GitHub one, would otherwise keep showing a stale phase
+		c.Header("Cache-Control", "no-cache, max-age=0")
+		c.Data(http.StatusOK, "image/svg+xml", badge)
+	})
+}
@@ -1,30 +1,51 @@
 package routes
 
 import (
+	"github.com/quickube/piper/pkg/event_store"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
+	"github.com/quickube/piper/pkg/tracing"
 	"github.com/quickube/piper/pkg/webhook_creator"
-	"log"
+	"github.com/quickube/piper/pkg/webhook_queue"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
-	webhookHandler "github.com/quickube/piper/pkg/webhook_handler"
+	"github.com/quickube/piper/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-func AddWebhookRoutes(cfg *conf.GlobalConfig, clients *clients.Clients, rg *gin.RouterGroup, wc *webhook_creator.WebhookCreatorImpl) {
+func AddWebhookRoutes(cfg *conf.GlobalConfig, clients *clients.Clients, rg *gin.RouterGroup, wc *webhook_creator.WebhookCreatorImpl, queue webhook_queue.WebhookQueue, store event_store.EventStore) {
 	webhook := rg.Group("/webhook")
+	// The delivery IDs are per replica, the idempotency key labels deduplicate the workflows across replicas
+	deliveries := utils.NewExpiringSet(cfg.WebhookConfig.DeduplicationWindow)
+	recorder := metrics.OrNoop(clients.Metrics)
+	logger := logging.OrDefault(clients.Logger)
+	provider := cfg.GitProviderConfig.Provider
 
 	webhook.POST("", func(c *gin.Context) {
-		ctx := c.Request.Context()
+		ctx, span := tracing.Tracer().Start(c.Request.Context(), "webhook", trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attribute.String("provider", provider)))
+		defer span.End()
 		webhookPayload, err := clients.GitProvider.HandlePayload(ctx, c.Request, []byte(cfg.GitProviderConfig.WebhookSecret))
 		if err != nil {
-			log.Println(err)
+			logger.WarnContext(ctx, "invalid webhook payload", "provider", provider, "error", err)
+			tracing.RecordError(span, err)
+			recorder.WebhookReceived(provider, "", "invalid")
 			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		ctx = git_provider.WithLogFields(ctx, webhookPayload)
+		span.SetAttributes(attribute.String("repo", webhookPayload.Repo), attribute.String("event", webhookPayload.Event),
+			attribute.String("commit", webhookPayload.Commit))
 		if webhookPayload.Event == "ping" {
+			recorder.WebhookReceived(provider, webhookPayload.Event, "ping")
+			// Pings are load balanced, so they are recorded for the leader whichever replica receives them
 			if cfg.GitProviderConfig.FullHealthCheck {
-				err = wc.SetWebhookHealth(webhookPayload.HookID, true)
+				err = wc.RecordPing(ctx, webhookPayload.HookID)
 				if err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 					return
@@ -34,29 +55,32 @@ func AddWebhookRoutes(cfg *conf.GlobalConfig, clients *clients.Clients, rg *gin.
 			return
 		}
 
-		wh, err := webhookHandler.NewWebhookHandler(cfg, clients, webhookPayload)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			log.Printf("failed to create webhook handler, error: %v", err)
-			return
+		deliveryID := webhookPayload.DeliveryID
+		if deliveryID != "" && cfg.WebhookConfig.DeduplicationWindow > 0 {
+			if !deliveries.Add(deliveryID) {
+				logger.InfoContext(ctx, "skipping duplicate delivery")
+				recorder.WebhookReceived(provider, webhookPayload.Event, "duplicate")
+				c.JSON(http.StatusOK, gin.H{"status": "duplicate delivery"})
+				return
+			}
+			// Rejected deliveries are forgotten so the provider redelivery is handled
+			defer func() {
+				if c.Writer.Status() != http.StatusAccepted {
+					deliveries.Remove(deliveryID)
+				}
+			}()
 		}
 
-		workflowsBatches, err := webhookHandler.HandleWebhook(ctx, wh)
+		event, err := acceptWebhook(ctx, logger, store, queue, webhookPayload, "")
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			log.Printf("failed to handle webhook, error: %v", err)
+			tracing.RecordError(span, err)
+			recorder.WebhookReceived(provider, webhookPayload.Event, "rejected")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
 			return
 		}
 
-		for _, wf := range workflowsBatches {
-			err = clients.Workflows.HandleWorkflowBatch(ctx, wf)
-			if err != nil {
-				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				log.Printf("failed to handle workflow, error: %v", err)
-				return
-			}
-		}
-
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+		recorder.WebhookReceived(provider, webhookPayload.Event, "accepted")
+		span.SetAttributes(attribute.String("event_id", event.ID))
+		c.JSON(http.StatusAccepted, gin.H{"status": "accepted", "event": event.ID})
 	})
 }
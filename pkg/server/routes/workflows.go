@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/gin-gonic/gin"
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/utils"
+	workflowHandler "github.com/quickube/piper/pkg/workflow_handler"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func AddWorkflowsRoutes(cfg *conf.GlobalConfig, clients *clients.Clients, rg *gin.RouterGroup) {
+	workflows := rg.Group("/workflows")
+	logger := logging.OrDefault(clients.Logger)
+
+	workflows.POST("/:name/retry", func(c *gin.Context) {
+		namespace, ok := workflowNamespace(cfg, c)
+		if !ok {
+			return
+		}
+
+		workflow, err := clients.Workflows.RetryWorkflow(c.Request.Context(), namespace, c.Param("name"))
+		if err != nil {
+			abortWithWorkflowError(c, logger, err)
+			return
+		}
+		c.JSON(http.StatusOK, workflowSummary(workflow))
+	})
+
+	workflows.POST("/:name/resubmit", func(c *gin.Context) {
+		namespace, ok := workflowNamespace(cfg, c)
+		if !ok {
+			return
+		}
+
+		workflow, err := clients.Workflows.ResubmitWorkflow(c.Request.Context(), namespace, c.Param("name"))
+		if err != nil {
+			abortWithWorkflowError(c, logger, err)
+			return
+		}
+		c.JSON(http.StatusCreated, workflowSummary(workflow))
+	})
+}
+
+// workflowNamespace returns the namespace query parameter, defaulting to ARGO_WORKFLOWS_NAMESPACE.
+// Only namespaces Piper submits workflows to are allowed.
+func workflowNamespace(cfg *conf.GlobalConfig, c *gin.Context) (string, bool) {
+	namespace := c.DefaultQuery("namespace", cfg.WorkflowServerConfig.Namespace)
+	if !utils.IsElementExists(cfg.WorkflowServerConfig.Namespaces(), namespace) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "namespace " + namespace + " is not managed by piper"})
+		return "", false
+	}
+	return namespace, true
+}
+
+func workflowSummary(workflow *v1alpha1.Workflow) gin.H {
+	return gin.H{
+		"name":      workflow.GetName(),
+		"namespace": workflow.GetNamespace(),
+		"phase":     workflow.Status.Phase,
+	}
+}
+
+func abortWithWorkflowError(c *gin.Context, logger *slog.Logger, err error) {
+	switch {
+	case k8serrors.IsNotFound(err):
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, workflowHandler.ErrWorkflowNotRetryable):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logger.ErrorContext(c.Request.Context(), "workflow request failed", "workflow", c.Param("name"), "error", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -3,23 +3,59 @@ package server
 import (
 	"context"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/controller"
+	"github.com/quickube/piper/pkg/event_handler"
+	"github.com/quickube/piper/pkg/event_store"
+	"github.com/quickube/piper/pkg/leader_election"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/run_cache"
 	"github.com/quickube/piper/pkg/server/routes"
 	"github.com/quickube/piper/pkg/webhook_creator"
-	"log"
+	"github.com/quickube/piper/pkg/webhook_queue"
 	"net/http"
+	"os"
 )
 
-func NewServer(config *conf.GlobalConfig, clients *clients.Clients) *Server {
+func NewServer(config *conf.GlobalConfig, clients *clients.Clients) (*Server, error) {
+	eventStore, err := event_store.NewEventStore(config)
+	if err != nil {
+		return nil, err
+	}
+
 	srv := &Server{
 		router:         gin.New(),
 		config:         config,
 		clients:        clients,
 		webhookCreator: webhook_creator.NewWebhookCreator(config, clients),
+		eventStore:     eventStore,
+		runCache:       run_cache.NewRunCache(clients),
+		logger:         logging.OrDefault(clients.Logger),
 	}
+	srv.webhookQueue = webhook_queue.NewWebhookQueue(config, srv.processWebhook, srv.logger)
 
-	return srv
+	srv.elector, err = leader_election.NewElector(config, srv.logger)
+	if err != nil {
+		return nil, err
+	}
+	if config.LeaderElectionConfig.Enabled && config.GitProviderConfig.FullHealthCheck {
+		pings, err := webhook_creator.NewPingStore(config)
+		if err != nil {
+			return nil, err
+		}
+		srv.webhookCreator.SetPingStore(pings)
+	}
+
+	if config.ControllerConfig.Enabled {
+		srv.controller, err = controller.NewController(config, srv.webhookCreator, srv.logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return srv, nil
 }
 
 func (s *Server) startServer() *http.Server {
@@ -29,9 +65,10 @@ func (s *Server) startServer() *http.Server {
 	}
 
 	go func() {
-		log.Printf("Server is listening on %s", s.httpServer.Addr)
+		s.logger.Info("server is listening", "address", srv.Addr)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
+			s.logger.Error("failed to listen", "address", srv.Addr, "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -40,9 +77,7 @@ func (s *Server) startServer() *http.Server {
 
 func (s *Server) registerMiddlewares() {
 	s.router.Use(
-		gin.LoggerWithConfig(gin.LoggerConfig{
-			SkipPaths: []string{"/healthz", "/readyz"},
-		}),
+		routes.RequestLogger(s.logger, "/healthz", "/readyz", "/metrics"),
 		gin.Recovery(),
 	)
 
@@ -51,12 +86,38 @@ func (s *Server) registerMiddlewares() {
 func (s *Server) getRoutes() {
 	v1 := s.router.Group("/")
 	routes.AddReadyRoutes(v1)
-	routes.AddHealthRoutes(v1, s.webhookCreator, s.config)
-	routes.AddWebhookRoutes(s.config, s.clients, v1, s.webhookCreator)
+	routes.AddHealthRoutes(v1, s.webhookCreator, s.elector, s.config, s.logger)
+	routes.AddMetricsRoutes(v1, prometheus.DefaultGatherer)
+	routes.AddWebhookRoutes(s.config, s.clients, v1, s.webhookCreator, s.webhookQueue, s.eventStore)
+	routes.AddBadgeRoutes(s.runCache, v1)
+
+	if s.config.ApiConfig.Token == "" {
+		s.logger.Warn("the API is disabled, PIPER_API_TOKEN is not configured")
+	}
+	api := s.router.Group("/api/v1", routes.APITokenAuth(s.config))
+	routes.AddRenderRoutes(s.config, s.clients, api)
+	routes.AddQueueRoutes(s.webhookQueue, api)
+	routes.AddEventsRoutes(s.eventStore, s.webhookQueue, api, s.logger)
+	routes.AddWorkflowsRoutes(s.config, s.clients, api)
+	routes.AddRunsRoutes(s.runCache, api)
 }
 
 func (s *Server) startServices(ctx context.Context) {
+	s.webhookQueue.Start()
+	s.runCache.Start(ctx)
+	if s.controller != nil {
+		s.controller.Start(ctx)
+	}
+	s.elector.Start(ctx, s.lead)
+}
+
+// lead starts the services of the leader replica, until ctx is done. Webhooks are handled by every replica.
+func (s *Server) lead(ctx context.Context) {
 	s.webhookCreator.Start(ctx)
+	if s.controller != nil {
+		s.controller.Lead(ctx)
+	}
+	event_handler.Start(ctx, s.config, s.clients)
 }
 
 func (s *Server) Start(ctx context.Context) {
@@ -2,7 +2,7 @@ package server
 
 import (
 	"golang.org/x/net/context"
-	"log"
+	"os"
 	"time"
 )
 
@@ -19,9 +19,25 @@ func NewGracefulShutdown(ctx context.Context, stop context.CancelFunc) *Graceful
 }
 
 func (s *GracefulShutdown) StopServices(ctx context.Context, server *Server) {
+	if server.config.LeaderElectionConfig.Enabled {
+		// The webhooks are kept for the next leader
+		return
+	}
 	server.webhookCreator.Stop(ctx)
 }
 
+func (s *GracefulShutdown) DrainQueue(ctx context.Context, server *Server) {
+	err := server.webhookQueue.Stop(ctx)
+	if err != nil {
+		server.logger.Error("failed to drain the webhook queue", "error", err)
+	}
+
+	err = server.eventStore.Close()
+	if err != nil {
+		server.logger.Error("failed to close the event store", "error", err)
+	}
+}
+
 func (s *GracefulShutdown) Shutdown(server *Server) {
 	// Listen for the interrupt signal.
 	<-s.ctx.Done()
@@ -29,7 +45,7 @@ func (s *GracefulShutdown) Shutdown(server *Server) {
 	// Restore default behavior on the interrupt signal and notify user of shutdown.
 	s.stop()
 
-	log.Println("shutting down gracefully...")
+	server.logger.Info("shutting down gracefully")
 	// The context is used to inform the server it has 10 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -39,7 +55,11 @@ func (s *GracefulShutdown) Shutdown(server *Server) {
 
 	err := server.httpServer.Shutdown(ctx)
 	if err != nil {
-		log.Fatal("Server forced to shutdown: ", err)
+		server.logger.Error("server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
+	// Draining after the server stopped accepting webhooks
+	s.DrainQueue(ctx, server)
+
 }
@@ -4,7 +4,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/controller"
+	"github.com/quickube/piper/pkg/event_store"
+	"github.com/quickube/piper/pkg/leader_election"
+	"github.com/quickube/piper/pkg/run_cache"
 	"github.com/quickube/piper/pkg/webhook_creator"
+	"github.com/quickube/piper/pkg/webhook_queue"
+	"log/slog"
 	"net/http"
 )
 
@@ -13,7 +19,13 @@ type Server struct {
 	config         *conf.GlobalConfig
 	clients        *clients.Clients
 	webhookCreator *webhook_creator.WebhookCreatorImpl
+	controller     controller.Controller
+	elector        leader_election.Elector
+	webhookQueue   webhook_queue.WebhookQueue
+	eventStore     event_store.EventStore
+	runCache       run_cache.RunCache
 	httpServer     *http.Server
+	logger         *slog.Logger
 }
 
 type Interface interface {
@@ -0,0 +1,98 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+const (
+	TRACE_ID_ANNOTATION = "piper.quickube.com/trace-id"
+	SPAN_ID_ANNOTATION  = "piper.quickube.com/span-id"
+
+	instrumentationName = "github.com/quickube/piper"
+)
+
+// Start sets the global tracer provider from the configuration and returns its shutdown function, which flushes the
+// remaining spans. The default exporter is none, which keeps the no-op tracer provider.
+func Start(ctx context.Context, cfg *conf.GlobalConfig) (func(context.Context) error, error) {
+	switch cfg.TracingConfig.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter %s, supported exporters are none and otlp", cfg.TracingConfig.Exporter)
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter, error: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(cfg.TracingConfig.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingConfig.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of Piper from the global tracer provider.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError records err on the span and sets its status to error.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// End records err on the span and ends it.
+func End(span trace.Span, err error) {
+	RecordError(span, err)
+	span.End()
+}
+
+// Annotate sets the trace ID and span ID of the span of ctx on the annotations, when it is sampled.
+func Annotate(ctx context.Context, annotations map[string]string) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || !spanContext.IsSampled() {
+		return
+	}
+	annotations[TRACE_ID_ANNOTATION] = spanContext.TraceID().String()
+	annotations[SPAN_ID_ANNOTATION] = spanContext.SpanID().String()
+}
+
+// LinkFromAnnotations returns a link to the span annotated by Annotate, or false when the annotations are not set.
+func LinkFromAnnotations(annotations map[string]string) (trace.Link, bool) {
+	traceID, err := trace.TraceIDFromHex(annotations[TRACE_ID_ANNOTATION])
+	if err != nil {
+		return trace.Link{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(annotations[SPAN_ID_ANNOTATION])
+	if err != nil {
+		return trace.Link{}, false
+	}
+	return trace.Link{SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})}, true
+}
@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+func TestStart(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "Default exporter", exporter: ""},
+		{name: "No-op exporter", exporter: "none"},
+		{name: "Unsupported exporter", exporter: "jaeger", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &conf.GlobalConfig{TracingConfig: conf.TracingConfig{Exporter: test.exporter}}
+			shutdown, err := Start(ctx, cfg)
+			if test.wantErr {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Nil(shutdown(ctx))
+		})
+	}
+}
+
+func TestAnnotationsLink(t *testing.T) {
+	assert := assertion.New(t)
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	submitCtx, submit := tracer.Start(context.Background(), "Submit")
+	annotations := make(map[string]string)
+	Annotate(submitCtx, annotations)
+	submit.End()
+	assert.Equal(submit.SpanContext().TraceID().String(), annotations[TRACE_ID_ANNOTATION])
+	assert.Equal(submit.SpanContext().SpanID().String(), annotations[SPAN_ID_ANNOTATION])
+
+	link, ok := LinkFromAnnotations(annotations)
+	assert.True(ok)
+	_, notify := tracer.Start(context.Background(), "Notify", trace.WithLinks(link))
+	notify.End()
+
+	spans := recorder.Ended()
+	assert.Len(spans, 2)
+	assert.Len(spans[1].Links(), 1)
+	assert.Equal(submit.SpanContext().TraceID(), spans[1].Links()[0].SpanContext.TraceID())
+	assert.Equal(submit.SpanContext().SpanID(), spans[1].Links()[0].SpanContext.SpanID())
+
+	// Workflows submitted without a sampled span are not annotated
+	unannotated := make(map[string]string)
+	Annotate(context.Background(), unannotated)
+	assert.Empty(unannotated)
+	_, ok = LinkFromAnnotations(unannotated)
+	assert.False(ok)
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// ExpiringSet is a concurrency safe set of keys that are forgotten once the ttl has passed since they were added.
+type ExpiringSet struct {
+	ttl   time.Duration
+	keys  map[string]time.Time
+	mu    sync.Mutex
+	nowFn func() time.Time
+}
+
+func NewExpiringSet(ttl time.Duration) *ExpiringSet {
+	return &ExpiringSet{
+		ttl:   ttl,
+		keys:  make(map[string]time.Time),
+		nowFn: time.Now,
+	}
+}
+
+// Add adds the key to the set, returning false if the key was already added within the ttl.
+func (s *ExpiringSet) Add(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.nowFn()
+	for k, added := range s.keys {
+		if now.Sub(added) >= s.ttl {
+			delete(s.keys, k)
+		}
+	}
+
+	if _, ok := s.keys[key]; ok {
+		return false
+	}
+	s.keys[key] = now
+	return true
+}
+
+func (s *ExpiringSet) Remove(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestExpiringSet(t *testing.T) {
+	assert := assertion.New(t)
+
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	set := NewExpiringSet(time.Hour)
+	set.nowFn = func() time.Time { return now }
+
+	assert.True(set.Add("a"))
+	assert.False(set.Add("a"))
+	assert.True(set.Add("b"))
+
+	set.Remove("b")
+	assert.True(set.Add("b"))
+
+	now = now.Add(time.Hour)
+	assert.True(set.Add("a"))
+	assert.False(set.Add("a"))
+}
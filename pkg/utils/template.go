@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Templates use ${{ }} delimiters, so Argo Workflows {{ }} expressions are left untouched.
+const (
+	TemplateLeftDelim  = "${{"
+	TemplateRightDelim = "}}"
+
+	maxTemplateOutput = 1 << 20
+)
+
+var (
+	errTemplateOutputLimit = errors.New("template output exceeds 1MiB")
+
+	semverPattern   = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+	sanitizePattern = regexp.MustCompile(`[^a-z0-9.]+`)
+)
+
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+// TemplateFuncs are the only functions available to templates, none of them reaches outside the template data.
+var TemplateFuncs = template.FuncMap{
+	"sanitize":   TemplateSanitize,
+	"truncate":   TemplateTruncate,
+	"semver":     ParseSemver,
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old string, new string, input string) string { return strings.ReplaceAll(input, old, new) },
+	"trimPrefix": func(prefix string, input string) string { return strings.TrimPrefix(input, prefix) },
+	"trimSuffix": func(suffix string, input string) string { return strings.TrimSuffix(input, suffix) },
+	"join":       func(sep string, items []string) string { return strings.Join(items, sep) },
+	"default": func(defaultValue string, input string) string {
+		if input == "" {
+			return defaultValue
+		}
+		return input
+	},
+}
+
+// ParseTemplate parses text with the template delimiters and functions.
+func ParseTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).
+		Delims(TemplateLeftDelim, TemplateRightDelim).
+		Option("missingkey=error").
+		Funcs(TemplateFuncs).
+		Parse(text)
+}
+
+// RenderTemplate renders text with data. Missing keys of map data are errors.
+func RenderTemplate(name string, text string, data interface{}) (string, error) {
+	tmpl, err := ParseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	output := &limitedBuffer{limit: maxTemplateOutput}
+	err = tmpl.Execute(output, data)
+	if err != nil {
+		return "", err
+	}
+	return output.String(), nil
+}
+
+// TemplateSanitize lowercases the input and replaces every run of characters other than a-z, 0-9 and . with -,
+// making it safe for image tags, label values and resource names.
+func TemplateSanitize(input string) string {
+	return strings.Trim(sanitizePattern.ReplaceAllString(strings.ToLower(input), "-"), "-.")
+}
+
+func TemplateTruncate(length int, input string) (string, error) {
+	if length < 0 {
+		return "", fmt.Errorf("truncate length must be non-negative, got %d", length)
+	}
+	return TrimString(input, length), nil
+}
+
+func ParseSemver(version string) (*Semver, error) {
+	match := semverPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return nil, fmt.Errorf("%s is not a semantic version", version)
+	}
+
+	semver := &Semver{Prerelease: match[4], Build: match[5]}
+	for i, part := range []*int{&semver.Major, &semver.Minor, &semver.Patch} {
+		value, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a semantic version: %v", version, err)
+		}
+		*part = value
+	}
+	return semver, nil
+}
+
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputLimit
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	assert := assertion.New(t)
+	data := map[string]interface{}{
+		"branch": "feature/Add_Login",
+		"tag":    "v1.4.2-rc.1+build.7",
+		"empty":  "",
+		"nodes":  []string{"build", "test"},
+	}
+
+	var tests = []struct {
+		name     string
+		text     string
+		expected string
+		err      bool
+	}{
+		{name: "Plain text", text: "value: latest", expected: "value: latest"},
+		{name: "Argo expressions are untouched", text: "{{ workflow.parameters.branch }}", expected: "{{ workflow.parameters.branch }}"},
+		{name: "Sanitize", text: "${{ .branch | sanitize }}", expected: "feature-add-login"},
+		{name: "Truncate", text: "${{ .branch | sanitize | truncate 7 }}", expected: "feature"},
+		{name: "Semver", text: "${{ with semver .tag }}${{ .Major }}.${{ .Minor }}-${{ .Prerelease }}${{ end }}", expected: "1.4-rc.1"},
+		{name: "Default", text: "${{ .empty | default \"main\" }}", expected: "main"},
+		{name: "Conditional", text: "${{ if eq .branch \"main\" }}prod${{ else }}dev${{ end }}", expected: "dev"},
+		{name: "Join", text: "${{ .nodes | join \", \" }}", expected: "build, test"},
+		{name: "Missing key", text: "${{ .missing }}", err: true},
+		{name: "Unknown function", text: "${{ env \"HOME\" }}", err: true},
+		{name: "Invalid semver", text: "${{ semver .branch }}", err: true},
+		{name: "Negative truncate", text: "${{ .branch | truncate -1 }}", err: true},
+		{name: "Output limit", text: "${{ range $i, $c := \"" + strings.Repeat("x", 1024) + "\" }}" + strings.Repeat("y", 2048) + "${{ end }}", err: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := RenderTemplate(test.name, test.text, data)
+			if test.err {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.expected, output)
+		})
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	assert := assertion.New(t)
+
+	semver, err := ParseSemver("v2.10.0+sha.abc")
+	assert.Nil(err)
+	assert.Equal(&Semver{Major: 2, Minor: 10, Patch: 0, Build: "sha.abc"}, semver)
+
+	_, err = ParseSemver("1.02.3")
+	assert.NotNil(err)
+}
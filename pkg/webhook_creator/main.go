@@ -2,13 +2,14 @@ package webhook_creator
 
 import (
 	"fmt"
-	"github.com/emicklei/go-restful/v3/log"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/conf"
 	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
 	"github.com/quickube/piper/pkg/utils"
 	"golang.org/x/net/context"
-	"strconv"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +19,11 @@ type WebhookCreatorImpl struct {
 	clients *clients.Clients
 	cfg     *conf.GlobalConfig
 	hooks   map[int64]*git_provider.HookWithStatus
-	mu      sync.Mutex
+	metrics metrics.Recorder
+	logger  *slog.Logger
+	// pings is set with leader election, when the pings can be received by any replica.
+	pings PingStore
+	mu    sync.Mutex
 }
 
 func NewWebhookCreator(cfg *conf.GlobalConfig, clients *clients.Clients) *WebhookCreatorImpl {
@@ -26,11 +31,18 @@ func NewWebhookCreator(cfg *conf.GlobalConfig, clients *clients.Clients) *Webhoo
 		clients: clients,
 		cfg:     cfg,
 		hooks:   make(map[int64]*git_provider.HookWithStatus, 0),
+		metrics: metrics.OrNoop(clients.Metrics),
+		logger:  logging.OrDefault(clients.Logger).With("component", "webhook_creator"),
 	}
 
 	return wr
 }
 
+// SetPingStore shares the pings received by every replica with the leader.
+func (wc *WebhookCreatorImpl) SetPingStore(pings PingStore) {
+	wc.pings = pings
+}
+
 func (wc *WebhookCreatorImpl) GetHooks() *map[int64]*git_provider.HookWithStatus {
 	return &wc.hooks
 }
@@ -39,7 +51,7 @@ func (wc *WebhookCreatorImpl) Start(ctx context.Context) {
 
 	err := wc.initWebhooks(ctx)
 	if err != nil {
-		log.Print(err)
+		wc.logger.ErrorContext(ctx, "failed to initialize webhooks", "error", err)
 		panic("failed in initializing webhooks")
 	}
 }
@@ -48,6 +60,7 @@ func (wc *WebhookCreatorImpl) setWebhook(hookID int64, healthStatus bool, repoNa
 	wc.mu.Lock()
 	defer wc.mu.Unlock()
 	wc.hooks[hookID] = &git_provider.HookWithStatus{HookID: hookID, HealthStatus: healthStatus, RepoName: &repoName}
+	wc.metrics.HookHealth(repoName, healthStatus)
 }
 
 func (wc *WebhookCreatorImpl) getWebhook(hookID int64) *git_provider.HookWithStatus {
@@ -64,25 +77,112 @@ func (wc *WebhookCreatorImpl) deleteWebhook(hookID int64) {
 	wc.mu.Lock()
 	defer wc.mu.Unlock()
 
+	if hook, ok := wc.hooks[hookID]; ok && hook.RepoName != nil {
+		wc.metrics.HookRemoved(*hook.RepoName)
+	}
 	delete(wc.hooks, hookID)
 }
 
+// listWebhooks returns a snapshot of the hooks, as the controller adds and removes hooks concurrently.
+func (wc *WebhookCreatorImpl) listWebhooks() map[int64]*git_provider.HookWithStatus {
+	wc.mu.Lock()
+	defer wc.mu.Unlock()
+
+	hooks := make(map[int64]*git_provider.HookWithStatus, len(wc.hooks))
+	for hookID, hook := range wc.hooks {
+		hooks[hookID] = hook
+	}
+	return hooks
+}
+
+// repoName returns the repo name as the git provider hooks it.
+func (wc *WebhookCreatorImpl) repoName(repo string) string {
+	if wc.cfg.GitProviderConfig.Provider == "bitbucket" {
+		return utils.SanitizeString(repo)
+	}
+	return repo
+}
+
+// GetRepoWebhook returns the tracked hook of a repo, or nil.
+func (wc *WebhookCreatorImpl) GetRepoWebhook(repo string) *git_provider.HookWithStatus {
+	repo = wc.repoName(repo)
+	for _, hook := range wc.listWebhooks() {
+		if hook.RepoName != nil && *hook.RepoName == repo {
+			return hook
+		}
+	}
+	return nil
+}
+
+// SetRepoWebhook creates or updates the webhook of a repo, and tracks its health.
+func (wc *WebhookCreatorImpl) SetRepoWebhook(ctx context.Context, repo string) (*git_provider.HookWithStatus, error) {
+	repo = wc.repoName(repo)
+	hook, err := wc.clients.GitProvider.SetWebhook(ctx, &repo)
+	if err != nil {
+		return nil, err
+	}
+	if existing := wc.GetRepoWebhook(*hook.RepoName); existing != nil && existing.HookID != hook.HookID {
+		wc.deleteWebhook(existing.HookID)
+	}
+	wc.setWebhook(hook.HookID, hook.HealthStatus, *hook.RepoName)
+	return hook, nil
+}
+
+// UnsetRepoWebhook deletes the webhook of a repo and stops tracking it. hookID identifies the webhook
+// when it is not tracked, e.g. after a restart.
+func (wc *WebhookCreatorImpl) UnsetRepoWebhook(ctx context.Context, repo string, hookID int64) error {
+	repo = wc.repoName(repo)
+	hook := wc.GetRepoWebhook(repo)
+	if hook == nil {
+		hook = &git_provider.HookWithStatus{HookID: hookID, RepoName: &repo}
+	}
+	err := wc.clients.GitProvider.UnsetWebhook(ctx, hook)
+	if err != nil {
+		return err
+	}
+	wc.deleteWebhook(hook.HookID)
+	return nil
+}
+
 func (wc *WebhookCreatorImpl) SetWebhookHealth(hookID int64, status bool) error {
 
-	hook, ok := wc.hooks[hookID]
-	if !ok {
-		return fmt.Errorf("unable to find hookID: %d in internal hooks map %v", hookID, wc.hooks)
+	hook := wc.getWebhook(hookID)
+	if hook == nil {
+		return fmt.Errorf("unable to find hookID: %d in internal hooks map %v", hookID, wc.listWebhooks())
 	}
 	wc.setWebhook(hookID, status, *hook.RepoName)
-	log.Printf("set health status to %s for hook id: %d", strconv.FormatBool(status), hookID)
+	wc.logger.Debug("set hook health status", "hook_id", hookID, "repo", *hook.RepoName, "healthy", status)
 	return nil
 }
 
+// RecordPing marks the hook of a received ping as healthy. With a ping store, the ping is recorded for the leader,
+// as the replica receiving it may not track the hooks.
+func (wc *WebhookCreatorImpl) RecordPing(ctx context.Context, hookID int64) error {
+	if wc.pings != nil {
+		return wc.pings.RecordPing(ctx, hookID)
+	}
+	return wc.SetWebhookHealth(hookID, true)
+}
+
+// syncPings marks as healthy the hooks pinged since pingsBefore was read from the ping store.
+func (wc *WebhookCreatorImpl) syncPings(ctx context.Context, pingsBefore map[int64]string) {
+	pings, err := wc.pings.Pings(ctx)
+	if err != nil {
+		wc.logger.WarnContext(ctx, "failed to get the webhook pings", "error", err)
+		return
+	}
+	for hookID, hook := range wc.listWebhooks() {
+		if ping, ok := pings[hookID]; ok && ping != pingsBefore[hookID] {
+			wc.setWebhook(hookID, true, *hook.RepoName)
+		}
+	}
+}
+
 func (wc *WebhookCreatorImpl) setAllHooksHealth(status bool) {
-	for hookID, hook := range wc.hooks {
+	for hookID, hook := range wc.listWebhooks() {
 		wc.setWebhook(hookID, status, *hook.RepoName)
 	}
-	log.Printf("set all hooks health status for to %s", strconv.FormatBool(status))
+	wc.logger.Debug("set all hooks health status", "healthy", status)
 }
 
 func (wc *WebhookCreatorImpl) initWebhooks(ctx context.Context) error {
@@ -90,6 +190,10 @@ func (wc *WebhookCreatorImpl) initWebhooks(ctx context.Context) error {
 	if wc.cfg.GitProviderConfig.OrgLevelWebhook && len(wc.cfg.GitProviderConfig.RepoList) != 0 {
 		return fmt.Errorf("org level webhook wanted but provided repositories list")
 	} else if !wc.cfg.GitProviderConfig.OrgLevelWebhook && len(wc.cfg.GitProviderConfig.RepoList) == 0 {
+		if wc.cfg.ControllerConfig.Enabled {
+			// The repos are declared by PiperRepository resources
+			return nil
+		}
 		return fmt.Errorf("either org level webhook or repos list must be provided")
 	}
 	for _, repo := range strings.Split(wc.cfg.GitProviderConfig.RepoList, ",") {
@@ -110,13 +214,13 @@ func (wc *WebhookCreatorImpl) Stop(ctx context.Context) {
 	if wc.cfg.GitProviderConfig.WebhookAutoCleanup {
 		err := wc.deleteWebhooks(ctx)
 		if err != nil {
-			log.Printf("Failed to delete webhooks, error: %v", err)
+			wc.logger.ErrorContext(ctx, "failed to delete webhooks", "error", err)
 		}
 	}
 }
 
 func (wc *WebhookCreatorImpl) deleteWebhooks(ctx context.Context) error {
-	for hookID, hook := range wc.hooks {
+	for hookID, hook := range wc.listWebhooks() {
 		err := wc.clients.GitProvider.UnsetWebhook(ctx, hook)
 		if err != nil {
 			return err
@@ -127,12 +231,15 @@ func (wc *WebhookCreatorImpl) deleteWebhooks(ctx context.Context) error {
 	return nil
 }
 
-func (wc *WebhookCreatorImpl) checkHooksHealth(timeoutSeconds time.Duration) bool {
+func (wc *WebhookCreatorImpl) checkHooksHealth(ctx context.Context, timeoutSeconds time.Duration, pingsBefore map[int64]string) bool {
 	startTime := time.Now()
 
 	for {
+		if wc.pings != nil {
+			wc.syncPings(ctx, pingsBefore)
+		}
 		allHealthy := true
-		for _, hook := range wc.hooks {
+		for _, hook := range wc.listWebhooks() {
 			if !hook.HealthStatus {
 				allHealthy = false
 				break
@@ -155,7 +262,7 @@ func (wc *WebhookCreatorImpl) checkHooksHealth(timeoutSeconds time.Duration) boo
 
 func (wc *WebhookCreatorImpl) recoverHook(ctx context.Context, hookID int64) error {
 
-	log.Printf("started recover of hook %d", hookID)
+	wc.logger.InfoContext(ctx, "started recover of hook", "hook_id", hookID)
 	hook := wc.getWebhook(hookID)
 	if hook == nil {
 		return fmt.Errorf("failed to recover hook, hookID %d not found", hookID)
@@ -166,13 +273,13 @@ func (wc *WebhookCreatorImpl) recoverHook(ctx context.Context, hookID int64) err
 	}
 	wc.deleteWebhook(hookID)
 	wc.setWebhook(newHook.HookID, newHook.HealthStatus, *newHook.RepoName)
-	log.Printf("successful recover of hook %d", hookID)
+	wc.logger.InfoContext(ctx, "recovered hook", "hook_id", hookID, "new_hook_id", newHook.HookID)
 	return nil
 
 }
 
 func (wc *WebhookCreatorImpl) pingHooks(ctx context.Context) error {
-	for _, hook := range wc.hooks {
+	for _, hook := range wc.listWebhooks() {
 		err := wc.clients.GitProvider.PingHook(ctx, hook)
 		if err != nil {
 			return err
@@ -182,20 +289,28 @@ func (wc *WebhookCreatorImpl) pingHooks(ctx context.Context) error {
 }
 
 func (wc *WebhookCreatorImpl) RunDiagnosis(ctx context.Context) error {
-	log.Printf("Starting webhook diagnostics")
+	wc.logger.DebugContext(ctx, "starting webhook diagnosis")
 	wc.setAllHooksHealth(false)
+	var pingsBefore map[int64]string
+	if wc.pings != nil {
+		var err error
+		pingsBefore, err = wc.pings.Pings(ctx)
+		if err != nil {
+			return err
+		}
+	}
 	err := wc.pingHooks(ctx)
 	if err != nil {
 		return err
 	}
-	if !wc.checkHooksHealth(5 * time.Second) {
-		for hookID, hook := range wc.hooks {
+	if !wc.checkHooksHealth(ctx, 5*time.Second, pingsBefore) {
+		for hookID, hook := range wc.listWebhooks() {
 			if !hook.HealthStatus {
 				return fmt.Errorf("hook %d is not healthy", hookID)
 			}
 		}
 	}
 
-	log.Print("Successful webhook diagnosis")
+	wc.logger.DebugContext(ctx, "successful webhook diagnosis")
 	return nil
 }
@@ -10,15 +10,19 @@ import (
 )
 
 type MockGitProviderClient struct {
-	ListFilesFunc           func(ctx context.Context, repo string, branch string, path string) ([]string, error)
-	GetFileFunc             func(ctx context.Context, repo string, branch string, path string) (*git_provider.CommitFile, error)
-	GetFilesFunc            func(ctx context.Context, repo string, branch string, paths []string) ([]*git_provider.CommitFile, error)
-	SetWebhookFunc          func(ctx context.Context, repo *string) (*git_provider.HookWithStatus, error)
-	UnsetWebhookFunc        func(ctx context.Context, hook *git_provider.HookWithStatus) error
-	HandlePayloadFunc       func(request *http.Request, secret []byte) (*git_provider.WebhookPayload, error)
-	SetStatusFunc           func(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error
-	PingHookFunc            func(ctx context.Context, hook *git_provider.HookWithStatus) error
-	GetCorrelatingEventFunc func(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error)
+	ListFilesFunc            func(ctx context.Context, repo string, branch string, path string) ([]string, error)
+	GetFileFunc              func(ctx context.Context, repo string, branch string, path string) (*git_provider.CommitFile, error)
+	GetFilesFunc             func(ctx context.Context, repo string, branch string, paths []string) ([]*git_provider.CommitFile, error)
+	SetWebhookFunc           func(ctx context.Context, repo *string) (*git_provider.HookWithStatus, error)
+	UnsetWebhookFunc         func(ctx context.Context, hook *git_provider.HookWithStatus) error
+	HandlePayloadFunc        func(request *http.Request, secret []byte) (*git_provider.WebhookPayload, error)
+	SetStatusFunc            func(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error
+	SetNamedStatusFunc       func(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error
+	PingHookFunc             func(ctx context.Context, hook *git_provider.HookWithStatus) error
+	GetCorrelatingEventFunc  func(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error)
+	IsCollaboratorFunc       func(ctx context.Context, repo string, user string) (bool, error)
+	CommentOnPullRequestFunc func(ctx context.Context, repo string, pullRequestID int, body string) (int64, error)
+	UpdateCommentFunc        func(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error
 }
 
 func (m *MockGitProviderClient) ListFiles(ctx context2.Context, repo string, branch string, path string) ([]string, error) {
@@ -69,6 +73,13 @@ func (m *MockGitProviderClient) SetStatus(ctx context2.Context, repo *string, co
 	}
 	return errors.New("unimplemented")
 }
+
+func (m *MockGitProviderClient) SetNamedStatus(ctx context2.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
+	if m.SetNamedStatusFunc != nil {
+		return m.SetNamedStatusFunc(ctx, repo, commit, name, linkURL, status, message)
+	}
+	return errors.New("unimplemented")
+}
 func (m *MockGitProviderClient) GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error) {
 	if m.GetCorrelatingEventFunc != nil {
 		return m.GetCorrelatingEventFunc(ctx, workflowEvent)
@@ -76,6 +87,27 @@ func (m *MockGitProviderClient) GetCorrelatingEvent(ctx context.Context, workflo
 	return "", errors.New("unimplemented")
 }
 
+func (m *MockGitProviderClient) IsCollaborator(ctx context2.Context, repo string, user string) (bool, error) {
+	if m.IsCollaboratorFunc != nil {
+		return m.IsCollaboratorFunc(ctx, repo, user)
+	}
+	return false, errors.New("unimplemented")
+}
+
+func (m *MockGitProviderClient) CommentOnPullRequest(ctx context2.Context, repo string, pullRequestID int, body string) (int64, error) {
+	if m.CommentOnPullRequestFunc != nil {
+		return m.CommentOnPullRequestFunc(ctx, repo, pullRequestID, body)
+	}
+	return 0, errors.New("unimplemented")
+}
+
+func (m *MockGitProviderClient) UpdateComment(ctx context2.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	if m.UpdateCommentFunc != nil {
+		return m.UpdateCommentFunc(ctx, repo, pullRequestID, commentID, body)
+	}
+	return errors.New("unimplemented")
+}
+
 func (m *MockGitProviderClient) PingHook(ctx context2.Context, hook *git_provider.HookWithStatus) error {
 	if m.PingHookFunc != nil {
 		return m.PingHookFunc(ctx, hook)
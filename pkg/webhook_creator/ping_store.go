@@ -0,0 +1,101 @@
+package webhook_creator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/net/context"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	coreclientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+// PingStore shares the webhook pings between the replicas. The Service load balances the pings the leader requests
+// during its diagnosis, so they are recorded by whichever replica receives them.
+type PingStore interface {
+	RecordPing(ctx context.Context, hookID int64) error
+	// Pings returns the last ping of every hook, which changes with every ping.
+	Pings(ctx context.Context) (map[int64]string, error)
+}
+
+// configMapPingStore keeps the time of the last ping of every hook in a ConfigMap, next to the leader election Lease.
+// The leader compares the pings before and after its diagnosis, so the clocks of the replicas don't matter.
+type configMapPingStore struct {
+	client    coreclientv1.ConfigMapsGetter
+	name      string
+	namespace string
+}
+
+func NewPingStore(cfg *conf.GlobalConfig) (PingStore, error) {
+	restClientConfig, err := utils.GetClientConfig(cfg.WorkflowServerConfig.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	client, err := coreclientv1.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newConfigMapPingStore(cfg, client), nil
+}
+
+func newConfigMapPingStore(cfg *conf.GlobalConfig, client coreclientv1.ConfigMapsGetter) *configMapPingStore {
+	namespace := cfg.LeaderElectionConfig.LeaseNamespace
+	if namespace == "" {
+		namespace = cfg.WorkflowServerConfig.Namespace
+	}
+	return &configMapPingStore{
+		client:    client,
+		name:      cfg.LeaderElectionConfig.LeaseName + "-pings",
+		namespace: namespace,
+	}
+}
+
+func (s *configMapPingStore) RecordPing(ctx context.Context, hookID int64) error {
+	data := map[string]string{strconv.FormatInt(hookID, 10): time.Now().UTC().Format(time.RFC3339Nano)}
+	patch, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.ConfigMaps(s.namespace).Patch(ctx, s.name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = s.client.ConfigMaps(s.namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+			Data:       data,
+		}, metav1.CreateOptions{})
+		if k8serrors.IsAlreadyExists(err) {
+			// Created by another replica in the meantime
+			_, err = s.client.ConfigMaps(s.namespace).Patch(ctx, s.name, types.MergePatchType, patch, metav1.PatchOptions{})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record the ping of hook %d, error: %v", hookID, err)
+	}
+	return nil
+}
+
+func (s *configMapPingStore) Pings(ctx context.Context) (map[int64]string, error) {
+	pings := make(map[int64]string)
+	configMap, err := s.client.ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return pings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the webhook pings, error: %v", err)
+	}
+	for key, value := range configMap.Data {
+		hookID, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		pings[hookID] = value
+	}
+	return pings, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/quickube/piper/pkg/git_provider"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	"math/rand"
 	"testing"
 	"time"
@@ -238,7 +240,7 @@ func TestWebhookCreatorImpl_CheckHooksHealth(t *testing.T) {
 
 	// Check the health sta tus of webhooks with a timeout of 1 second
 	timeout := 1 * time.Second
-	result := wc.checkHooksHealth(timeout)
+	result := wc.checkHooksHealth(context.Background(), timeout, nil)
 
 	// Verify that the result indicates that all webhooks are healthy
 	assertion.False(result)
@@ -246,7 +248,7 @@ func TestWebhookCreatorImpl_CheckHooksHealth(t *testing.T) {
 	wc.setWebhook(2, true, "repo2")
 
 	timeStart := time.Now()
-	result = wc.checkHooksHealth(timeout)
+	result = wc.checkHooksHealth(context.Background(), timeout, nil)
 	timeTook := time.Since(timeStart)
 	assertion.Less(timeTook, timeout)
 	assertion.True(result)
@@ -369,3 +371,56 @@ func TestWebhookCreatorImpl_RunDiagnosis(t *testing.T) {
 	assertion.NotNil(err)
 
 }
+
+func TestConfigMapPingStore(t *testing.T) {
+	assertion := assert.New(t)
+	ctx := context.Background()
+
+	cfg := &conf.GlobalConfig{LeaderElectionConfig: conf.LeaderElectionConfig{LeaseName: "piper-leader", LeaseNamespace: "piper"}}
+	client := kubefake.NewSimpleClientset()
+	store := newConfigMapPingStore(cfg, client.CoreV1())
+
+	pings, err := store.Pings(ctx)
+	assertion.Nil(err)
+	assertion.Empty(pings)
+
+	assertion.Nil(store.RecordPing(ctx, 1))
+	assertion.Nil(store.RecordPing(ctx, 2))
+	pings, err = store.Pings(ctx)
+	assertion.Nil(err)
+	assertion.Len(pings, 2)
+
+	configMap, err := client.CoreV1().ConfigMaps("piper").Get(ctx, "piper-leader-pings", metav1.GetOptions{})
+	assertion.Nil(err)
+	assertion.Contains(configMap.Data, "1")
+}
+
+func TestWebhookCreatorImpl_RunDiagnosis_PingOnFollower(t *testing.T) {
+	assertion := assert.New(t)
+	ctx := context.Background()
+
+	cfg := &conf.GlobalConfig{LeaderElectionConfig: conf.LeaderElectionConfig{LeaseName: "piper-leader", LeaseNamespace: "piper"}}
+	client := kubefake.NewSimpleClientset()
+
+	// The follower doesn't track the hooks of the leader
+	follower := NewWebhookCreator(cfg, &clients.Clients{})
+	follower.SetPingStore(newConfigMapPingStore(cfg, client.CoreV1()))
+
+	leader := NewWebhookCreator(cfg, &clients.Clients{GitProvider: &MockGitProviderClient{
+		PingHookFunc: func(ctx context.Context, hook *git_provider.HookWithStatus) error {
+			// The Service load balances the ping to the follower
+			return follower.RecordPing(ctx, hook.HookID)
+		},
+	}})
+	leader.SetPingStore(newConfigMapPingStore(cfg, client.CoreV1()))
+	leader.setWebhook(1, true, "repo1")
+	leader.setWebhook(2, true, "repo2")
+
+	// A ping of an earlier diagnosis doesn't count
+	assertion.Nil(follower.RecordPing(ctx, 1))
+
+	assertion.Nil(leader.RunDiagnosis(ctx))
+	for _, hook := range leader.listWebhooks() {
+		assertion.True(hook.HealthStatus)
+	}
+}
@@ -0,0 +1,167 @@
+package webhook_handler
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+)
+
+const CHATOPS_PREFIX = "/piper"
+
+// ParseChatOpsCommands returns the commands of a comment, one for every line starting with /piper.
+func ParseChatOpsCommands(comment string) []ChatOpsCommand {
+	commands := make([]ChatOpsCommand, 0)
+	for _, line := range strings.Split(comment, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != CHATOPS_PREFIX {
+			continue
+		}
+		commands = append(commands, ChatOpsCommand{
+			Name: strings.ToLower(fields[1]),
+			Args: fields[2:],
+		})
+	}
+	return commands
+}
+
+// HandleChatOps runs the commands of a pull request comment on the workflows of the pull request head commit.
+// Commands are accepted only from collaborators of the repo.
+func HandleChatOps(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients, payload *git_provider.WebhookPayload) (*ProcessResult, error) {
+	logger := logging.OrDefault(clients.Logger)
+	result := newProcessResult()
+	if payload.Event != "pull_request_comment" || payload.Action != "created" {
+		logger.InfoContext(ctx, "skipping comment", "event", payload.Event, "action", payload.Action)
+		return result, nil
+	}
+
+	commands := ParseChatOpsCommands(payload.Comment)
+	if len(commands) == 0 {
+		return result, nil
+	}
+
+	allowed, err := clients.GitProvider.IsCollaborator(ctx, payload.Repo, payload.CommentAuthor)
+	if err != nil {
+		return result, fmt.Errorf("failed to check permissions of %s, error: %v", payload.CommentAuthor, err)
+	}
+	if !allowed {
+		logger.WarnContext(ctx, "skipping commands, not a collaborator of the repo", "user", payload.CommentAuthor)
+		result.Errors = append(result.Errors, fmt.Sprintf("%s is not a collaborator of repo %s", payload.CommentAuthor, payload.Repo))
+		return result, nil
+	}
+
+	for _, command := range commands {
+		logger.InfoContext(ctx, "running command", "command", command.Name, "args", command.Args, "user", payload.CommentAuthor)
+		switch command.Name {
+		case "retry":
+			err = chatOpsRetry(ctx, clients, payload, result)
+		case "run":
+			err = chatOpsRun(ctx, cfg, clients, payload, command.Args, result)
+		case "cancel":
+			err = chatOpsCancel(ctx, clients, payload, result)
+		default:
+			result.Errors = append(result.Errors, fmt.Sprintf("unknown command %s, expected retry, run or cancel", command.Name))
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to run command %s, error: %w", command.Name, err)
+		}
+	}
+
+	return result, nil
+}
+
+// chatOpsRetry retries the failed workflows of the commit.
+func chatOpsRetry(ctx context.Context, clients *clients.Clients, payload *git_provider.WebhookPayload, result *ProcessResult) error {
+	workflows, err := clients.Workflows.ListCommitWorkflows(ctx, payload.Repo, payload.Commit)
+	if err != nil {
+		return err
+	}
+
+	for _, workflow := range workflows {
+		if workflow.Status.Phase != v1alpha1.WorkflowFailed && workflow.Status.Phase != v1alpha1.WorkflowError {
+			continue
+		}
+		retried, err := clients.Workflows.RetryWorkflow(ctx, workflow.GetNamespace(), workflow.GetName())
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Workflows = append(result.Workflows, retried.GetName())
+	}
+	return nil
+}
+
+// chatOpsRun submits the workflows of a trigger, given by name or index, regardless of its events and branches.
+func chatOpsRun(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients, payload *git_provider.WebhookPayload, args []string, result *ProcessResult) error {
+	if len(args) != 1 {
+		result.Errors = append(result.Errors, fmt.Sprintf("expected %s run <trigger>, got %d arguments", CHATOPS_PREFIX, len(args)))
+		return nil
+	}
+
+	wh, err := NewWebhookHandler(cfg, clients, payload)
+	if err != nil {
+		return err
+	}
+	err = wh.RegisterTriggers(ctx)
+	if err != nil {
+		return err
+	}
+
+	index, trigger := wh.FindTrigger(args[0])
+	if trigger == nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("trigger %s not found in repo %s branch %s", args[0], payload.Repo, payload.Branch))
+		return nil
+	}
+
+	workflowsBatch, err := wh.PrepareBatch(ctx, trigger)
+	if err != nil {
+		return err
+	}
+	workflowsBatch.TriggerIndex = index
+	result.Triggers = append(result.Triggers, index)
+
+	return submitBatches(ctx, cfg, clients, []*common.WorkflowsBatch{workflowsBatch}, result)
+}
+
+// chatOpsCancel stops the running workflows of the commit.
+func chatOpsCancel(ctx context.Context, clients *clients.Clients, payload *git_provider.WebhookPayload, result *ProcessResult) error {
+	workflows, err := clients.Workflows.ListCommitWorkflows(ctx, payload.Repo, payload.Commit)
+	if err != nil {
+		return err
+	}
+
+	for _, workflow := range workflows {
+		if workflow.Status.Fulfilled() || workflow.Spec.Shutdown.Enabled() {
+			continue
+		}
+		err = clients.Workflows.StopWorkflow(ctx, workflow.GetNamespace(), workflow.GetName())
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		result.Workflows = append(result.Workflows, workflow.GetName())
+	}
+	return nil
+}
+
+// FindTrigger returns the trigger with the given name, or at the given index when no trigger has that name.
+func (wh *WebhookHandlerImpl) FindTrigger(nameOrIndex string) (int, *Trigger) {
+	for i := range *wh.Triggers {
+		if (*wh.Triggers)[i].Name == nameOrIndex {
+			return i, &(*wh.Triggers)[i]
+		}
+	}
+
+	index, err := strconv.Atoi(nameOrIndex)
+	if err != nil || index < 0 || index >= len(*wh.Triggers) {
+		return -1, nil
+	}
+	return index, &(*wh.Triggers)[index]
+}
@@ -0,0 +1,124 @@
+package webhook_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quickube/piper/pkg/clients"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestParseChatOpsCommands(t *testing.T) {
+	assert := assertion.New(t)
+	var tests = []struct {
+		name     string
+		comment  string
+		expected []ChatOpsCommand
+	}{
+		{name: "Retry",
+			comment:  "/piper retry",
+			expected: []ChatOpsCommand{{Name: "retry", Args: []string{}}},
+		},
+		{name: "Run with trigger",
+			comment:  "  /piper   Run build  ",
+			expected: []ChatOpsCommand{{Name: "run", Args: []string{"build"}}},
+		},
+		{name: "Multiple lines",
+			comment: "LGTM, let's try again\n/piper cancel\r\n/piper run e2e",
+			expected: []ChatOpsCommand{
+				{Name: "cancel", Args: []string{}},
+				{Name: "run", Args: []string{"e2e"}},
+			},
+		},
+		{name: "Not a command",
+			comment:  "please /piper retry",
+			expected: []ChatOpsCommand{},
+		},
+		{name: "Missing command",
+			comment:  "/piper",
+			expected: []ChatOpsCommand{},
+		},
+		{name: "Other prefix",
+			comment:  "/piperx retry",
+			expected: []ChatOpsCommand{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(test.expected, ParseChatOpsCommands(test.comment))
+		})
+	}
+}
+
+func TestFindTrigger(t *testing.T) {
+	assert := assertion.New(t)
+	wh := &WebhookHandlerImpl{
+		Triggers: &[]Trigger{
+			{Name: "build"},
+			{Name: "e2e"},
+			{},
+		},
+	}
+
+	index, trigger := wh.FindTrigger("e2e")
+	assert.Equal(1, index)
+	assert.Equal("e2e", trigger.Name)
+
+	index, trigger = wh.FindTrigger("2")
+	assert.Equal(2, index)
+	assert.NotNil(trigger)
+
+	index, trigger = wh.FindTrigger("deploy")
+	assert.Equal(-1, index)
+	assert.Nil(trigger)
+
+	_, trigger = wh.FindTrigger("3")
+	assert.Nil(trigger)
+}
+
+func TestHandleChatOps(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	globalClients := &clients.Clients{GitProvider: &mockGitProvider{}}
+
+	var tests = []struct {
+		name           string
+		payload        *git_provider.WebhookPayload
+		expectedErrors []string
+	}{
+		{name: "Issue comment",
+			payload:        &git_provider.WebhookPayload{Event: "issue_comment", Action: "created", Repo: "repo1", Comment: "/piper retry", CommentAuthor: "collaborator"},
+			expectedErrors: []string{},
+		},
+		{name: "Edited comment",
+			payload:        &git_provider.WebhookPayload{Event: "pull_request_comment", Action: "edited", Repo: "repo1", Comment: "/piper retry", CommentAuthor: "collaborator"},
+			expectedErrors: []string{},
+		},
+		{name: "No command",
+			payload:        &git_provider.WebhookPayload{Event: "pull_request_comment", Action: "created", Repo: "repo1", Comment: "LGTM", CommentAuthor: "outsider"},
+			expectedErrors: []string{},
+		},
+		{name: "Not a collaborator",
+			payload:        &git_provider.WebhookPayload{Event: "pull_request_comment", Action: "created", Repo: "repo1", User: "collaborator", Comment: "/piper retry", CommentAuthor: "outsider"},
+			expectedErrors: []string{"outsider is not a collaborator of repo repo1"},
+		},
+		{name: "Unknown command",
+			payload:        &git_provider.WebhookPayload{Event: "pull_request_comment", Action: "created", Repo: "repo1", Comment: "/piper deploy", CommentAuthor: "collaborator"},
+			expectedErrors: []string{"unknown command deploy, expected retry, run or cancel"},
+		},
+		{name: "Run without trigger",
+			payload:        &git_provider.WebhookPayload{Event: "pull_request_comment", Action: "created", Repo: "repo1", Comment: "/piper run", CommentAuthor: "collaborator"},
+			expectedErrors: []string{"expected /piper run <trigger>, got 0 arguments"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := HandleChatOps(ctx, &conf.GlobalConfig{}, globalClients, test.payload)
+			assert.Nil(err)
+			assert.Equal(test.expectedErrors, result.Errors)
+			assert.Empty(result.Workflows)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package webhook_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	workflowHandler "github.com/quickube/piper/pkg/workflow_handler"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderWebhook evaluates every trigger for the payload and renders the matching workflows without submitting them.
+func RenderWebhook(ctx context.Context, wh *WebhookHandlerImpl) (*RenderResult, error) {
+	err := wh.RegisterTriggers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register triggers, error: %v", err)
+	}
+
+	result := &RenderResult{
+		Payload:   wh.Payload,
+		Triggers:  make([]TriggerMatch, 0, len(*wh.Triggers)),
+		Workflows: make([]RenderedWorkflow, 0),
+	}
+
+	for i := range *wh.Triggers {
+		trigger := &(*wh.Triggers)[i]
+		match := TriggerMatch{
+			Index:  i,
+			Name:   trigger.Name,
+			Config: trigger.Config,
+		}
+		if trigger.Events != nil {
+			match.Events = *trigger.Events
+		}
+		if trigger.Branches != nil {
+			match.Branches = *trigger.Branches
+		}
+
+		matched, reason, err := wh.MatchTrigger(trigger)
+		if err != nil {
+			reason = err.Error()
+		}
+		match.Matched = matched
+		match.Reason = reason
+		result.Triggers = append(result.Triggers, match)
+
+		if matched {
+			result.Workflows = append(result.Workflows, renderTrigger(ctx, wh, i, trigger))
+		}
+	}
+
+	return result, nil
+}
+
+func renderTrigger(ctx context.Context, wh *WebhookHandlerImpl, index int, trigger *Trigger) RenderedWorkflow {
+	rendered := RenderedWorkflow{
+		Trigger: index,
+		Config:  trigger.Config,
+	}
+
+	workflowsBatch, err := wh.PrepareBatch(ctx, trigger)
+	if err != nil {
+		rendered.Error = err.Error()
+		return rendered
+	}
+
+	workflow, err := wh.clients.Workflows.RenderWorkflow(ctx, workflowsBatch)
+	if err != nil {
+		rendered.Error = err.Error()
+		return rendered
+	}
+
+	var lintErr *workflowHandler.LintError
+	if err = wh.clients.Workflows.Lint(workflow); errors.As(err, &lintErr) {
+		rendered.LintErrors = lintErr.Errors
+	} else if err != nil {
+		rendered.Error = err.Error()
+	}
+	if err = wh.clients.Workflows.ResolveTemplateRefs(ctx, workflow); errors.As(err, &lintErr) {
+		rendered.LintErrors = append(rendered.LintErrors, lintErr.Errors...)
+	} else if err != nil {
+		rendered.Error = err.Error()
+	}
+	var policyErr *workflowHandler.PolicyError
+	if err = wh.clients.Workflows.EnforcePolicy(ctx, workflow); errors.As(err, &policyErr) {
+		rendered.PolicyViolations = policyErr.Violations
+	} else if err != nil {
+		rendered.Error = err.Error()
+	}
+
+	workflowYaml, err := yaml.Marshal(workflow)
+	if err != nil {
+		rendered.Error = fmt.Sprintf("failed to marshal workflow, error: %v", err)
+		return rendered
+	}
+	rendered.Workflow = string(workflowYaml)
+
+	return rendered
+}
@@ -3,18 +3,71 @@ package webhook_handler
 import (
 	"context"
 	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/git_provider"
 )
 
 type Trigger struct {
-	Events    *[]string `yaml:"events"`
-	Branches  *[]string `yaml:"branches"`
-	OnStart   *[]string `yaml:"onStart"`
-	Templates *[]string `yaml:"templates"`
-	OnExit    *[]string `yaml:"onExit"`
-	Config    string    `yaml:"config" default:"default"`
+	Name                string               `yaml:"name"`
+	Events              *[]string            `yaml:"events"`
+	Branches            *[]string            `yaml:"branches"`
+	OnStart             *[]string            `yaml:"onStart"`
+	Templates           *[]string            `yaml:"templates"`
+	OnExit              *[]string            `yaml:"onExit"`
+	Config              string               `yaml:"config" default:"default"`
+	WorkflowTemplateRef *WorkflowTemplateRef `yaml:"workflowTemplateRef"`
+	Concurrency         *Concurrency         `yaml:"concurrency"`
+	Parameters          []common.Parameter   `yaml:"parameters"`
+	NodeStatuses        bool                 `yaml:"nodeStatuses"`
+}
+
+type Concurrency struct {
+	Group            string `yaml:"group"`
+	CancelInProgress bool   `yaml:"cancelInProgress"`
+	CancelStrategy   string `yaml:"cancelStrategy"`
+}
+
+type WorkflowTemplateRef struct {
+	Name         string `yaml:"name"`
+	ClusterScope bool   `yaml:"clusterScope"`
 }
 
 type WebhookHandler interface {
 	RegisterTriggers(ctx context.Context) error
 	PrepareBatchForMatchingTriggers(ctx context.Context) ([]*common.WorkflowsBatch, error)
 }
+
+type TriggerMatch struct {
+	Index    int      `json:"index"`
+	Name     string   `json:"name,omitempty"`
+	Events   []string `json:"events"`
+	Branches []string `json:"branches"`
+	Config   string   `json:"config"`
+	Matched  bool     `json:"matched"`
+	Reason   string   `json:"reason"`
+}
+
+type RenderedWorkflow struct {
+	Trigger          int      `json:"trigger"`
+	Config           string   `json:"config"`
+	Workflow         string   `json:"workflow,omitempty"`
+	LintErrors       []string `json:"lintErrors,omitempty"`
+	PolicyViolations []string `json:"policyViolations,omitempty"`
+	Error            string   `json:"error,omitempty"`
+}
+
+type RenderResult struct {
+	Payload   *git_provider.WebhookPayload `json:"payload"`
+	Triggers  []TriggerMatch               `json:"triggers"`
+	Workflows []RenderedWorkflow           `json:"workflows"`
+}
+
+type ChatOpsCommand struct {
+	Name string
+	Args []string
+}
+
+type ProcessResult struct {
+	Triggers  []int
+	Workflows []string
+	Errors    []string
+}
@@ -2,14 +2,35 @@ package webhook_handler
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/common"
 	"github.com/quickube/piper/pkg/conf"
 	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/metrics"
+	"github.com/quickube/piper/pkg/tracing"
 	"github.com/quickube/piper/pkg/utils"
+	workflowHandler "github.com/quickube/piper/pkg/workflow_handler"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
-	"log"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoMatchingTrigger = errors.New("no matching trigger found")
+	// ErrNoTriggers is returned for the branches without .workflows/triggers.yaml, which don't use Piper.
+	ErrNoTriggers = errors.New("no triggers found")
+	// ErrInvalidTriggers is returned for triggers that fail to parse or miss files, which retrying doesn't fix.
+	ErrInvalidTriggers = errors.New("invalid triggers")
 )
 
 type WebhookHandlerImpl struct {
@@ -30,13 +51,32 @@ func NewWebhookHandler(cfg *conf.GlobalConfig, clients *clients.Clients, payload
 	}, err
 }
 
-func (wh *WebhookHandlerImpl) RegisterTriggers(ctx context.Context) error {
-	if !IsFileExists(ctx, wh, "", ".workflows") {
-		return fmt.Errorf(".workflows folder does not exist in %s/%s", wh.Payload.Repo, wh.Payload.Branch)
+// logger returns the logger of the clients of the handler.
+func (wh *WebhookHandlerImpl) logger() *slog.Logger {
+	if wh.clients == nil {
+		return slog.Default()
 	}
+	return logging.OrDefault(wh.clients.Logger)
+}
 
-	if !IsFileExists(ctx, wh, ".workflows", "triggers.yaml") {
-		return fmt.Errorf(".workflows/triggers.yaml file does not exist in %s/%s", wh.Payload.Repo, wh.Payload.Branch)
+func (wh *WebhookHandlerImpl) RegisterTriggers(ctx context.Context) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "RegisterTriggers")
+	defer func() { tracing.End(span, err) }()
+
+	exists, err := IsFileExists(ctx, wh, "", ".workflows")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w, .workflows folder does not exist in %s/%s", ErrNoTriggers, wh.Payload.Repo, wh.Payload.Branch)
+	}
+
+	exists, err = IsFileExists(ctx, wh, ".workflows", "triggers.yaml")
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w, .workflows/triggers.yaml file does not exist in %s/%s", ErrNoTriggers, wh.Payload.Repo, wh.Payload.Branch)
 	}
 
 	triggers, err := wh.clients.GitProvider.GetFile(ctx, wh.Payload.Repo, wh.Payload.Branch, ".workflows/triggers.yaml")
@@ -44,150 +84,327 @@ func (wh *WebhookHandlerImpl) RegisterTriggers(ctx context.Context) error {
 		return fmt.Errorf("failed to get triggers content: %v", err)
 	}
 
-	log.Printf("triggers content is: \n %s \n", *triggers.Content) // DEBUG
+	wh.logger().DebugContext(ctx, "read triggers", "content", *triggers.Content)
 
 	err = yaml.Unmarshal([]byte(*triggers.Content), wh.Triggers)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal triggers content: %v", err)
+		return fmt.Errorf("%w, failed to unmarshal triggers content: %v", ErrInvalidTriggers, err)
+	}
+
+	if wh.cfg != nil {
+		ApplyTriggerOverrides(*wh.Triggers, wh.cfg.WorkflowServerConfig.RouteFor(wh.Payload.Repo).TriggerOverrides)
 	}
 	return nil
 }
 
+// ApplyTriggerOverrides replaces the events, branches and config of the named triggers with the set override fields.
+func ApplyTriggerOverrides(triggers []Trigger, overrides []conf.TriggerOverride) {
+	for _, override := range overrides {
+		for i := range triggers {
+			trigger := &triggers[i]
+			if override.Name == "" || trigger.Name != override.Name {
+				continue
+			}
+			if len(override.Events) != 0 {
+				events := append([]string{}, override.Events...)
+				trigger.Events = &events
+			}
+			if len(override.Branches) != 0 {
+				branches := append([]string{}, override.Branches...)
+				trigger.Branches = &branches
+			}
+			if override.Config != "" {
+				trigger.Config = override.Config
+			}
+		}
+	}
+}
+
 func (wh *WebhookHandlerImpl) PrepareBatchForMatchingTriggers(ctx context.Context) ([]*common.WorkflowsBatch, error) {
 	triggered := false
 	var workflowBatches []*common.WorkflowsBatch
-	for _, trigger := range *wh.Triggers {
-		if trigger.Branches == nil {
-			return nil, fmt.Errorf("trigger from repo %s branch %s missing branch field", wh.Payload.Repo, wh.Payload.Branch)
-		}
-		if trigger.Events == nil {
-			return nil, fmt.Errorf("trigger from repo %s branch %s missing event field", wh.Payload.Repo, wh.Payload.Branch)
-		}
-
-		eventToCheck := wh.Payload.Event
-		if wh.Payload.Action != "" {
-			eventToCheck += "." + wh.Payload.Action
+	for i := range *wh.Triggers {
+		trigger := &(*wh.Triggers)[i]
+		matched, _, err := wh.MatchTrigger(trigger)
+		if err != nil {
+			return nil, err
 		}
-		if utils.IsElementMatch(wh.Payload.Branch, *trigger.Branches) && utils.IsElementMatch(eventToCheck, *trigger.Events) {
-			log.Printf(
-				"Triggering event %s for repo %s branch %s are triggered.",
-				wh.Payload.Event,
-				wh.Payload.Repo,
-				wh.Payload.Branch,
-			)
+		if matched {
+			metrics.OrNoop(wh.clients.Metrics).TriggerMatched(wh.Payload.Repo, wh.Payload.Event)
+			wh.logger().InfoContext(ctx, "trigger matched", "event", wh.Payload.Event, "trigger", i)
 			triggered = true
-			onStartFiles, err := wh.clients.GitProvider.GetFiles(
-				ctx,
-				wh.Payload.Repo,
-				wh.Payload.Branch,
-				utils.AddPrefixToList(*trigger.OnStart, ".workflows/"),
-			)
-			if len(onStartFiles) == 0 {
-				return nil, fmt.Errorf("one or more of onStart: %s files found in repo: %s branch %s", *trigger.OnStart, wh.Payload.Repo, wh.Payload.Branch)
-			}
+			batchCtx, span := tracing.Tracer().Start(ctx, "PrepareBatch",
+				trace.WithAttributes(attribute.Int("trigger", i), attribute.String("trigger.name", trigger.Name)))
+			workflowsBatch, err := wh.PrepareBatch(batchCtx, trigger)
+			tracing.End(span, err)
 			if err != nil {
 				return nil, err
 			}
+			workflowsBatch.TriggerIndex = i
+			workflowBatches = append(workflowBatches, workflowsBatch)
+		}
+	}
+	if !triggered {
+		return nil, fmt.Errorf("%w for event: %s action: %s in branch :%s", ErrNoMatchingTrigger, wh.Payload.Event, wh.Payload.Action, wh.Payload.Branch)
+	}
+	return workflowBatches, nil
+}
 
-			onExitFiles := make([]*git_provider.CommitFile, 0)
-			if trigger.OnExit != nil {
-				onExitFiles, err = wh.clients.GitProvider.GetFiles(
-					ctx,
-					wh.Payload.Repo,
-					wh.Payload.Branch,
-					utils.AddPrefixToList(*trigger.OnExit, ".workflows/"),
-				)
-				if len(onExitFiles) == 0 {
-					log.Printf("one or more of onExist: %s files not found in repo: %s branch %s", *trigger.OnExit, wh.Payload.Repo, wh.Payload.Branch)
-				}
-				if err != nil {
-					return nil, err
-				}
-			}
+// MatchTrigger reports whether the trigger matches the payload event and branch, with the reason for the decision.
+func (wh *WebhookHandlerImpl) MatchTrigger(trigger *Trigger) (bool, string, error) {
+	if trigger.Branches == nil {
+		return false, "", fmt.Errorf("%w, trigger from repo %s branch %s missing branch field", ErrInvalidTriggers, wh.Payload.Repo, wh.Payload.Branch)
+	}
+	if trigger.Events == nil {
+		return false, "", fmt.Errorf("%w, trigger from repo %s branch %s missing event field", ErrInvalidTriggers, wh.Payload.Repo, wh.Payload.Branch)
+	}
 
-			templatesFiles := make([]*git_provider.CommitFile, 0)
-			if trigger.Templates != nil {
-				templatesFiles, err = wh.clients.GitProvider.GetFiles(
-					ctx,
-					wh.Payload.Repo,
-					wh.Payload.Branch,
-					utils.AddPrefixToList(*trigger.Templates, ".workflows/"),
-				)
-				if len(templatesFiles) == 0 {
-					log.Printf("one or more of templates: %s files not found in repo: %s branch %s", *trigger.Templates, wh.Payload.Repo, wh.Payload.Branch)
-				}
-				if err != nil {
-					return nil, err
-				}
-			}
+	eventToCheck := wh.Payload.Event
+	if wh.Payload.Action != "" {
+		eventToCheck += "." + wh.Payload.Action
+	}
+	if !utils.IsElementMatch(wh.Payload.Branch, *trigger.Branches) {
+		return false, fmt.Sprintf("branch %s does not match branches %v", wh.Payload.Branch, *trigger.Branches), nil
+	}
+	if !utils.IsElementMatch(eventToCheck, *trigger.Events) {
+		return false, fmt.Sprintf("event %s does not match events %v", eventToCheck, *trigger.Events), nil
+	}
 
-			parameters := &git_provider.CommitFile{
-				Path:    nil,
-				Content: nil,
-			}
-			if IsFileExists(ctx, wh, ".workflows", "parameters.yaml") {
-				parameters, err = wh.clients.GitProvider.GetFile(
-					ctx,
-					wh.Payload.Repo,
-					wh.Payload.Branch,
-					".workflows/parameters.yaml",
-				)
-				if err != nil {
-					return nil, err
-				}
-			} else {
-				log.Printf("parameters.yaml not found in repo: %s branch %s", wh.Payload.Repo, wh.Payload.Branch)
-			}
+	return true, fmt.Sprintf("branch %s matches branches %v and event %s matches events %v", wh.Payload.Branch, *trigger.Branches, eventToCheck, *trigger.Events), nil
+}
 
-			workflowBatches = append(workflowBatches, &common.WorkflowsBatch{
-				OnStart:    onStartFiles,
-				OnExit:     onExitFiles,
-				Templates:  templatesFiles,
-				Parameters: parameters,
-				Config:     &trigger.Config,
-				Payload:    wh.Payload,
-			})
+// PrepareBatch fetches the files referenced by a trigger and builds its workflows batch.
+func (wh *WebhookHandlerImpl) PrepareBatch(ctx context.Context, trigger *Trigger) (*common.WorkflowsBatch, error) {
+	var err error
+	var workflowTemplateRef *v1alpha1.WorkflowTemplateRef
+	if trigger.WorkflowTemplateRef != nil {
+		if trigger.WorkflowTemplateRef.Name == "" {
+			return nil, fmt.Errorf("%w, trigger from repo %s branch %s missing workflowTemplateRef name", ErrInvalidTriggers, wh.Payload.Repo, wh.Payload.Branch)
+		}
+		// Argo rejects the workflows created from a WorkflowTemplate that have templates of their own
+		if trigger.OnStart != nil || trigger.OnExit != nil || trigger.Templates != nil {
+			return nil, fmt.Errorf("%w, trigger from repo %s branch %s can't set onStart, onExit or templates with workflowTemplateRef", ErrInvalidTriggers, wh.Payload.Repo, wh.Payload.Branch)
+		}
+		workflowTemplateRef = &v1alpha1.WorkflowTemplateRef{
+			Name:         trigger.WorkflowTemplateRef.Name,
+			ClusterScope: trigger.WorkflowTemplateRef.ClusterScope,
 		}
 	}
-	if !triggered {
-		return nil, fmt.Errorf("no matching trigger found for event: %s action: %s in branch :%s", wh.Payload.Event, wh.Payload.Action, wh.Payload.Branch)
+
+	concurrency, err := ParseConcurrency(trigger.Concurrency, wh.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w, %v", ErrInvalidTriggers, err)
 	}
-	return workflowBatches, nil
+
+	idempotencyKey := GetIdempotencyKey(trigger, wh.Payload)
+
+	onStartFiles := make([]*git_provider.CommitFile, 0)
+	if trigger.OnStart != nil {
+		onStartFiles, err = wh.clients.GitProvider.GetFiles(
+			ctx,
+			wh.Payload.Repo,
+			wh.Payload.Branch,
+			utils.AddPrefixToList(*trigger.OnStart, ".workflows/"),
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(onStartFiles) == 0 {
+			return nil, fmt.Errorf("%w, one or more of onStart: %s files found in repo: %s branch %s", ErrInvalidTriggers, *trigger.OnStart, wh.Payload.Repo, wh.Payload.Branch)
+		}
+	} else if workflowTemplateRef == nil {
+		return nil, fmt.Errorf("%w, trigger from repo %s branch %s missing onStart or workflowTemplateRef field", ErrInvalidTriggers, wh.Payload.Repo, wh.Payload.Branch)
+	}
+
+	onExitFiles := make([]*git_provider.CommitFile, 0)
+	if trigger.OnExit != nil {
+		onExitFiles, err = wh.clients.GitProvider.GetFiles(
+			ctx,
+			wh.Payload.Repo,
+			wh.Payload.Branch,
+			utils.AddPrefixToList(*trigger.OnExit, ".workflows/"),
+		)
+		if len(onExitFiles) == 0 {
+			wh.logger().WarnContext(ctx, "one or more of onExit files not found", "files", *trigger.OnExit)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	templatesFiles := make([]*git_provider.CommitFile, 0)
+	if trigger.Templates != nil {
+		templatesFiles, err = wh.clients.GitProvider.GetFiles(
+			ctx,
+			wh.Payload.Repo,
+			wh.Payload.Branch,
+			utils.AddPrefixToList(*trigger.Templates, ".workflows/"),
+		)
+		if len(templatesFiles) == 0 {
+			wh.logger().WarnContext(ctx, "one or more of templates files not found", "files", *trigger.Templates)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	parameters := &git_provider.CommitFile{
+		Path:    nil,
+		Content: nil,
+	}
+	exists, err := IsFileExists(ctx, wh, ".workflows", "parameters.yaml")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		parameters, err = wh.clients.GitProvider.GetFile(
+			ctx,
+			wh.Payload.Repo,
+			wh.Payload.Branch,
+			".workflows/parameters.yaml",
+		)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		wh.logger().DebugContext(ctx, "parameters.yaml not found")
+	}
+
+	return &common.WorkflowsBatch{
+		OnStart:             onStartFiles,
+		OnExit:              onExitFiles,
+		Templates:           templatesFiles,
+		Parameters:          parameters,
+		TriggerParameters:   trigger.Parameters,
+		Config:              &trigger.Config,
+		WorkflowTemplateRef: workflowTemplateRef,
+		Concurrency:         concurrency,
+		IdempotencyKey:      idempotencyKey,
+		NodeStatuses:        trigger.NodeStatuses,
+		Payload:             wh.Payload,
+	}, nil
+}
+
+// GetIdempotencyKey derives a deterministic key from the repo, commit, trigger and event,
+// so redeliveries of the same webhook map to the same workflow.
+// Comments are keyed by their delivery, so every ChatOps command runs the trigger again.
+// The trigger fields are listed explicitly, so new trigger fields don't change the keys of existing workflows.
+func GetIdempotencyKey(trigger *Trigger, payload *git_provider.WebhookPayload) string {
+	values := []string{
+		payload.Repo,
+		payload.Commit,
+		payload.Event,
+		payload.Action,
+		trigger.Name,
+		joinKeyList(trigger.Events),
+		joinKeyList(trigger.Branches),
+		joinKeyList(trigger.OnStart),
+		joinKeyList(trigger.Templates),
+		joinKeyList(trigger.OnExit),
+		trigger.Config,
+	}
+	if trigger.WorkflowTemplateRef != nil {
+		values = append(values, trigger.WorkflowTemplateRef.Name, strconv.FormatBool(trigger.WorkflowTemplateRef.ClusterScope))
+	}
+	if payload.Comment != "" {
+		values = append(values, payload.DeliveryID)
+	}
+
+	hash := sha256.New()
+	for _, value := range values {
+		hash.Write([]byte(value))
+		hash.Write([]byte{0})
+	}
+	// Truncated to fit a label value
+	return hex.EncodeToString(hash.Sum(nil))[:40]
 }
 
-func IsFileExists(ctx context.Context, wh *WebhookHandlerImpl, path string, file string) bool {
+func joinKeyList(list *[]string) string {
+	if list == nil {
+		return ""
+	}
+	return strings.Join(*list, "\x1f")
+}
+
+var groupVariablePattern = regexp.MustCompile(`{{\s*([a-z_]+)\s*}}`)
+
+// ParseConcurrency renders the concurrency group of a trigger with the payload values, e.g. {{repo}}-{{branch}}.
+func ParseConcurrency(concurrency *Concurrency, payload *git_provider.WebhookPayload) (*common.Concurrency, error) {
+	if concurrency == nil {
+		return nil, nil
+	}
+	if concurrency.Group == "" {
+		return nil, fmt.Errorf("concurrency group cannot be empty for repo %s branch %s", payload.Repo, payload.Branch)
+	}
+
+	strategy := v1alpha1.ShutdownStrategyStop
+	switch concurrency.CancelStrategy {
+	case "", "stop":
+	case "terminate":
+		strategy = v1alpha1.ShutdownStrategyTerminate
+	default:
+		return nil, fmt.Errorf("unknown concurrency cancelStrategy %s, expected stop or terminate", concurrency.CancelStrategy)
+	}
+
+	variables := map[string]string{
+		"event":       payload.Event,
+		"action":      payload.Action,
+		"repo":        payload.Repo,
+		"branch":      payload.Branch,
+		"commit":      payload.Commit,
+		"user":        payload.User,
+		"dest_branch": payload.DestBranch,
+	}
+	var unknown []string
+	group := groupVariablePattern.ReplaceAllStringFunc(concurrency.Group, func(match string) string {
+		name := groupVariablePattern.FindStringSubmatch(match)[1]
+		value, ok := variables[name]
+		if !ok {
+			unknown = append(unknown, name)
+		}
+		return value
+	})
+	if len(unknown) != 0 {
+		return nil, fmt.Errorf("unknown variables %v in concurrency group %s", unknown, concurrency.Group)
+	}
+
+	return &common.Concurrency{
+		Group:            group,
+		CancelInProgress: concurrency.CancelInProgress,
+		CancelStrategy:   strategy,
+	}, nil
+}
+
+// IsFileExists reports whether file is in the path directory of the branch. Failing to list the directory is an error,
+// as it doesn't tell whether the file exists.
+func IsFileExists(ctx context.Context, wh *WebhookHandlerImpl, path string, file string) (bool, error) {
 	files, err := wh.clients.GitProvider.ListFiles(ctx, wh.Payload.Repo, wh.Payload.Branch, path)
 	if err != nil {
-		log.Printf("Error listing files in repo: %s branch: %s. %v", wh.Payload.Repo, wh.Payload.Branch, err)
-		return false
+		return false, fmt.Errorf("failed to list files in %s, error: %v", path, err)
 	}
 	if len(files) == 0 {
-		log.Printf("Empty list of files in repo: %s branch: %s", wh.Payload.Repo, wh.Payload.Branch)
-		return false
-	}
-
-	if utils.IsElementExists(files, file) {
-		return true
+		wh.logger().DebugContext(ctx, "empty list of files", "path", path)
+		return false, nil
 	}
 
-	return false
+	return utils.IsElementExists(files, file), nil
 }
 
 func HandleWebhook(ctx context.Context, wh *WebhookHandlerImpl) ([]*common.WorkflowsBatch, error) {
 	err := wh.RegisterTriggers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to register triggers, error: %v", err)
+		return nil, fmt.Errorf("failed to register triggers, error: %w", err)
 	} else {
-		log.Printf("successfully registered triggers for repo: %s branch: %s", wh.Payload.Repo, wh.Payload.Branch)
+		wh.logger().InfoContext(ctx, "registered triggers", "triggers", len(*wh.Triggers))
 	}
 
 	workflowsBatches, err := wh.PrepareBatchForMatchingTriggers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare matching triggers, error: %v", err)
+		return nil, fmt.Errorf("failed to prepare matching triggers, error: %w", err)
 	}
 
 	if len(workflowsBatches) == 0 {
-		log.Printf("no workflows to execute")
+		wh.logger().InfoContext(ctx, "no workflows to execute")
 		return nil, fmt.Errorf("no workflows to execute for repo: %s branch: %s",
 			wh.Payload.Repo,
 			wh.Payload.Branch,
@@ -195,3 +412,83 @@ func HandleWebhook(ctx context.Context, wh *WebhookHandlerImpl) ([]*common.Workf
 	}
 	return workflowsBatches, nil
 }
+
+// ReportBatchFailure sets a failed commit status for a batch that could not be submitted.
+func ReportBatchFailure(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients, workflowsBatch *common.WorkflowsBatch, reason error) error {
+	phase := v1alpha1.WorkflowFailed
+	status, err := clients.GitProvider.GetCorrelatingEvent(ctx, &phase)
+	if err != nil {
+		return fmt.Errorf("failed to translate workflow status for phase: %s, error: %v", phase, err)
+	}
+
+	namespace := cfg.WorkflowServerConfig.RouteFor(workflowsBatch.Payload.Repo).Namespace
+	link := fmt.Sprintf("%s/workflows/%s", cfg.WorkflowServerConfig.ArgoAddress, namespace)
+	message := utils.TrimString(reason.Error(), 140) // Max length of message is 140 characters
+	err = clients.GitProvider.SetStatus(ctx, &workflowsBatch.Payload.Repo, &workflowsBatch.Payload.Commit, &link, &status, &message)
+	if err != nil {
+		return fmt.Errorf("failed to set failure status for repo: %s commit: %s, error: %v", workflowsBatch.Payload.Repo, workflowsBatch.Payload.Commit, err)
+	}
+
+	return nil
+}
+
+// ProcessWebhook runs the triggers of the repo for the payload and submits the matching workflows.
+// Workflows that fail lint, parameters rendering or validation are reported to the commit status instead of failing the processing.
+// Pull request comments are handled as ChatOps commands.
+func ProcessWebhook(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients, payload *git_provider.WebhookPayload) (*ProcessResult, error) {
+	if payload.Comment != "" {
+		return HandleChatOps(ctx, cfg, clients, payload)
+	}
+
+	result := newProcessResult()
+	wh, err := NewWebhookHandler(cfg, clients, payload)
+	if err != nil {
+		return result, fmt.Errorf("failed to create webhook handler, error: %v", err)
+	}
+
+	workflowsBatches, err := HandleWebhook(ctx, wh)
+	if errors.Is(err, ErrNoMatchingTrigger) || errors.Is(err, ErrNoTriggers) {
+		wh.logger().InfoContext(ctx, "skipping webhook", "reason", err)
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to handle webhook, error: %w", err)
+	}
+
+	err = submitBatches(ctx, cfg, clients, workflowsBatches, result)
+	return result, err
+}
+
+// submitBatches submits the workflows batches and records the outcome in the result.
+func submitBatches(ctx context.Context, cfg *conf.GlobalConfig, clients *clients.Clients, workflowsBatches []*common.WorkflowsBatch, result *ProcessResult) error {
+	logger := logging.OrDefault(clients.Logger)
+	for _, wf := range workflowsBatches {
+		result.Triggers = append(result.Triggers, wf.TriggerIndex)
+		created, err := clients.Workflows.HandleWorkflowBatch(ctx, wf)
+		if workflowHandler.IsDefinitionError(err) {
+			logger.WarnContext(ctx, "workflow cannot be submitted", "trigger", wf.TriggerIndex, "error", err)
+			result.Errors = append(result.Errors, err.Error())
+			err = ReportBatchFailure(ctx, cfg, clients, wf, err)
+			if err != nil {
+				logger.ErrorContext(ctx, "failed to report workflow failure", "error", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to handle workflow, error: %v", err)
+		}
+		if created != nil {
+			result.Workflows = append(result.Workflows, created.GetName())
+		}
+	}
+
+	return nil
+}
+
+func newProcessResult() *ProcessResult {
+	return &ProcessResult{
+		Triggers:  make([]int, 0),
+		Workflows: make([]string, 0),
+		Errors:    make([]string, 0),
+	}
+}
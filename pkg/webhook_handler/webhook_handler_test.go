@@ -6,6 +6,7 @@ import (
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/clients"
 	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/conf"
 	"github.com/quickube/piper/pkg/git_provider"
 	"github.com/quickube/piper/pkg/utils"
 	assertion "github.com/stretchr/testify/assert"
@@ -96,6 +97,10 @@ func (m *mockGitProvider) HandlePayload(ctx context.Context, request *http.Reque
 func (m *mockGitProvider) SetStatus(ctx context.Context, repo *string, commit *string, linkURL *string, status *string, message *string) error {
 	return nil
 }
+
+func (m *mockGitProvider) SetNamedStatus(ctx context.Context, repo *string, commit *string, name string, linkURL *string, status *string, message *string) error {
+	return nil
+}
 func (m *mockGitProvider) GetCorrelatingEvent(ctx context.Context, workflowEvent *v1alpha1.WorkflowPhase) (string, error) {
 	return "", nil
 }
@@ -103,6 +108,18 @@ func (m *mockGitProvider) PingHook(ctx context.Context, hook *git_provider.HookW
 	return nil
 }
 
+func (m *mockGitProvider) CommentOnPullRequest(ctx context.Context, repo string, pullRequestID int, body string) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockGitProvider) UpdateComment(ctx context.Context, repo string, pullRequestID int, commentID int64, body string) error {
+	return nil
+}
+
+func (m *mockGitProvider) IsCollaborator(ctx context.Context, repo string, user string) (bool, error) {
+	return user == "collaborator", nil
+}
+
 func TestPrepareBatchForMatchingTriggers(t *testing.T) {
 	assert := assertion.New(t)
 	ctx := context.Background()
@@ -547,3 +564,220 @@ func TestPrepareBatchForMatchingTriggers(t *testing.T) {
 	}
 
 }
+
+func TestPrepareBatch_WorkflowTemplateRef(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	wh := &WebhookHandlerImpl{
+		Payload: &git_provider.WebhookPayload{Repo: "repo1", Branch: "branch1"},
+		clients: &clients.Clients{
+			GitProvider: &mockGitProvider{},
+		},
+	}
+
+	workflowsBatch, err := wh.PrepareBatch(ctx, &Trigger{WorkflowTemplateRef: &WorkflowTemplateRef{Name: "build", ClusterScope: true}})
+	assert.Nil(err)
+	assert.Equal(&v1alpha1.WorkflowTemplateRef{Name: "build", ClusterScope: true}, workflowsBatch.WorkflowTemplateRef)
+	assert.Empty(workflowsBatch.OnStart)
+
+	_, err = wh.PrepareBatch(ctx, &Trigger{OnStart: &[]string{"main.yaml"}, WorkflowTemplateRef: &WorkflowTemplateRef{Name: "build"}})
+	assert.ErrorContains(err, "can't set onStart, onExit or templates with workflowTemplateRef")
+
+	_, err = wh.PrepareBatch(ctx, &Trigger{OnExit: &[]string{"exit.yaml"}, WorkflowTemplateRef: &WorkflowTemplateRef{Name: "build"}})
+	assert.ErrorContains(err, "can't set onStart, onExit or templates with workflowTemplateRef")
+}
+
+func TestMatchTrigger(t *testing.T) {
+	assert := assertion.New(t)
+
+	trigger := &Trigger{
+		Events:   &[]string{"push", "pull_request.synchronize"},
+		Branches: &[]string{"main"},
+		OnStart:  &[]string{"main.yaml"},
+	}
+	tests := []struct {
+		name          string
+		payload       *git_provider.WebhookPayload
+		trigger       *Trigger
+		expectedMatch bool
+		expectedError bool
+	}{
+		{name: "Matching event and branch",
+			payload:       &git_provider.WebhookPayload{Event: "push", Branch: "main"},
+			trigger:       trigger,
+			expectedMatch: true,
+		},
+		{name: "Matching event with action",
+			payload:       &git_provider.WebhookPayload{Event: "pull_request", Action: "synchronize", Branch: "main"},
+			trigger:       trigger,
+			expectedMatch: true,
+		},
+		{name: "Not matching branch",
+			payload:       &git_provider.WebhookPayload{Event: "push", Branch: "dev"},
+			trigger:       trigger,
+			expectedMatch: false,
+		},
+		{name: "Not matching event",
+			payload:       &git_provider.WebhookPayload{Event: "pull_request", Action: "opened", Branch: "main"},
+			trigger:       trigger,
+			expectedMatch: false,
+		},
+		{name: "Missing branches",
+			payload:       &git_provider.WebhookPayload{Event: "push", Branch: "main"},
+			trigger:       &Trigger{Events: &[]string{"push"}},
+			expectedError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wh := &WebhookHandlerImpl{Payload: test.payload}
+			matched, reason, err := wh.MatchTrigger(test.trigger)
+			if test.expectedError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.expectedMatch, matched)
+			assert.NotEmpty(reason)
+		})
+	}
+}
+
+func TestParseConcurrency(t *testing.T) {
+	assert := assertion.New(t)
+	payload := &git_provider.WebhookPayload{Repo: "my-repo", Branch: "feature", Commit: "abc123"}
+
+	concurrency, err := ParseConcurrency(nil, payload)
+	assert.Nil(err)
+	assert.Nil(concurrency)
+
+	concurrency, err = ParseConcurrency(&Concurrency{Group: "{{repo}}-{{ branch }}", CancelInProgress: true}, payload)
+	assert.Nil(err)
+	assert.Equal("my-repo-feature", concurrency.Group)
+	assert.True(concurrency.CancelInProgress)
+	assert.Equal(v1alpha1.ShutdownStrategyStop, concurrency.CancelStrategy)
+
+	concurrency, err = ParseConcurrency(&Concurrency{Group: "{{repo}}", CancelStrategy: "terminate"}, payload)
+	assert.Nil(err)
+	assert.Equal(v1alpha1.ShutdownStrategyTerminate, concurrency.CancelStrategy)
+
+	_, err = ParseConcurrency(&Concurrency{Group: "{{repository}}"}, payload)
+	assert.NotNil(err)
+
+	_, err = ParseConcurrency(&Concurrency{Group: "{{repo}}", CancelStrategy: "kill"}, payload)
+	assert.NotNil(err)
+}
+
+func TestApplyTriggerOverrides(t *testing.T) {
+	assert := assertion.New(t)
+	triggers := []Trigger{
+		{Name: "build", Events: &[]string{"push"}, Branches: &[]string{"*"}, Config: "default"},
+		{Name: "deploy", Events: &[]string{"push"}, Branches: &[]string{"*"}, Config: "default"},
+		{Events: &[]string{"pull_request"}, Branches: &[]string{"*"}},
+	}
+
+	ApplyTriggerOverrides(triggers, []conf.TriggerOverride{
+		{Name: "deploy", Branches: []string{"main"}, Config: "production"},
+		{Name: "missing", Events: []string{"release"}},
+		{Events: []string{"release"}},
+	})
+
+	assert.Equal([]string{"*"}, *triggers[0].Branches)
+	assert.Equal([]string{"push"}, *triggers[1].Events)
+	assert.Equal([]string{"main"}, *triggers[1].Branches)
+	assert.Equal("production", triggers[1].Config)
+	assert.Equal([]string{"pull_request"}, *triggers[2].Events)
+}
+
+func TestGetIdempotencyKey(t *testing.T) {
+	assert := assertion.New(t)
+	payload := &git_provider.WebhookPayload{Repo: "my-repo", Event: "push", Commit: "abc123", DeliveryID: "delivery-1"}
+	trigger := &Trigger{Events: &[]string{"push"}, Branches: &[]string{"main"}, OnStart: &[]string{"main.yaml"}}
+
+	key := GetIdempotencyKey(trigger, payload)
+	assert.Len(key, 40)
+
+	redelivery := *payload
+	redelivery.DeliveryID = "delivery-2"
+	assert.Equal(key, GetIdempotencyKey(trigger, &redelivery))
+
+	otherCommit := *payload
+	otherCommit.Commit = "def456"
+	assert.NotEqual(key, GetIdempotencyKey(trigger, &otherCommit))
+
+	otherTrigger := &Trigger{Events: &[]string{"push"}, Branches: &[]string{"main"}, OnStart: &[]string{"release.yaml"}}
+	assert.NotEqual(key, GetIdempotencyKey(otherTrigger, payload))
+
+	templateRef := &Trigger{Events: &[]string{"push"}, Branches: &[]string{"main"}, WorkflowTemplateRef: &WorkflowTemplateRef{Name: "build"}}
+	clusterTemplateRef := &Trigger{Events: &[]string{"push"}, Branches: &[]string{"main"}, WorkflowTemplateRef: &WorkflowTemplateRef{Name: "build", ClusterScope: true}}
+	assert.NotEqual(GetIdempotencyKey(templateRef, payload), GetIdempotencyKey(clusterTemplateRef, payload))
+
+	// Fields outside of the key, like the ones added by upgrades, keep the key of the existing workflows
+	withNodeStatuses := *trigger
+	withNodeStatuses.NodeStatuses = true
+	withNodeStatuses.Parameters = []common.Parameter{{Name: "image"}}
+	assert.Equal(key, GetIdempotencyKey(&withNodeStatuses, payload))
+
+	comment := *payload
+	comment.Comment = "/piper run build"
+	otherComment := comment
+	otherComment.DeliveryID = "delivery-2"
+	assert.NotEqual(GetIdempotencyKey(trigger, &comment), GetIdempotencyKey(trigger, &otherComment))
+}
+
+// triggersGitProvider serves a .workflows folder holding a triggers.yaml with the given content.
+type triggersGitProvider struct {
+	mockGitProvider
+	triggers string
+	listErr  error
+}
+
+func (m *triggersGitProvider) ListFiles(ctx context.Context, repo string, branch string, path string) ([]string, error) {
+	if m.listErr != nil {
+		return nil, m.listErr
+	}
+	if path == "" {
+		return []string{".workflows"}, nil
+	}
+	return []string{"triggers.yaml"}, nil
+}
+
+func (m *triggersGitProvider) GetFile(ctx context.Context, repo string, branch string, path string) (*git_provider.CommitFile, error) {
+	return &git_provider.CommitFile{Path: &path, Content: &m.triggers}, nil
+}
+
+func TestProcessWebhook_Triggers(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	payload := &git_provider.WebhookPayload{Event: "push", Repo: "repo1", Branch: "main", Commit: "abc123"}
+
+	tests := []struct {
+		name        string
+		gitProvider git_provider.Client
+		expectedErr error
+		transient   bool
+	}{
+		{name: "Repo without triggers is skipped", gitProvider: &mockGitProvider{}},
+		{name: "Triggers without a match are skipped", gitProvider: &triggersGitProvider{triggers: "- events: [push]\n  branches: [release]\n  onStart: [main.yaml]\n"}},
+		{name: "Invalid triggers", gitProvider: &triggersGitProvider{triggers: "- events: [push\n"}, expectedErr: ErrInvalidTriggers},
+		{name: "Trigger missing its events", gitProvider: &triggersGitProvider{triggers: "- branches: [main]\n  onStart: [main.yaml]\n"}, expectedErr: ErrInvalidTriggers},
+		{name: "Failure to list the files", gitProvider: &triggersGitProvider{listErr: fmt.Errorf("git provider unavailable")}, transient: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ProcessWebhook(ctx, &conf.GlobalConfig{}, &clients.Clients{GitProvider: test.gitProvider}, payload)
+			switch {
+			case test.transient:
+				assert.NotNil(err)
+				assert.NotErrorIs(err, ErrNoTriggers)
+				assert.NotErrorIs(err, ErrInvalidTriggers)
+			case test.expectedErr != nil:
+				assert.ErrorIs(err, test.expectedErr)
+			default:
+				assert.Nil(err)
+			}
+		})
+	}
+}
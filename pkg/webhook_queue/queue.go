@@ -0,0 +1,239 @@
+package webhook_queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/logging"
+)
+
+// WebhookQueueImpl processes webhook payloads asynchronously with a fixed number of workers.
+// Failed payloads are scheduled for a retry with exponential backoff, freeing the worker in the meantime, and kept in a
+// bounded dead-letter list once retries are exhausted or when the error is permanent.
+type WebhookQueueImpl struct {
+	cfg      *conf.GlobalConfig
+	process  ProcessFunc
+	logger   *slog.Logger
+	queue    workqueue.DelayingInterface
+	capacity int
+	workers  int
+	wg       sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	mu sync.Mutex
+	// idle is signaled whenever a webhook is completed, for Stop to wait for the queue to drain.
+	idle         *sync.Cond
+	closed       bool
+	queued       int
+	inFlight     int
+	retrying     int
+	processed    int64
+	retried      int64
+	failed       int64
+	lastLatency  time.Duration
+	totalLatency time.Duration
+	deadLetters  []DeadLetter
+}
+
+func NewWebhookQueue(cfg *conf.GlobalConfig, process ProcessFunc, logger *slog.Logger) *WebhookQueueImpl {
+	capacity := cfg.WebhookConfig.QueueSize
+	if capacity < 1 {
+		capacity = 1
+	}
+	workers := cfg.WebhookConfig.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &WebhookQueueImpl{
+		cfg:         cfg,
+		process:     process,
+		logger:      logging.OrDefault(logger).With("component", "webhook_queue"),
+		queue:       workqueue.NewDelayingQueue(),
+		capacity:    capacity,
+		workers:     workers,
+		ctx:         ctx,
+		cancel:      cancel,
+		deadLetters: make([]DeadLetter, 0),
+	}
+	q.idle = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *WebhookQueueImpl) Start() {
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.logger.InfoContext(q.ctx, "webhook queue started", "workers", q.workers, "capacity", q.capacity)
+}
+
+// Enqueue adds the payload to the queue without blocking, returning ErrQueueFull when the queue is at capacity.
+// The span of ctx is kept as the parent of the processing spans.
+func (q *WebhookQueueImpl) Enqueue(ctx context.Context, eventID string, payload *git_provider.WebhookPayload) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.queued >= q.capacity {
+		return ErrQueueFull
+	}
+
+	q.queued++
+	q.queue.Add(&Item{EventID: eventID, Payload: payload, EnqueuedAt: time.Now(), SpanContext: trace.SpanContextFromContext(ctx)})
+	return nil
+}
+
+// Stop stops accepting payloads and waits for the queued ones, and the ones waiting for a retry, to be processed.
+// When the context is done first, the processing of the remaining payloads is cancelled.
+func (q *WebhookQueueImpl) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.mu.Lock()
+		for q.queued+q.inFlight+q.retrying > 0 && q.ctx.Err() == nil {
+			q.idle.Wait()
+		}
+		q.mu.Unlock()
+		q.queue.ShutDown()
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		q.logger.InfoContext(ctx, "webhook queue drained")
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		left := q.queued + q.retrying
+		q.cancel()
+		// The retries scheduled after the shutdown are dropped
+		q.idle.Broadcast()
+		q.mu.Unlock()
+		return fmt.Errorf("webhook queue drain timed out with %d webhooks left in the queue", left)
+	}
+}
+
+func (q *WebhookQueueImpl) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := Stats{
+		Depth:              q.queued,
+		Capacity:           q.capacity,
+		Workers:            q.workers,
+		InFlight:           q.inFlight,
+		Retrying:           q.retrying,
+		Processed:          q.processed,
+		Retried:            q.retried,
+		Failed:             q.failed,
+		LastLatencySeconds: q.lastLatency.Seconds(),
+	}
+	if completed := q.processed + q.failed; completed != 0 {
+		stats.AverageLatencySeconds = q.totalLatency.Seconds() / float64(completed)
+	}
+	return stats
+}
+
+func (q *WebhookQueueImpl) DeadLetters() []DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	deadLetters := make([]DeadLetter, len(q.deadLetters))
+	copy(deadLetters, q.deadLetters)
+	return deadLetters
+}
+
+func (q *WebhookQueueImpl) worker() {
+	defer q.wg.Done()
+	for {
+		obj, shutdown := q.queue.Get()
+		if shutdown {
+			return
+		}
+		q.handle(obj.(*Item))
+		q.queue.Done(obj)
+	}
+}
+
+// handle processes a single attempt of the item, and schedules its next attempt when it fails with a transient error.
+func (q *WebhookQueueImpl) handle(item *Item) {
+	q.mu.Lock()
+	if item.Attempts == 0 {
+		q.queued--
+	} else {
+		q.retrying--
+	}
+	q.inFlight++
+	q.mu.Unlock()
+
+	item.Attempts++
+	err := q.process(q.ctx, item)
+	if err == nil {
+		q.complete(item, nil)
+		return
+	}
+
+	q.logger.WarnContext(q.ctx, "failed to process webhook", logging.EventIDKey, item.EventID, logging.RepoKey, item.Payload.Repo,
+		logging.CommitKey, item.Payload.Commit, "attempt", item.Attempts, "error", err)
+	if IsPermanent(err) || item.Attempts > q.cfg.WebhookConfig.MaxRetries || q.ctx.Err() != nil {
+		q.complete(item, err)
+		return
+	}
+
+	q.mu.Lock()
+	q.inFlight--
+	q.retrying++
+	q.retried++
+	q.mu.Unlock()
+
+	backoff := q.cfg.WebhookConfig.RetryBackoff * time.Duration(1<<(item.Attempts-1))
+	q.queue.AddAfter(item, backoff)
+}
+
+func (q *WebhookQueueImpl) complete(item *Item, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.idle.Broadcast()
+
+	latency := time.Since(item.EnqueuedAt)
+	q.inFlight--
+	q.lastLatency = latency
+	q.totalLatency += latency
+
+	if err == nil {
+		q.processed++
+		return
+	}
+
+	q.failed++
+	q.logger.ErrorContext(q.ctx, "moving webhook to the dead-letter list", logging.EventIDKey, item.EventID, logging.RepoKey, item.Payload.Repo,
+		logging.CommitKey, item.Payload.Commit, "attempts", item.Attempts)
+	q.deadLetters = append(q.deadLetters, DeadLetter{
+		EventID:  item.EventID,
+		Payload:  item.Payload,
+		Attempts: item.Attempts,
+		Error:    err.Error(),
+		FailedAt: time.Now(),
+	})
+	if size := q.cfg.WebhookConfig.DeadLetterSize; size > 0 && len(q.deadLetters) > size {
+		q.deadLetters = q.deadLetters[len(q.deadLetters)-size:]
+	}
+}
@@ -0,0 +1,143 @@
+package webhook_queue
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func newTestConfig(queueSize int, workers int, maxRetries int) *conf.GlobalConfig {
+	return &conf.GlobalConfig{
+		WebhookConfig: conf.WebhookConfig{
+			QueueSize:      queueSize,
+			Workers:        workers,
+			MaxRetries:     maxRetries,
+			RetryBackoff:   time.Millisecond,
+			DeadLetterSize: 10,
+		},
+	}
+}
+
+func TestWebhookQueue_Process(t *testing.T) {
+	assert := assertion.New(t)
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	queue := NewWebhookQueue(newTestConfig(10, 2, 2), func(ctx context.Context, item *Item) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[item.Payload.Commit]++
+		switch {
+		case item.Payload.Commit == "flaky" && attempts[item.Payload.Commit] < 2:
+			return fmt.Errorf("git provider timeout")
+		case item.Payload.Commit == "broken":
+			return fmt.Errorf("git provider unavailable")
+		}
+		return nil
+	}, nil)
+	queue.Start()
+
+	for _, commit := range []string{"ok", "flaky", "broken"} {
+		assert.Nil(queue.Enqueue(context.Background(), commit, &git_provider.WebhookPayload{Repo: "my-repo", Commit: commit}))
+	}
+	assert.Nil(queue.Stop(context.Background()))
+
+	assert.Equal(map[string]int{"ok": 1, "flaky": 2, "broken": 3}, attempts)
+	stats := queue.Stats()
+	assert.Equal(int64(2), stats.Processed)
+	assert.Equal(int64(1), stats.Failed)
+	assert.Equal(int64(3), stats.Retried)
+	assert.Equal(0, stats.Depth)
+	assert.Equal(0, stats.InFlight)
+
+	deadLetters := queue.DeadLetters()
+	assert.Len(deadLetters, 1)
+	assert.Equal("broken", deadLetters[0].EventID)
+	assert.Equal("broken", deadLetters[0].Payload.Commit)
+	assert.Equal(3, deadLetters[0].Attempts)
+	assert.Equal("git provider unavailable", deadLetters[0].Error)
+
+	assert.ErrorIs(queue.Enqueue(context.Background(), "closed", &git_provider.WebhookPayload{}), ErrQueueClosed)
+}
+
+func TestWebhookQueue_Full(t *testing.T) {
+	assert := assertion.New(t)
+
+	release := make(chan struct{})
+	queue := NewWebhookQueue(newTestConfig(1, 1, 0), func(ctx context.Context, item *Item) error {
+		<-release
+		return nil
+	}, nil)
+
+	// Workers aren't started, so the queue holds a single payload
+	assert.Nil(queue.Enqueue(context.Background(), "a", &git_provider.WebhookPayload{Commit: "a"}))
+	assert.ErrorIs(queue.Enqueue(context.Background(), "b", &git_provider.WebhookPayload{Commit: "b"}), ErrQueueFull)
+	assert.Equal(1, queue.Stats().Depth)
+
+	queue.Start()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.NotNil(queue.Stop(ctx))
+
+	close(release)
+}
+
+func TestWebhookQueue_Permanent(t *testing.T) {
+	assert := assertion.New(t)
+
+	attempts := 0
+	queue := NewWebhookQueue(newTestConfig(10, 1, 3), func(ctx context.Context, item *Item) error {
+		attempts++
+		return Permanent(fmt.Errorf("invalid triggers"))
+	}, nil)
+	queue.Start()
+
+	assert.Nil(queue.Enqueue(context.Background(), "invalid", &git_provider.WebhookPayload{Repo: "my-repo", Commit: "invalid"}))
+	assert.Nil(queue.Stop(context.Background()))
+
+	assert.Equal(1, attempts)
+	stats := queue.Stats()
+	assert.Equal(int64(1), stats.Failed)
+	assert.Equal(int64(0), stats.Retried)
+	deadLetters := queue.DeadLetters()
+	assert.Len(deadLetters, 1)
+	assert.Equal(1, deadLetters[0].Attempts)
+	assert.Equal("invalid triggers", deadLetters[0].Error)
+}
+
+func TestWebhookQueue_RetryReleasesWorker(t *testing.T) {
+	assert := assertion.New(t)
+
+	cfg := newTestConfig(10, 1, 1)
+	cfg.WebhookConfig.RetryBackoff = 50 * time.Millisecond
+	var mu sync.Mutex
+	processed := make([]string, 0)
+	queue := NewWebhookQueue(cfg, func(ctx context.Context, item *Item) error {
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, item.EventID)
+		if item.EventID == "flaky" && item.Attempts == 1 {
+			return fmt.Errorf("git provider timeout")
+		}
+		return nil
+	}, nil)
+	queue.Start()
+
+	assert.Nil(queue.Enqueue(context.Background(), "flaky", &git_provider.WebhookPayload{Commit: "flaky"}))
+	assert.Eventually(func() bool { return queue.Stats().Retrying == 1 }, time.Second, time.Millisecond)
+	assert.Nil(queue.Enqueue(context.Background(), "ok", &git_provider.WebhookPayload{Commit: "ok"}))
+	assert.Nil(queue.Stop(context.Background()))
+
+	// The single worker processed the other webhook while the failed one waited for its retry
+	assert.Equal([]string{"flaky", "ok", "flaky"}, processed)
+	stats := queue.Stats()
+	assert.Equal(int64(2), stats.Processed)
+	assert.Equal(int64(1), stats.Retried)
+	assert.Equal(0, stats.Retrying)
+}
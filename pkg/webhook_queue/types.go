@@ -0,0 +1,80 @@
+package webhook_queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/quickube/piper/pkg/git_provider"
+)
+
+var (
+	ErrQueueFull   = errors.New("webhook queue is full")
+	ErrQueueClosed = errors.New("webhook queue is closed")
+)
+
+// ProcessFunc handles a single webhook taken from the queue. Errors wrapped with Permanent are not retried.
+type ProcessFunc func(ctx context.Context, item *Item) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err as an error that retrying doesn't fix, so the webhook fails without being retried.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanentErr *permanentError
+	return errors.As(err, &permanentErr)
+}
+
+type Item struct {
+	EventID    string
+	Payload    *git_provider.WebhookPayload
+	EnqueuedAt time.Time
+	Attempts   int
+	// SpanContext is the span that enqueued the webhook, the parent of the spans of its processing.
+	SpanContext trace.SpanContext
+}
+
+type DeadLetter struct {
+	EventID  string                       `json:"eventID"`
+	Payload  *git_provider.WebhookPayload `json:"payload"`
+	Attempts int                          `json:"attempts"`
+	Error    string                       `json:"error"`
+	FailedAt time.Time                    `json:"failedAt"`
+}
+
+type Stats struct {
+	Depth                 int     `json:"depth"`
+	Capacity              int     `json:"capacity"`
+	Workers               int     `json:"workers"`
+	InFlight              int     `json:"inFlight"`
+	Retrying              int     `json:"retrying"`
+	Processed             int64   `json:"processed"`
+	Retried               int64   `json:"retried"`
+	Failed                int64   `json:"failed"`
+	LastLatencySeconds    float64 `json:"lastLatencySeconds"`
+	AverageLatencySeconds float64 `json:"averageLatencySeconds"`
+}
+
+type WebhookQueue interface {
+	Start()
+	Enqueue(ctx context.Context, eventID string, payload *git_provider.WebhookPayload) error
+	Stop(ctx context.Context) error
+	Stats() Stats
+	DeadLetters() []DeadLetter
+}
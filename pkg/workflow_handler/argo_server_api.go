@@ -0,0 +1,395 @@
+package workflow_handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wfutil "github.com/argoproj/argo-workflows/v3/workflow/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+// ArgoServerWorkflowsAPI reaches Argo Workflows through the Argo Server REST API with bearer token authentication.
+// The Argo Server API has no way to patch workflow labels, so labels set by Piper are kept in memory
+// and applied to the listed and watched workflows. They are lost on restart, so the notified and stuck labels are
+// reset and the notifications of the existing workflows are sent again. The label selectors sent to the Argo Server
+// only match the labels set at creation.
+type ArgoServerWorkflowsAPI struct {
+	address      string
+	token        string
+	client       *http.Client
+	streamClient *http.Client
+	labels       map[string]map[string]string
+	pruned       map[string]time.Time
+	mu           sync.Mutex
+}
+
+// argoServerPruneInterval is how often the labels of the workflows deleted while the watch was down are forgotten.
+const argoServerPruneInterval = 10 * time.Minute
+
+type argoServerCreateRequest struct {
+	Namespace string             `json:"namespace"`
+	Workflow  *v1alpha1.Workflow `json:"workflow"`
+}
+
+type argoServerWorkflowRequest struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type argoServerStreamMessage struct {
+	Result *struct {
+		Type   watch.EventType    `json:"type"`
+		Object *v1alpha1.Workflow `json:"object"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewArgoServerWorkflowsAPI(cfg *conf.GlobalConfig) (WorkflowsAPI, error) {
+	if cfg.WorkflowServerConfig.ArgoAddress == "" {
+		return nil, fmt.Errorf("ARGO_WORKFLOWS_ADDRESS is required to submit workflows through the Argo Server API")
+	}
+	err := validateInMemoryLabels(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	token := cfg.WorkflowServerConfig.ArgoToken
+	if token != "" && !strings.HasPrefix(token, "Bearer ") {
+		token = "Bearer " + token
+	}
+
+	return &ArgoServerWorkflowsAPI{
+		address:      strings.TrimSuffix(cfg.WorkflowServerConfig.ArgoAddress, "/"),
+		token:        token,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		streamClient: &http.Client{},
+		labels:       make(map[string]map[string]string),
+		pruned:       make(map[string]time.Time),
+	}, nil
+}
+
+func (a *ArgoServerWorkflowsAPI) Create(ctx context.Context, namespace string, wf *v1alpha1.Workflow) (*v1alpha1.Workflow, error) {
+	created := &v1alpha1.Workflow{}
+	body := &argoServerCreateRequest{Namespace: namespace, Workflow: wf}
+	err := a.do(ctx, http.MethodPost, fmt.Sprintf("/api/v1/workflows/%s", namespace), nil, body, created)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (a *ArgoServerWorkflowsAPI) Watch(ctx context.Context, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	query := listOptionsQuery(opts)
+	request, err := a.newRequest(ctx, http.MethodGet, fmt.Sprintf("/api/v1/workflow-events/%s", namespace), query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.streamClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		return nil, a.responseError(response, "workflows")
+	}
+
+	decoder := &argoServerEventDecoder{
+		body:    response.Body,
+		decoder: json.NewDecoder(response.Body),
+		api:     a,
+	}
+	return watch.NewStreamWatcher(decoder, &argoServerErrorReporter{}), nil
+}
+
+func (a *ArgoServerWorkflowsAPI) List(ctx context.Context, namespace string, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error) {
+	query := listOptionsQuery(opts)
+	workflowList := &v1alpha1.WorkflowList{}
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/workflows/%s", namespace), query, nil, workflowList)
+	if err != nil {
+		return nil, err
+	}
+	for i := range workflowList.Items {
+		a.applyLabels(&workflowList.Items[i])
+	}
+	// Lists follow missed watch events, so deleted workflows may not have been forgotten. Pruning lists the whole
+	// namespace, so it runs once per interval rather than on every list.
+	err = a.prune(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return workflowList, nil
+}
+
+func (a *ArgoServerWorkflowsAPI) SetLabels(ctx context.Context, namespace string, name string, labels map[string]string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := namespace + "/" + name
+	if _, ok := a.labels[key]; !ok {
+		a.labels[key] = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		a.labels[key][k] = v
+	}
+	return nil
+}
+
+func (a *ArgoServerWorkflowsAPI) Shutdown(ctx context.Context, namespace string, name string, strategy v1alpha1.ShutdownStrategy) error {
+	action := "stop"
+	if strategy == v1alpha1.ShutdownStrategyTerminate {
+		action = "terminate"
+	}
+	body := &argoServerWorkflowRequest{Namespace: namespace, Name: name}
+	return a.do(ctx, http.MethodPut, fmt.Sprintf("/api/v1/workflows/%s/%s/%s", namespace, name, action), nil, body, nil)
+}
+
+// Retry retries the workflow on the Argo Server, and forgets the labels Piper set on its previous run.
+func (a *ArgoServerWorkflowsAPI) Retry(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	retried := &v1alpha1.Workflow{}
+	body := &argoServerWorkflowRequest{Namespace: namespace, Name: name}
+	err := a.do(ctx, http.MethodPut, fmt.Sprintf("/api/v1/workflows/%s/%s/retry", namespace, name), nil, body, retried)
+	if err != nil {
+		return nil, err
+	}
+	a.forget(retried)
+	return retried, nil
+}
+
+// Resubmit formulates the resubmitted workflow like the Argo Server would, so the labels of the webhook that submitted
+// the original workflow are dropped before it is created.
+func (a *ArgoServerWorkflowsAPI) Resubmit(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	wf := &v1alpha1.Workflow{}
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/workflows/%s/%s", namespace, name), nil, nil, wf)
+	if err != nil {
+		return nil, err
+	}
+	resubmitted, err := wfutil.FormulateResubmitWorkflow(wf, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	resetPiperLabels(resubmitted, resubmitResetLabels)
+	return a.Create(ctx, namespace, resubmitted)
+}
+
+func (a *ArgoServerWorkflowsAPI) GetWorkflowTemplate(ctx context.Context, namespace string, name string) (*v1alpha1.WorkflowTemplate, error) {
+	workflowTemplate := &v1alpha1.WorkflowTemplate{}
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/workflow-templates/%s/%s", namespace, name), nil, nil, workflowTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return workflowTemplate, nil
+}
+
+func (a *ArgoServerWorkflowsAPI) GetClusterWorkflowTemplate(ctx context.Context, name string) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	clusterWorkflowTemplate := &v1alpha1.ClusterWorkflowTemplate{}
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/cluster-workflow-templates/%s", name), nil, nil, clusterWorkflowTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return clusterWorkflowTemplate, nil
+}
+
+// validateInMemoryLabels refuses the features that rely on labels set after creation surviving a restart or a leader
+// change. Without them, every workflow would be notified again to the sinks, and pull request comments would be
+// posted again.
+func validateInMemoryLabels(cfg *conf.GlobalConfig) error {
+	unsupported := make([]string, 0)
+	if cfg.LeaderElectionConfig.Enabled {
+		unsupported = append(unsupported, "leader election")
+	}
+	if len(cfg.NotificationsConfig.Notifications.Sinks) != 0 {
+		unsupported = append(unsupported, "notification sinks")
+	}
+	if cfg.NotificationsConfig.Notifications.PullRequestComment.Enabled {
+		unsupported = append(unsupported, "pull request comments")
+	}
+	if len(unsupported) != 0 {
+		return fmt.Errorf("%s need the notification state persisted in the workflow labels, which the Argo Server API can't update, set ARGO_WORKFLOWS_CREATE_CRD to true", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// listOptionsQuery passes the label selector and the resource version to the list and watch requests.
+func listOptionsQuery(opts metav1.ListOptions) url.Values {
+	query := url.Values{}
+	query.Set("listOptions.labelSelector", opts.LabelSelector)
+	if opts.ResourceVersion != "" {
+		query.Set("listOptions.resourceVersion", opts.ResourceVersion)
+	}
+	return query
+}
+
+// applyLabels overlays the labels Piper set in memory on a workflow received from the Argo Server.
+func (a *ArgoServerWorkflowsAPI) applyLabels(wf *v1alpha1.Workflow) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	labels, ok := a.labels[wf.GetNamespace()+"/"+wf.GetName()]
+	if !ok {
+		return
+	}
+	if wf.Labels == nil {
+		wf.Labels = make(map[string]string, len(labels))
+	}
+	for k, v := range labels {
+		wf.Labels[k] = v
+	}
+}
+
+// prune forgets the labels of the workflows of the namespace that no longer exist, at most once per
+// argoServerPruneInterval.
+func (a *ArgoServerWorkflowsAPI) prune(ctx context.Context, namespace string) error {
+	a.mu.Lock()
+	if len(a.labels) == 0 || time.Since(a.pruned[namespace]) < argoServerPruneInterval {
+		a.mu.Unlock()
+		return nil
+	}
+	a.pruned[namespace] = time.Now()
+	a.mu.Unlock()
+
+	query := url.Values{}
+	query.Set("fields", "items.metadata.name")
+	workflowList := &v1alpha1.WorkflowList{}
+	err := a.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/workflows/%s", namespace), query, nil, workflowList)
+	if err != nil {
+		a.mu.Lock()
+		delete(a.pruned, namespace)
+		a.mu.Unlock()
+		return err
+	}
+
+	existing := make(map[string]bool, len(workflowList.Items))
+	for _, workflow := range workflowList.Items {
+		existing[namespace+"/"+workflow.GetName()] = true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for key := range a.labels {
+		if strings.HasPrefix(key, namespace+"/") && !existing[key] {
+			delete(a.labels, key)
+		}
+	}
+	return nil
+}
+
+func (a *ArgoServerWorkflowsAPI) forget(wf *v1alpha1.Workflow) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.labels, wf.GetNamespace()+"/"+wf.GetName())
+}
+
+func (a *ArgoServerWorkflowsAPI) newRequest(ctx context.Context, method string, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(jsonBytes)
+	}
+
+	requestURL := a.address + path
+	if len(query) != 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if a.token != "" {
+		request.Header.Set("Authorization", a.token)
+	}
+	return request, nil
+}
+
+func (a *ArgoServerWorkflowsAPI) do(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	request, err := a.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	response, err := a.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return a.responseError(response, path)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+func (a *ArgoServerWorkflowsAPI) responseError(response *http.Response, resource string) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	if response.StatusCode == http.StatusNotFound {
+		return k8serrors.NewNotFound(schema.GroupResource{Group: "argoproj.io", Resource: resource}, strings.TrimSpace(string(message)))
+	}
+	return fmt.Errorf("argo server API %s %s returned %d: %s", response.Request.Method, response.Request.URL.Path, response.StatusCode, strings.TrimSpace(string(message)))
+}
+
+type argoServerEventDecoder struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	api     *ArgoServerWorkflowsAPI
+}
+
+func (d *argoServerEventDecoder) Decode() (watch.EventType, runtime.Object, error) {
+	message := &argoServerStreamMessage{}
+	if err := d.decoder.Decode(message); err != nil {
+		return "", nil, err
+	}
+	if message.Error != nil {
+		return "", nil, fmt.Errorf("argo server watch error %d: %s", message.Error.Code, message.Error.Message)
+	}
+	if message.Result == nil || message.Result.Object == nil {
+		return "", nil, fmt.Errorf("argo server watch returned an empty event")
+	}
+
+	if message.Result.Type == watch.Deleted {
+		d.api.forget(message.Result.Object)
+	} else {
+		d.api.applyLabels(message.Result.Object)
+	}
+	return message.Result.Type, message.Result.Object, nil
+}
+
+func (d *argoServerEventDecoder) Close() {
+	d.body.Close()
+}
+
+type argoServerErrorReporter struct{}
+
+func (r *argoServerErrorReporter) AsObject(err error) runtime.Object {
+	return &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: err.Error(),
+	}
+}
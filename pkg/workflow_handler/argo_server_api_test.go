@@ -0,0 +1,220 @@
+package workflow_handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/workflow/common"
+	"github.com/quickube/piper/pkg/conf"
+	assertion "github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newArgoServerTestAPI(t *testing.T, handler http.HandlerFunc) *ArgoServerWorkflowsAPI {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	api, err := NewArgoServerWorkflowsAPI(&conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{
+			ArgoAddress: server.URL,
+			ArgoToken:   "my-token",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*ArgoServerWorkflowsAPI)
+}
+
+func TestArgoServerWorkflowsAPI_Create(t *testing.T) {
+	assert := assertion.New(t)
+
+	api := newArgoServerTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("/api/v1/workflows/workflows", r.URL.Path)
+		assert.Equal("Bearer my-token", r.Header.Get("Authorization"))
+
+		request := &argoServerCreateRequest{}
+		assert.Nil(json.NewDecoder(r.Body).Decode(request))
+		request.Workflow.Name = request.Workflow.GenerateName + "abcde"
+		_ = json.NewEncoder(w).Encode(request.Workflow)
+	})
+
+	created, err := api.Create(context.Background(), "workflows", &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "my-repo-my-branch-"},
+	})
+	assert.Nil(err)
+	assert.Equal("my-repo-my-branch-abcde", created.Name)
+}
+
+func TestArgoServerWorkflowsAPI_Watch(t *testing.T) {
+	assert := assertion.New(t)
+
+	api := newArgoServerTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v1/workflow-events/workflows", r.URL.Path)
+		assert.Equal("piper.quickube.com/notified", r.URL.Query().Get("listOptions.labelSelector"))
+		assert.Equal("42", r.URL.Query().Get("listOptions.resourceVersion"))
+		for _, phase := range []string{"Running", "Succeeded"} {
+			fmt.Fprintf(w, `{"result":{"type":"MODIFIED","object":{"metadata":{"name":"wf","namespace":"workflows","labels":{"piper.quickube.com/notified":"false"}},"status":{"phase":"%s"}}}}`+"\n", phase)
+		}
+	})
+
+	err := api.SetLabels(context.Background(), "workflows", "wf", map[string]string{"piper.quickube.com/notified": "Running"})
+	assert.Nil(err)
+
+	watcher, err := api.Watch(context.Background(), "workflows", metav1.ListOptions{
+		LabelSelector:   "piper.quickube.com/notified",
+		ResourceVersion: "42",
+	})
+	assert.Nil(err)
+	defer watcher.Stop()
+
+	events := make([]watch.Event, 0)
+	for event := range watcher.ResultChan() {
+		events = append(events, event)
+	}
+
+	assert.Len(events, 2)
+	workflow := events[1].Object.(*v1alpha1.Workflow)
+	assert.Equal(watch.Modified, events[1].Type)
+	assert.Equal(v1alpha1.WorkflowSucceeded, workflow.Status.Phase)
+	assert.Equal("Running", workflow.Labels["piper.quickube.com/notified"])
+}
+
+func TestArgoServerWorkflowsAPI_NotFound(t *testing.T) {
+	assert := assertion.New(t)
+
+	api := newArgoServerTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v1/cluster-workflow-templates/common-toolkit", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	_, err := api.GetClusterWorkflowTemplate(context.Background(), "common-toolkit")
+	assert.True(k8serrors.IsNotFound(err))
+}
+
+func TestArgoServerWorkflowsAPI_InMemoryLabels(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		name    string
+		setup   func(cfg *conf.GlobalConfig)
+		wantErr bool
+	}{
+		{name: "Commit statuses only", setup: func(cfg *conf.GlobalConfig) {}},
+		{name: "Leader election", setup: func(cfg *conf.GlobalConfig) {
+			cfg.LeaderElectionConfig.Enabled = true
+		}, wantErr: true},
+		{name: "Notification sinks", setup: func(cfg *conf.GlobalConfig) {
+			cfg.NotificationsConfig.Notifications.Sinks = []conf.NotificationSink{{Name: "slack", Type: "slack"}}
+		}, wantErr: true},
+		{name: "Pull request comments", setup: func(cfg *conf.GlobalConfig) {
+			cfg.NotificationsConfig.Notifications.PullRequestComment.Enabled = true
+		}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := &conf.GlobalConfig{WorkflowServerConfig: conf.WorkflowServerConfig{ArgoAddress: "http://argo-server:2746"}}
+			test.setup(cfg)
+			_, err := NewArgoServerWorkflowsAPI(cfg)
+			if test.wantErr {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+		})
+	}
+}
+
+func TestArgoServerWorkflowsAPI_List(t *testing.T) {
+	assert := assertion.New(t)
+
+	prunes := 0
+	api := newArgoServerTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal("/api/v1/workflows/workflows", r.URL.Path)
+		if r.URL.Query().Get("fields") == "items.metadata.name" {
+			prunes++
+			fmt.Fprint(w, `{"items":[{"metadata":{"name":"wf"}}]}`)
+			return
+		}
+		assert.Equal("piper.quickube.com/notified", r.URL.Query().Get("listOptions.labelSelector"))
+		assert.Empty(r.URL.Query().Get("listOptions.resourceVersion"))
+		fmt.Fprint(w, `{"metadata":{"resourceVersion":"42"},"items":[{"metadata":{"name":"wf","namespace":"workflows","labels":{"piper.quickube.com/notified":"false"}},"status":{"phase":"Succeeded"}}]}`)
+	})
+
+	ctx := context.Background()
+	assert.Nil(api.SetLabels(ctx, "workflows", "wf", map[string]string{"piper.quickube.com/notified": "Succeeded"}))
+	// Deleted while the watch was down
+	assert.Nil(api.SetLabels(ctx, "workflows", "deleted", map[string]string{"piper.quickube.com/notified": "Running"}))
+	assert.Nil(api.SetLabels(ctx, "other", "wf", map[string]string{"piper.quickube.com/notified": "Running"}))
+
+	workflows, err := api.List(ctx, "workflows", metav1.ListOptions{LabelSelector: "piper.quickube.com/notified"})
+	assert.Nil(err)
+	assert.Equal("42", workflows.ResourceVersion)
+	assert.Len(workflows.Items, 1)
+	assert.Equal("Succeeded", workflows.Items[0].Labels["piper.quickube.com/notified"])
+
+	assert.Contains(api.labels, "workflows/wf")
+	assert.NotContains(api.labels, "workflows/deleted")
+	assert.Contains(api.labels, "other/wf")
+
+	// The namespace was pruned recently, so the next list skips it
+	assert.Nil(api.SetLabels(ctx, "workflows", "deleted", map[string]string{"piper.quickube.com/notified": "Running"}))
+	_, err = api.List(ctx, "workflows", metav1.ListOptions{LabelSelector: "piper.quickube.com/notified"})
+	assert.Nil(err)
+	assert.Equal(1, prunes)
+	assert.Contains(api.labels, "workflows/deleted")
+
+	api.pruned["workflows"] = time.Now().Add(-argoServerPruneInterval)
+	_, err = api.List(ctx, "workflows", metav1.ListOptions{LabelSelector: "piper.quickube.com/notified"})
+	assert.Nil(err)
+	assert.Equal(2, prunes)
+	assert.NotContains(api.labels, "workflows/deleted")
+}
+
+func TestArgoServerWorkflowsAPI_RetryAndResubmit(t *testing.T) {
+	assert := assertion.New(t)
+
+	api := newArgoServerTestAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/api/v1/workflows/workflows/my-repo-main-abcde/retry":
+			retried := newFailedWorkflow()
+			retried.Status.Phase = v1alpha1.WorkflowRunning
+			_ = json.NewEncoder(w).Encode(retried)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/workflows/workflows/my-repo-main-abcde":
+			_ = json.NewEncoder(w).Encode(newFailedWorkflow())
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/workflows/workflows":
+			request := &argoServerCreateRequest{}
+			assert.Nil(json.NewDecoder(r.Body).Decode(request))
+			request.Workflow.Name = request.Workflow.GenerateName + "fghij"
+			_ = json.NewEncoder(w).Encode(request.Workflow)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	ctx := context.Background()
+	assert.Nil(api.SetLabels(ctx, "workflows", "my-repo-main-abcde", map[string]string{SUPERSEDED_BY_LABEL: "my-repo-main-klmno"}))
+
+	_, err := api.Retry(ctx, "workflows", "my-repo-main-abcde")
+	assert.Nil(err)
+	assert.NotContains(api.labels, "workflows/my-repo-main-abcde")
+
+	resubmitted, err := api.Resubmit(ctx, "workflows", "my-repo-main-abcde")
+	assert.Nil(err)
+	assert.Equal("my-repo-main-fghij", resubmitted.Name)
+	assert.Equal("false", resubmitted.Labels["piper.quickube.com/notified"])
+	assert.Equal("my-repo-main-abcde", resubmitted.Labels[common.LabelKeyPreviousWorkflowName])
+	for _, label := range resubmitResetLabels {
+		assert.NotContains(resubmitted.Labels, label)
+	}
+}
@@ -0,0 +1,44 @@
+package workflow_handler
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/logging"
+)
+
+// CancelInProgress shuts down the running workflows of the concurrency group and marks them as superseded by the commit.
+// The workflows of the commit itself, submitted by other triggers of the group, are kept.
+func (wfc *WorkflowsClientImpl) CancelInProgress(ctx context.Context, namespace string, concurrency *common.Concurrency, commit string) error {
+	labelSelector := fmt.Sprintf("%s=%s,workflows.argoproj.io/completed!=true", CONCURRENCY_GROUP_LABEL, ConvertToValidLabelValue(concurrency.Group))
+	workflows, err := wfc.api.List(ctx, namespace, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return err
+	}
+
+	for _, workflow := range workflows.Items {
+		if workflow.Status.Fulfilled() || workflow.Spec.Shutdown.Enabled() ||
+			workflow.GetLabels()["commit"] == ConvertToValidString(commit) {
+			continue
+		}
+
+		// Labeling first so the notifier reports the superseding commit once the workflow stops
+		err = wfc.api.SetLabels(ctx, workflow.GetNamespace(), workflow.GetName(), map[string]string{
+			SUPERSEDED_BY_LABEL: ConvertToValidLabelValue(commit),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to label superseded workflow %s, error: %v", workflow.GetName(), err)
+		}
+
+		err = wfc.api.Shutdown(ctx, workflow.GetNamespace(), workflow.GetName(), concurrency.CancelStrategy)
+		if err != nil {
+			return fmt.Errorf("failed to %s superseded workflow %s, error: %v", concurrency.CancelStrategy, workflow.GetName(), err)
+		}
+		logging.OrDefault(wfc.logger).InfoContext(ctx, "workflow superseded", "workflow", workflow.GetName(), "group", concurrency.Group, "superseded_by", commit)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package workflow_handler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/utils"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCancelInProgress(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	newWorkflow := func(name string, group string, phase v1alpha1.WorkflowPhase) *v1alpha1.Workflow {
+		labels := map[string]string{CONCURRENCY_GROUP_LABEL: group, "commit": name}
+		if phase == v1alpha1.WorkflowSucceeded {
+			labels["workflows.argoproj.io/completed"] = "true"
+		}
+		return &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "workflows", Labels: labels},
+			Status:     v1alpha1.WorkflowStatus{Phase: phase},
+		}
+	}
+	clientSet := fake.NewSimpleClientset(
+		newWorkflow("running", "my-repo-main", v1alpha1.WorkflowRunning),
+		newWorkflow("completed", "my-repo-main", v1alpha1.WorkflowSucceeded),
+		newWorkflow("other-group", "my-repo-dev", v1alpha1.WorkflowRunning),
+		newWorkflow("abc123", "my-repo-main", v1alpha1.WorkflowRunning),
+	)
+	wfcImpl := &WorkflowsClientImpl{
+		api: &KubernetesWorkflowsAPI{clientSet: clientSet},
+		cfg: &conf.GlobalConfig{
+			WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+		},
+	}
+
+	err := wfcImpl.CancelInProgress(ctx, "workflows", &common.Concurrency{
+		Group:            "my-repo-main",
+		CancelInProgress: true,
+		CancelStrategy:   v1alpha1.ShutdownStrategyStop,
+	}, "abc123")
+	assert.Nil(err)
+
+	workflows := clientSet.ArgoprojV1alpha1().Workflows("workflows")
+	running, _ := workflows.Get(ctx, "running", metav1.GetOptions{})
+	assert.Equal(v1alpha1.ShutdownStrategyStop, running.Spec.Shutdown)
+	assert.Equal("abc123", running.Labels[SUPERSEDED_BY_LABEL])
+
+	for _, name := range []string{"completed", "other-group", "abc123"} {
+		workflow, _ := workflows.Get(ctx, name, metav1.GetOptions{})
+		assert.Equal(v1alpha1.ShutdownStrategyNone, workflow.Spec.Shutdown)
+		assert.NotContains(workflow.Labels, SUPERSEDED_BY_LABEL)
+	}
+}
+
+func TestHandleWorkflowBatch_Concurrency(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	onStart := "- name: build\n  template: build\n"
+	templates := "- name: build\n  container:\n    image: alpine\n"
+	path := ".workflows/main.yaml"
+	config := ""
+	newBatch := func(commit string) *common.WorkflowsBatch {
+		return &common.WorkflowsBatch{
+			OnStart:   []*git_provider.CommitFile{{Path: &path, Content: &onStart}},
+			Templates: []*git_provider.CommitFile{{Path: &path, Content: &templates}},
+			Config:    &config,
+			Concurrency: &common.Concurrency{
+				Group:            "my-repo-main",
+				CancelInProgress: true,
+				CancelStrategy:   v1alpha1.ShutdownStrategyStop,
+			},
+			Payload: &git_provider.WebhookPayload{Repo: "my-repo", Branch: "main", Commit: commit},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		commit       string
+		submitErr    error
+		wantCanceled bool
+	}{
+		{name: "Failed submission keeps the group running", commit: "def456", submitErr: errors.New("argo unavailable"), wantCanceled: false},
+		{name: "Another trigger of the same commit", commit: "abc123", wantCanceled: false},
+		{name: "Superseding commit", commit: "def456", wantCanceled: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientSet := fake.NewSimpleClientset(&v1alpha1.Workflow{
+				ObjectMeta: metav1.ObjectMeta{Name: "running", Namespace: "workflows", Labels: map[string]string{
+					CONCURRENCY_GROUP_LABEL: "my-repo-main",
+					"commit":                "abc123",
+				}},
+				Status: v1alpha1.WorkflowStatus{Phase: v1alpha1.WorkflowRunning},
+			})
+			if test.submitErr != nil {
+				clientSet.PrependReactor("create", "workflows", func(action k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, test.submitErr
+				})
+			}
+			wfcImpl := &WorkflowsClientImpl{
+				api: &KubernetesWorkflowsAPI{clientSet: clientSet},
+				cfg: &conf.GlobalConfig{
+					WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+					WorkflowsConfig: conf.WorkflowsConfig{Configs: map[string]*conf.ConfigInstance{
+						"default": {Spec: v1alpha1.WorkflowSpec{}},
+					}},
+				},
+				submitted: utils.NewExpiringSet(time.Hour),
+			}
+
+			_, err := wfcImpl.HandleWorkflowBatch(ctx, newBatch(test.commit))
+			assert.Equal(test.submitErr != nil, err != nil)
+
+			running, _ := clientSet.ArgoprojV1alpha1().Workflows("workflows").Get(ctx, "running", metav1.GetOptions{})
+			assert.Equal(test.wantCanceled, running.Spec.Shutdown.Enabled())
+
+			if test.submitErr == nil {
+				workflows, _ := clientSet.ArgoprojV1alpha1().Workflows("workflows").List(ctx, metav1.ListOptions{LabelSelector: "commit=" + test.commit})
+				for _, workflow := range workflows.Items {
+					if workflow.Name == "running" {
+						continue
+					}
+					assert.Equal("my-repo", workflow.Annotations[REPO_ANNOTATION])
+					assert.Equal("main", workflow.Annotations[BRANCH_ANNOTATION])
+				}
+			}
+		})
+	}
+}
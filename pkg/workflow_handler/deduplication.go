@@ -0,0 +1,43 @@
+package workflow_handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IsDuplicate reports whether a workflow with the idempotency key was already submitted within the deduplication window.
+// The key is reserved in memory against concurrent deliveries, and looked up as a workflow label to survive restarts.
+func (wfc *WorkflowsClientImpl) IsDuplicate(ctx context.Context, namespace string, idempotencyKey string) (bool, error) {
+	window := wfc.cfg.WebhookConfig.DeduplicationWindow
+	if idempotencyKey == "" || window <= 0 {
+		return false, nil
+	}
+
+	if wfc.submitted != nil && !wfc.submitted.Add(idempotencyKey) {
+		return true, nil
+	}
+
+	workflows, err := wfc.api.List(ctx, namespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", IDEMPOTENCY_KEY_LABEL, idempotencyKey),
+	})
+	if err != nil {
+		wfc.releaseIdempotencyKey(idempotencyKey)
+		return false, fmt.Errorf("failed to list workflows with idempotency key %s, error: %v", idempotencyKey, err)
+	}
+	for _, workflow := range workflows.Items {
+		if time.Since(workflow.GetCreationTimestamp().Time) < window {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (wfc *WorkflowsClientImpl) releaseIdempotencyKey(idempotencyKey string) {
+	if wfc.submitted != nil && idempotencyKey != "" {
+		wfc.submitted.Remove(idempotencyKey)
+	}
+}
@@ -0,0 +1,69 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsDuplicate(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	newWorkflow := func(name string, key string, age time.Duration) *v1alpha1.Workflow {
+		return &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Namespace:         "workflows",
+				Labels:            map[string]string{IDEMPOTENCY_KEY_LABEL: key},
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+			},
+		}
+	}
+	clientSet := fake.NewSimpleClientset(
+		newWorkflow("recent", "recent-key", time.Minute),
+		newWorkflow("old", "old-key", 2*time.Hour),
+	)
+	cfg := &conf.GlobalConfig{
+		WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+		WebhookConfig:        conf.WebhookConfig{DeduplicationWindow: time.Hour},
+	}
+	wfcImpl := &WorkflowsClientImpl{
+		api:       &KubernetesWorkflowsAPI{clientSet: clientSet},
+		cfg:       cfg,
+		submitted: utils.NewExpiringSet(cfg.WebhookConfig.DeduplicationWindow),
+	}
+
+	var tests = []struct {
+		name     string
+		key      string
+		expected bool
+	}{
+		{name: "Submitted within the window", key: "recent-key", expected: true},
+		{name: "Submitted before the window", key: "old-key", expected: false},
+		{name: "New key", key: "new-key", expected: false},
+		{name: "Reserved key", key: "new-key", expected: true},
+		{name: "Empty key", key: "", expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			duplicate, err := wfcImpl.IsDuplicate(ctx, "workflows", test.key)
+			assert.Nil(err)
+			assert.Equal(test.expected, duplicate)
+		})
+	}
+
+	t.Run("Released key", func(t *testing.T) {
+		wfcImpl.releaseIdempotencyKey("new-key")
+		duplicate, err := wfcImpl.IsDuplicate(ctx, "workflows", "new-key")
+		assert.Nil(err)
+		assert.False(duplicate)
+	})
+}
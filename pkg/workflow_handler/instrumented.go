@@ -0,0 +1,39 @@
+package workflow_handler
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/metrics"
+)
+
+// instrumentedWorkflowsClient counts the workflows submitted and failed by repo and config.
+type instrumentedWorkflowsClient struct {
+	WorkflowsClient
+	recorder metrics.Recorder
+}
+
+func NewInstrumentedWorkflowsClient(client WorkflowsClient, recorder metrics.Recorder) WorkflowsClient {
+	return &instrumentedWorkflowsClient{WorkflowsClient: client, recorder: recorder}
+}
+
+// HandleWorkflowBatch counts the submitted workflows, with the config they were rendered with, and the failures.
+// Deduplicated batches are not counted.
+func (c *instrumentedWorkflowsClient) HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error) {
+	workflow, err := c.WorkflowsClient.HandleWorkflowBatch(ctx, workflowsBatch)
+	if err == nil && workflow == nil {
+		return nil, nil
+	}
+
+	config := "default"
+	if workflowsBatch.Config != nil && *workflowsBatch.Config != "" {
+		config = *workflowsBatch.Config
+	}
+	if workflow != nil && workflow.GetAnnotations()[CONFIG_ANNOTATION] != "" {
+		config = workflow.GetAnnotations()[CONFIG_ANNOTATION]
+	}
+	c.recorder.WorkflowSubmitted(workflowsBatch.Payload.Repo, config, err)
+	return workflow, err
+}
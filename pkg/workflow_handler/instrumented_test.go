@@ -0,0 +1,60 @@
+package workflow_handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/metrics"
+)
+
+type stubWorkflowsClient struct {
+	WorkflowsClient
+	results map[string]*v1alpha1.Workflow
+}
+
+func (s *stubWorkflowsClient) HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error) {
+	workflow, ok := s.results[workflowsBatch.Payload.Commit]
+	if !ok {
+		return nil, errors.New("lint failed")
+	}
+	return workflow, nil
+}
+
+func TestInstrumentedWorkflowsClient(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+	registry := prometheus.NewRegistry()
+	stub := &stubWorkflowsClient{results: map[string]*v1alpha1.Workflow{
+		"submitted": {ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{CONFIG_ANNOTATION: "release"}}},
+		"duplicate": nil,
+	}}
+	client := NewInstrumentedWorkflowsClient(stub, metrics.NewRecorder(registry))
+
+	config := ""
+	for _, commit := range []string{"submitted", "duplicate", "invalid"} {
+		_, _ = client.HandleWorkflowBatch(ctx, &common.WorkflowsBatch{
+			Config:  &config,
+			Payload: &git_provider.WebhookPayload{Repo: "my-repo", Commit: commit},
+		})
+	}
+
+	expected := `
+# HELP piper_workflows_failed_total Workflows that failed to be rendered or submitted, by repo and config.
+# TYPE piper_workflows_failed_total counter
+piper_workflows_failed_total{config="default",repo="my-repo"} 1
+# HELP piper_workflows_submitted_total Workflows submitted, by repo and config.
+# TYPE piper_workflows_submitted_total counter
+piper_workflows_submitted_total{config="release",repo="my-repo"} 1
+`
+	assert.Nil(testutil.GatherAndCompare(registry, strings.NewReader(expected), "piper_workflows_submitted_total", "piper_workflows_failed_total"))
+}
@@ -0,0 +1,136 @@
+package workflow_handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	wfClientSet "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
+	wfutil "github.com/argoproj/argo-workflows/v3/workflow/util"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+// KubernetesWorkflowsAPI reaches Argo Workflows by managing the Workflow resources through the Kubernetes API.
+type KubernetesWorkflowsAPI struct {
+	clientSet  wfClientSet.Interface
+	kubeClient kubernetes.Interface
+}
+
+func NewKubernetesWorkflowsAPI(cfg *conf.GlobalConfig) (WorkflowsAPI, error) {
+	restClientConfig, err := utils.GetClientConfig(cfg.WorkflowServerConfig.KubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clientSet, err := wfClientSet.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KubernetesWorkflowsAPI{
+		clientSet:  clientSet,
+		kubeClient: kubeClient,
+	}, nil
+}
+
+func (k *KubernetesWorkflowsAPI) Create(ctx context.Context, namespace string, wf *v1alpha1.Workflow) (*v1alpha1.Workflow, error) {
+	return k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Create(ctx, wf, metav1.CreateOptions{})
+}
+
+func (k *KubernetesWorkflowsAPI) Watch(ctx context.Context, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	opts.Watch = true
+	return k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Watch(ctx, opts)
+}
+
+func (k *KubernetesWorkflowsAPI) List(ctx context.Context, namespace string, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error) {
+	return k.clientSet.ArgoprojV1alpha1().Workflows(namespace).List(ctx, opts)
+}
+
+func (k *KubernetesWorkflowsAPI) SetLabels(ctx context.Context, namespace string, name string, labels map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{"metadata": metav1.ObjectMeta{
+		Labels: labels,
+	}})
+	if err != nil {
+		return err
+	}
+
+	_, err = k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (k *KubernetesWorkflowsAPI) Shutdown(ctx context.Context, namespace string, name string, strategy v1alpha1.ShutdownStrategy) error {
+	patch, err := json.Marshal(map[string]interface{}{"spec": map[string]interface{}{
+		"shutdown": strategy,
+	}})
+	if err != nil {
+		return err
+	}
+
+	_, err = k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// Retry resumes a failed workflow from its failed nodes, deleting the pods of the nodes that run again.
+func (k *KubernetesWorkflowsAPI) Retry(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	wf, err := k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	err = checkRetryable(wf)
+	if err != nil {
+		return nil, err
+	}
+	if wf.Status.IsOffloadNodeStatus() {
+		return nil, fmt.Errorf("workflow %s has an offloaded node status, retry it through the Argo Server", name)
+	}
+
+	retried, podsToDelete, err := wfutil.FormulateRetryWorkflow(ctx, wf, false, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, podName := range podsToDelete {
+		err = k.kubeClient.CoreV1().Pods(namespace).Delete(ctx, podName, metav1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to delete pod %s, error: %v", podName, err)
+		}
+	}
+	resetPiperLabels(retried, retryResetLabels)
+
+	return k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Update(ctx, retried, metav1.UpdateOptions{})
+}
+
+// Resubmit creates a new workflow with the spec, labels and annotations of the given one, that runs all of its steps again.
+func (k *KubernetesWorkflowsAPI) Resubmit(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	wf, err := k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	resubmitted, err := wfutil.FormulateResubmitWorkflow(wf, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	resetPiperLabels(resubmitted, resubmitResetLabels)
+
+	return k.clientSet.ArgoprojV1alpha1().Workflows(namespace).Create(ctx, resubmitted, metav1.CreateOptions{})
+}
+
+func (k *KubernetesWorkflowsAPI) GetWorkflowTemplate(ctx context.Context, namespace string, name string) (*v1alpha1.WorkflowTemplate, error) {
+	return k.clientSet.ArgoprojV1alpha1().WorkflowTemplates(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+func (k *KubernetesWorkflowsAPI) GetClusterWorkflowTemplate(ctx context.Context, name string) (*v1alpha1.ClusterWorkflowTemplate, error) {
+	return k.clientSet.ArgoprojV1alpha1().ClusterWorkflowTemplates().Get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,217 @@
+package workflow_handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+var (
+	inputParameterPattern    = regexp.MustCompile(`{{\s*inputs\.parameters\.([A-Za-z0-9_-]+)\s*}}`)
+	workflowParameterPattern = regexp.MustCompile(`{{\s*workflow\.parameters\.([A-Za-z0-9_-]+)\s*}}`)
+	dependsTaskPattern       = regexp.MustCompile(`([A-Za-z0-9][A-Za-z0-9_-]*)(\.[A-Za-z]+)?`)
+)
+
+// LintError holds every problem found while linting a workflow before submission.
+type LintError struct {
+	Workflow string
+	Errors   []string
+}
+
+func (e *LintError) Error() string {
+	return fmt.Sprintf("workflow %s failed lint: %s", e.Workflow, strings.Join(e.Errors, "; "))
+}
+
+// LintWorkflowSpec validates a workflow spec offline and returns the list of lint errors found.
+func LintWorkflowSpec(spec *v1alpha1.WorkflowSpec) []string {
+	lintErrors := make([]string, 0)
+
+	templates := make(map[string]*v1alpha1.Template, len(spec.Templates))
+	for i := range spec.Templates {
+		template := &spec.Templates[i]
+		if _, ok := templates[template.Name]; ok {
+			lintErrors = append(lintErrors, fmt.Sprintf("duplicate template name %s", template.Name))
+			continue
+		}
+		templates[template.Name] = template
+	}
+
+	// Without an entrypoint, a workflow with workflowTemplateRef uses the entrypoint of the referenced template.
+	if spec.Entrypoint != "" || spec.WorkflowTemplateRef == nil {
+		if _, ok := templates[spec.Entrypoint]; !ok {
+			lintErrors = append(lintErrors, fmt.Sprintf("entrypoint template %s is not defined", spec.Entrypoint))
+		}
+	}
+	if spec.OnExit != "" {
+		if _, ok := templates[spec.OnExit]; !ok {
+			lintErrors = append(lintErrors, fmt.Sprintf("onExit template %s is not defined", spec.OnExit))
+		}
+	}
+
+	for i := range spec.Templates {
+		template := &spec.Templates[i]
+		if template.DAG != nil {
+			lintErrors = append(lintErrors, LintDAGTemplate(template, templates)...)
+		}
+		for _, parallelSteps := range template.Steps {
+			for _, step := range parallelSteps.Steps {
+				if step.TemplateRef == nil && step.Inline == nil {
+					if _, ok := templates[step.Template]; !ok {
+						lintErrors = append(lintErrors, fmt.Sprintf("template %s step %s references undefined template %s", template.Name, step.Name, step.Template))
+					}
+				}
+			}
+		}
+		lintErrors = append(lintErrors, LintInputParameters(template)...)
+	}
+
+	lintErrors = append(lintErrors, LintWorkflowParameters(spec)...)
+
+	return lintErrors
+}
+
+// LintDAGTemplate checks the tasks of a DAG template for undefined templates, duplicate names,
+// unknown dependencies and dependency cycles.
+func LintDAGTemplate(template *v1alpha1.Template, templates map[string]*v1alpha1.Template) []string {
+	lintErrors := make([]string, 0)
+	dependencies := make(map[string][]string, len(template.DAG.Tasks))
+
+	for _, task := range template.DAG.Tasks {
+		if _, ok := dependencies[task.Name]; ok {
+			lintErrors = append(lintErrors, fmt.Sprintf("template %s has duplicate task name %s", template.Name, task.Name))
+			continue
+		}
+		if task.TemplateRef == nil && task.Inline == nil {
+			if _, ok := templates[task.Template]; !ok {
+				lintErrors = append(lintErrors, fmt.Sprintf("template %s task %s references undefined template %s", template.Name, task.Name, task.Template))
+			}
+		}
+		dependencies[task.Name] = GetTaskDependencies(&task)
+	}
+
+	for _, task := range template.DAG.Tasks {
+		for _, dependency := range dependencies[task.Name] {
+			if _, ok := dependencies[dependency]; !ok {
+				lintErrors = append(lintErrors, fmt.Sprintf("template %s task %s depends on undefined task %s", template.Name, task.Name, dependency))
+			}
+		}
+	}
+
+	if cycle := FindDependencyCycle(template.DAG.Tasks, dependencies); cycle != nil {
+		lintErrors = append(lintErrors, fmt.Sprintf("template %s has a dependency cycle: %s", template.Name, strings.Join(cycle, " -> ")))
+	}
+
+	return lintErrors
+}
+
+// GetTaskDependencies returns the task names a DAG task depends on, from both dependencies and depends fields.
+func GetTaskDependencies(task *v1alpha1.DAGTask) []string {
+	dependencies := make([]string, 0, len(task.Dependencies))
+	dependencies = append(dependencies, task.Dependencies...)
+	for _, match := range dependsTaskPattern.FindAllStringSubmatch(task.Depends, -1) {
+		if !utils.IsElementExists(dependencies, match[1]) {
+			dependencies = append(dependencies, match[1])
+		}
+	}
+	return dependencies
+}
+
+// FindDependencyCycle returns the first dependency cycle found between DAG tasks, or nil when there is none.
+func FindDependencyCycle(tasks []v1alpha1.DAGTask, dependencies map[string][]string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(tasks))
+	path := make([]string, 0)
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range dependencies[name] {
+			if _, ok := dependencies[dependency]; !ok {
+				continue
+			}
+			switch state[dependency] {
+			case visiting:
+				for i, p := range path {
+					if p == dependency {
+						return append(append([]string{}, path[i:]...), dependency)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dependency); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	for _, task := range tasks {
+		if state[task.Name] == unvisited {
+			if cycle := visit(task.Name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// LintInputParameters checks that every {{inputs.parameters.*}} used in a template is declared in its inputs.
+func LintInputParameters(template *v1alpha1.Template) []string {
+	lintErrors := make([]string, 0)
+
+	declared := make(map[string]bool, len(template.Inputs.Parameters))
+	for _, param := range template.Inputs.Parameters {
+		declared[param.Name] = true
+	}
+
+	for _, name := range findReferences(template, inputParameterPattern) {
+		if !declared[name] {
+			lintErrors = append(lintErrors, fmt.Sprintf("template %s uses undefined input parameter %s", template.Name, name))
+		}
+	}
+
+	return lintErrors
+}
+
+// LintWorkflowParameters checks that every {{workflow.parameters.*}} used in the spec is provided as a workflow argument.
+func LintWorkflowParameters(spec *v1alpha1.WorkflowSpec) []string {
+	lintErrors := make([]string, 0)
+
+	provided := make(map[string]bool, len(spec.Arguments.Parameters))
+	for _, param := range spec.Arguments.Parameters {
+		provided[param.Name] = true
+	}
+
+	for _, name := range findReferences(spec, workflowParameterPattern) {
+		if !provided[name] {
+			lintErrors = append(lintErrors, fmt.Sprintf("workflow parameter %s is used but not provided", name))
+		}
+	}
+
+	return lintErrors
+}
+
+func findReferences(obj interface{}, pattern *regexp.Regexp) []string {
+	references := make([]string, 0)
+	jsonBytes, err := json.Marshal(obj)
+	if err != nil {
+		return references
+	}
+	for _, match := range pattern.FindAllStringSubmatch(string(jsonBytes), -1) {
+		if !utils.IsElementExists(references, match[1]) {
+			references = append(references, match[1])
+		}
+	}
+	return references
+}
@@ -0,0 +1,154 @@
+package workflow_handler
+
+import (
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLintWorkflowSpec(t *testing.T) {
+	assert := assertion.New(t)
+
+	stepTemplate := v1alpha1.Template{
+		Name: "local-step",
+		Inputs: v1alpha1.Inputs{
+			Parameters: []v1alpha1.Parameter{{Name: "message"}},
+		},
+		Script: &v1alpha1.ScriptTemplate{
+			Source: "echo {{ inputs.parameters.message }} {{ workflow.parameters.repo }}",
+		},
+	}
+	globalParams := v1alpha1.Arguments{
+		Parameters: []v1alpha1.Parameter{{Name: "repo", Value: v1alpha1.AnyStringPtr("my-repo")}},
+	}
+
+	tests := []struct {
+		name           string
+		spec           *v1alpha1.WorkflowSpec
+		expectedErrors []string
+	}{
+		{
+			name: "Valid workflow",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Arguments:  globalParams,
+				Templates: []v1alpha1.Template{
+					{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+						{Name: "a", Template: "local-step"},
+						{Name: "b", Template: "local-step", Dependencies: []string{"a"}},
+						{Name: "c", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "versioning"}, Depends: "a.Succeeded && b"},
+					}}},
+					stepTemplate,
+				},
+			},
+			expectedErrors: []string{},
+		},
+		{
+			name: "Undefined template reference",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Arguments:  globalParams,
+				Templates: []v1alpha1.Template{
+					{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+						{Name: "a", Template: "missing-step"},
+					}}},
+					stepTemplate,
+				},
+			},
+			expectedErrors: []string{"template entryPoint task a references undefined template missing-step"},
+		},
+		{
+			name: "Dependency cycle",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Arguments:  globalParams,
+				Templates: []v1alpha1.Template{
+					{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+						{Name: "a", Template: "local-step", Dependencies: []string{"c"}},
+						{Name: "b", Template: "local-step", Dependencies: []string{"a"}},
+						{Name: "c", Template: "local-step", Depends: "b.Succeeded"},
+					}}},
+					stepTemplate,
+				},
+			},
+			expectedErrors: []string{"template entryPoint has a dependency cycle: a -> c -> b -> a"},
+		},
+		{
+			name: "Duplicate task names and unknown dependency",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Arguments:  globalParams,
+				Templates: []v1alpha1.Template{
+					{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+						{Name: "a", Template: "local-step"},
+						{Name: "a", Template: "local-step", Dependencies: []string{"z"}},
+					}}},
+					stepTemplate,
+				},
+			},
+			expectedErrors: []string{"template entryPoint has duplicate task name a"},
+		},
+		{
+			name: "Undefined input and workflow parameters",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Templates: []v1alpha1.Template{
+					{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+						{Name: "a", Template: "bad-step"},
+					}}},
+					{Name: "bad-step", Script: &v1alpha1.ScriptTemplate{
+						Source: "echo {{inputs.parameters.message}} {{workflow.parameters.not_provided}}",
+					}},
+				},
+			},
+			expectedErrors: []string{
+				"template bad-step uses undefined input parameter message",
+				"workflow parameter not_provided is used but not provided",
+			},
+		},
+		{
+			name: "Missing entrypoint",
+			spec: &v1alpha1.WorkflowSpec{
+				Entrypoint: ENTRYPOINT,
+				Templates:  []v1alpha1.Template{stepTemplate},
+				Arguments:  globalParams,
+			},
+			expectedErrors: []string{"entrypoint template entryPoint is not defined"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(test.expectedErrors, LintWorkflowSpec(test.spec))
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	assert := assertion.New(t)
+	wfcImpl := &WorkflowsClientImpl{}
+
+	workflow := &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "my-repo-my-branch-"},
+		Spec: v1alpha1.WorkflowSpec{
+			Entrypoint: ENTRYPOINT,
+			Templates: []v1alpha1.Template{
+				{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+					{Name: "a", Template: "missing-step"},
+				}}},
+			},
+		},
+	}
+
+	err := wfcImpl.Lint(workflow)
+	var lintErr *LintError
+	assert.ErrorAs(err, &lintErr)
+	assert.Equal("my-repo-my-branch-", lintErr.Workflow)
+	assert.Len(lintErr.Errors, 1)
+
+	workflow.Spec.Templates[0].DAG.Tasks[0].Template = ENTRYPOINT
+	workflow.Spec.Templates[0].DAG.Tasks[0].TemplateRef = nil
+	assert.Nil(wfcImpl.Lint(workflow))
+}
@@ -0,0 +1,26 @@
+package workflow_handler
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// NewListWatch lists and watches the workflows matching the label selector, for informers. The workflows client
+// covers every namespace Piper creates workflows in, and the reflector options, such as the resource version
+// to resume a watch from, are passed through. A watch without resource version starts with the existing workflows.
+func NewListWatch(ctx context.Context, workflows WorkflowsClient, labelSelector *metav1.LabelSelector) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.LabelSelector = metav1.FormatLabelSelector(labelSelector)
+			return workflows.List(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.LabelSelector = metav1.FormatLabelSelector(labelSelector)
+			return workflows.Watch(ctx, options)
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	"github.com/quickube/piper/pkg/conf"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNewListWatch(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		name                    string
+		routes                  []conf.Route
+		expectedListVersion     string
+		expectedWatchNamespaces []string
+	}{
+		{name: "Single namespace keeps the list resource version",
+			expectedListVersion:     "5",
+			expectedWatchNamespaces: []string{"workflows"},
+		},
+		{name: "Several namespaces start the watch with the existing workflows",
+			routes:                  []conf.Route{{Repo: "payments", Namespace: "payments"}},
+			expectedListVersion:     "",
+			expectedWatchNamespaces: []string{"workflows", "payments"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clientSet := fake.NewSimpleClientset()
+			listOptions := make([]metav1.ListOptions, 0)
+			clientSet.PrependReactor("list", "workflows", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				restrictions := action.(k8stesting.ListActionImpl).GetListRestrictions()
+				listOptions = append(listOptions, metav1.ListOptions{LabelSelector: restrictions.Labels.String()})
+				return true, &v1alpha1.WorkflowList{ListMeta: metav1.ListMeta{ResourceVersion: "5"}}, nil
+			})
+			watchNamespaces := make([]string, 0)
+			clientSet.PrependWatchReactor("workflows", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				restrictions := action.(k8stesting.WatchActionImpl).GetWatchRestrictions()
+				assert.Equal("7", restrictions.ResourceVersion)
+				assert.Equal("piper.quickube.com/notified", restrictions.Labels.String())
+				watchNamespaces = append(watchNamespaces, action.GetNamespace())
+				return true, watch.NewFake(), nil
+			})
+			wfcImpl := &WorkflowsClientImpl{
+				api: &KubernetesWorkflowsAPI{clientSet: clientSet},
+				cfg: &conf.GlobalConfig{
+					WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows", Routes: test.routes},
+				},
+			}
+
+			listWatch := NewListWatch(context.Background(), wfcImpl, &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "piper.quickube.com/notified", Operator: metav1.LabelSelectorOpExists},
+				},
+			})
+
+			list, err := listWatch.List(metav1.ListOptions{ResourceVersion: "0"})
+			assert.Nil(err)
+			assert.Equal(test.expectedListVersion, list.(*v1alpha1.WorkflowList).ResourceVersion)
+			assert.Len(listOptions, len(test.expectedWatchNamespaces))
+			assert.Equal("piper.quickube.com/notified", listOptions[0].LabelSelector)
+
+			watcher, err := listWatch.Watch(metav1.ListOptions{ResourceVersion: "7"})
+			assert.Nil(err)
+			watcher.Stop()
+			assert.ElementsMatch(test.expectedWatchNamespaces, watchNamespaces)
+		})
+	}
+}
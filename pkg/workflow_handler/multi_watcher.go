@@ -0,0 +1,69 @@
+package workflow_handler
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// MultiWatcher merges the events of several watchers into a single result channel.
+// When one of the watchers ends, all of them are stopped and the result channel is closed,
+// so consumers restart the watch as they would for a single watcher.
+type MultiWatcher struct {
+	watchers []watch.Interface
+	result   chan watch.Event
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewMultiWatcher(watchers []watch.Interface) *MultiWatcher {
+	mw := &MultiWatcher{
+		watchers: watchers,
+		result:   make(chan watch.Event),
+		done:     make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	for _, watcher := range watchers {
+		wg.Add(1)
+		go func(watcher watch.Interface) {
+			defer wg.Done()
+			defer mw.Stop()
+			for {
+				select {
+				case event, ok := <-watcher.ResultChan():
+					if !ok {
+						return
+					}
+					select {
+					case mw.result <- event:
+					case <-mw.done:
+						return
+					}
+				case <-mw.done:
+					return
+				}
+			}
+		}(watcher)
+	}
+
+	go func() {
+		wg.Wait()
+		close(mw.result)
+	}()
+
+	return mw
+}
+
+func (mw *MultiWatcher) Stop() {
+	mw.stopOnce.Do(func() {
+		close(mw.done)
+		for _, watcher := range mw.watchers {
+			watcher.Stop()
+		}
+	})
+}
+
+func (mw *MultiWatcher) ResultChan() <-chan watch.Event {
+	return mw.result
+}
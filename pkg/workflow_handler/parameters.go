@@ -0,0 +1,231 @@
+package workflow_handler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"gopkg.in/yaml.v3"
+
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+// TemplateError is returned when parameters.yaml or a trigger parameter fails to render.
+type TemplateError struct {
+	Source string
+	Err    error
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("failed to render %s: %v", e.Source, e.Err)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// ParameterError holds every trigger parameter that failed validation.
+type ParameterError struct {
+	Errors []string
+}
+
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("invalid parameters: %s", strings.Join(e.Errors, "; "))
+}
+
+// IsDefinitionError reports whether err is caused by the .workflows files of the repo,
+// such errors are reported to the commit status rather than retried.
+func IsDefinitionError(err error) bool {
+	var lintErr *LintError
+	var templateErr *TemplateError
+	var parameterErr *ParameterError
+	var policyErr *PolicyError
+	return errors.As(err, &lintErr) || errors.As(err, &templateErr) || errors.As(err, &parameterErr) || errors.As(err, &policyErr)
+}
+
+// TemplateData exposes the payload fields to parameters templates, under the names of the global variables.
+func TemplateData(payload *git_provider.WebhookPayload) map[string]interface{} {
+	labels := payload.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	return map[string]interface{}{
+		"event":               payload.Event,
+		"action":              payload.Action,
+		"repo":                payload.Repo,
+		"branch":              payload.Branch,
+		"commit":              payload.Commit,
+		"user":                payload.User,
+		"user_email":          payload.UserEmail,
+		"pull_request_url":    payload.PullRequestURL,
+		"pull_request_title":  payload.PullRequestTitle,
+		"pull_request_id":     payload.PullRequestID,
+		"dest_branch":         payload.DestBranch,
+		"labels":              labels,
+		"pull_request_labels": strings.Join(labels, ","),
+		"hook_id":             payload.HookID,
+		"owner_id":            payload.OwnerID,
+		"delivery_id":         payload.DeliveryID,
+		"comment":             payload.Comment,
+		"comment_author":      payload.CommentAuthor,
+	}
+}
+
+// GlobalParameters returns the payload values every workflow receives as parameters.
+func GlobalParameters(payload *git_provider.WebhookPayload) []v1alpha1.Parameter {
+	return []v1alpha1.Parameter{
+		{Name: "event", Value: v1alpha1.AnyStringPtr(payload.Event)},
+		{Name: "action", Value: v1alpha1.AnyStringPtr(payload.Action)},
+		{Name: "repo", Value: v1alpha1.AnyStringPtr(payload.Repo)},
+		{Name: "branch", Value: v1alpha1.AnyStringPtr(payload.Branch)},
+		{Name: "commit", Value: v1alpha1.AnyStringPtr(payload.Commit)},
+		{Name: "user", Value: v1alpha1.AnyStringPtr(payload.User)},
+		{Name: "user_email", Value: v1alpha1.AnyStringPtr(payload.UserEmail)},
+		{Name: "pull_request_url", Value: v1alpha1.AnyStringPtr(payload.PullRequestURL)},
+		{Name: "pull_request_title", Value: v1alpha1.AnyStringPtr(payload.PullRequestTitle)},
+		{Name: "dest_branch", Value: v1alpha1.AnyStringPtr(payload.DestBranch)},
+		{Name: "pull_request_labels", Value: v1alpha1.AnyStringPtr(strings.Join(payload.Labels, ","))},
+	}
+}
+
+// BuildParameters returns the workflow parameters of the batch. Trigger parameters take precedence over
+// parameters.yaml, which takes precedence over the global variables. The trigger parameters are validated
+// against the merged values.
+func BuildParameters(workflowsBatch *common.WorkflowsBatch) ([]v1alpha1.Parameter, error) {
+	params, err := RenderParameters(workflowsBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, globalParam := range GlobalParameters(workflowsBatch.Payload) {
+		if getParameter(params, globalParam.Name) == nil {
+			params = append(params, globalParam)
+		}
+	}
+
+	err = ValidateParameters(params, workflowsBatch.TriggerParameters)
+	if err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// RenderParameters renders the values of parameters.yaml and of the trigger parameters with the payload values.
+// Trigger parameters override the parameters.yaml parameters of the same name.
+func RenderParameters(workflowsBatch *common.WorkflowsBatch) ([]v1alpha1.Parameter, error) {
+	data := TemplateData(workflowsBatch.Payload)
+	params := make([]v1alpha1.Parameter, 0)
+
+	if workflowsBatch.Parameters != nil && workflowsBatch.Parameters.Content != nil {
+		source := "parameters.yaml"
+		if workflowsBatch.Parameters.Path != nil {
+			source = *workflowsBatch.Parameters.Path
+		}
+		err := yaml.Unmarshal([]byte(*workflowsBatch.Parameters.Content), &params)
+		if err != nil {
+			return nil, &TemplateError{Source: source, Err: err}
+		}
+		// Rendering the values after parsing, so payload fields such as the pull request title can't change the structure of the file
+		for i := range params {
+			if params[i].Value == nil {
+				continue
+			}
+			value, err := utils.RenderTemplate(fmt.Sprintf("%s parameter %s", source, params[i].Name), params[i].Value.String(), data)
+			if err != nil {
+				return nil, &TemplateError{Source: source, Err: err}
+			}
+			params[i].Value = v1alpha1.AnyStringPtr(value)
+		}
+	}
+
+	for _, triggerParam := range workflowsBatch.TriggerParameters {
+		if triggerParam.Name == "" {
+			return nil, &TemplateError{Source: "trigger parameters", Err: fmt.Errorf("parameter name cannot be empty")}
+		}
+		if triggerParam.Value == nil {
+			continue
+		}
+		value, err := utils.RenderTemplate(triggerParam.Name, *triggerParam.Value, data)
+		if err != nil {
+			return nil, &TemplateError{Source: fmt.Sprintf("trigger parameter %s", triggerParam.Name), Err: err}
+		}
+		params = setParameter(params, v1alpha1.Parameter{Name: triggerParam.Name, Value: v1alpha1.AnyStringPtr(value)})
+	}
+
+	return params, nil
+}
+
+// ValidateParameters checks the merged parameters against the type, enum, required and pattern of the trigger parameters.
+// Empty values that aren't required are not checked.
+func ValidateParameters(params []v1alpha1.Parameter, triggerParams []common.Parameter) error {
+	validationErrors := make([]string, 0)
+	for _, triggerParam := range triggerParams {
+		value := ""
+		if param := getParameter(params, triggerParam.Name); param != nil && param.Value != nil {
+			value = param.Value.String()
+		}
+
+		if value == "" {
+			if triggerParam.Required {
+				validationErrors = append(validationErrors, fmt.Sprintf("parameter %s is required", triggerParam.Name))
+			}
+			continue
+		}
+
+		switch triggerParam.Type {
+		case "", "string":
+		case "number":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("parameter %s value %s is not a number", triggerParam.Name, value))
+			}
+		case "boolean":
+			if _, err := strconv.ParseBool(value); err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("parameter %s value %s is not a boolean", triggerParam.Name, value))
+			}
+		default:
+			validationErrors = append(validationErrors, fmt.Sprintf("parameter %s has unknown type %s, expected string, number or boolean", triggerParam.Name, triggerParam.Type))
+		}
+
+		if len(triggerParam.Enum) != 0 && !utils.IsElementExists(triggerParam.Enum, value) {
+			validationErrors = append(validationErrors, fmt.Sprintf("parameter %s value %s is not one of %v", triggerParam.Name, value, triggerParam.Enum))
+		}
+
+		if triggerParam.Pattern != "" {
+			// Anchored, so the pattern matches the whole value
+			pattern, err := regexp.Compile("^(?:" + triggerParam.Pattern + ")$")
+			if err != nil {
+				validationErrors = append(validationErrors, fmt.Sprintf("parameter %s has invalid pattern %s: %v", triggerParam.Name, triggerParam.Pattern, err))
+			} else if !pattern.MatchString(value) {
+				validationErrors = append(validationErrors, fmt.Sprintf("parameter %s value %s does not match pattern %s", triggerParam.Name, value, triggerParam.Pattern))
+			}
+		}
+	}
+
+	if len(validationErrors) != 0 {
+		return &ParameterError{Errors: validationErrors}
+	}
+	return nil
+}
+
+func getParameter(params []v1alpha1.Parameter, name string) *v1alpha1.Parameter {
+	for i := range params {
+		if params[i].Name == name {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+// setParameter replaces the parameter with the same name, or appends it.
+func setParameter(params []v1alpha1.Parameter, param v1alpha1.Parameter) []v1alpha1.Parameter {
+	if existing := getParameter(params, param.Name); existing != nil {
+		*existing = param
+		return params
+	}
+	return append(params, param)
+}
@@ -0,0 +1,196 @@
+package workflow_handler
+
+import (
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/git_provider"
+	"github.com/quickube/piper/pkg/utils"
+	assertion "github.com/stretchr/testify/assert"
+)
+
+func TestRenderParameters(t *testing.T) {
+	assert := assertion.New(t)
+	payload := &git_provider.WebhookPayload{
+		Event:  "push",
+		Repo:   "my-repo",
+		Branch: "feature/New_UI",
+		Commit: "6f1b2c3d4e",
+		Labels: []string{"deploy"},
+
+		PullRequestTitle: "fix: x\n- name: env\n  value: prod",
+	}
+
+	var tests = []struct {
+		name              string
+		parameters        *string
+		triggerParameters []common.Parameter
+		expected          []v1alpha1.Parameter
+		err               string
+	}{
+		{name: "No parameters",
+			expected: []v1alpha1.Parameter{},
+		},
+		{name: "Literal parameters.yaml",
+			parameters: utils.SPtr("- name: global\n  value: multiline\n- name: branch_ref\n  value: \"{{ workflow.parameters.branch }}\"\n"),
+			expected: []v1alpha1.Parameter{
+				{Name: "global", Value: v1alpha1.AnyStringPtr("multiline")},
+				{Name: "branch_ref", Value: v1alpha1.AnyStringPtr("{{ workflow.parameters.branch }}")},
+			},
+		},
+		{name: "Templated parameters.yaml",
+			parameters: utils.SPtr("- name: image_tag\n  value: ${{ .branch | sanitize }}-${{ .commit | truncate 7 }}\n- name: deploy\n  value: ${{ if .labels }}true${{ else }}false${{ end }}\n"),
+			expected: []v1alpha1.Parameter{
+				{Name: "image_tag", Value: v1alpha1.AnyStringPtr("feature-new-ui-6f1b2c3")},
+				{Name: "deploy", Value: v1alpha1.AnyStringPtr("true")},
+			},
+		},
+		{name: "Trigger parameters override parameters.yaml",
+			parameters: utils.SPtr("- name: env\n  value: dev\n- name: region\n  value: us-east-1\n"),
+			triggerParameters: []common.Parameter{
+				{Name: "env", Value: utils.SPtr("${{ .event }}-env")},
+				{Name: "replicas", Value: utils.SPtr("2")},
+			},
+			expected: []v1alpha1.Parameter{
+				{Name: "env", Value: v1alpha1.AnyStringPtr("push-env")},
+				{Name: "region", Value: v1alpha1.AnyStringPtr("us-east-1")},
+				{Name: "replicas", Value: v1alpha1.AnyStringPtr("2")},
+			},
+		},
+		{name: "Payload fields don't change the structure of parameters.yaml",
+			parameters: utils.SPtr("- name: title\n  value: ${{ .pull_request_title }}\n- name: env\n  value: dev\n"),
+			expected: []v1alpha1.Parameter{
+				{Name: "title", Value: v1alpha1.AnyStringPtr("fix: x\n- name: env\n  value: prod")},
+				{Name: "env", Value: v1alpha1.AnyStringPtr("dev")},
+			},
+		},
+		{name: "Unknown field",
+			parameters: utils.SPtr("- name: env\n  value: ${{ .environment }}\n"),
+			err:        "failed to render .workflows/parameters.yaml",
+		},
+		{name: "Invalid trigger parameter",
+			triggerParameters: []common.Parameter{{Name: "version", Value: utils.SPtr("${{ semver .branch }}")}},
+			err:               "failed to render trigger parameter version",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workflowsBatch := &common.WorkflowsBatch{
+				Parameters:        &git_provider.CommitFile{},
+				TriggerParameters: test.triggerParameters,
+				Payload:           payload,
+			}
+			if test.parameters != nil {
+				workflowsBatch.Parameters = &git_provider.CommitFile{
+					Path:    utils.SPtr(".workflows/parameters.yaml"),
+					Content: test.parameters,
+				}
+			}
+
+			params, err := RenderParameters(workflowsBatch)
+			if test.err != "" {
+				var templateErr *TemplateError
+				assert.ErrorAs(err, &templateErr)
+				assert.ErrorContains(err, test.err)
+				return
+			}
+			assert.Nil(err)
+			assert.Equal(test.expected, params)
+		})
+	}
+}
+
+func TestBuildParameters(t *testing.T) {
+	assert := assertion.New(t)
+	workflowsBatch := &common.WorkflowsBatch{
+		Parameters: &git_provider.CommitFile{
+			Path:    utils.SPtr(".workflows/parameters.yaml"),
+			Content: utils.SPtr("- name: env\n  value: dev\n- name: branch\n  value: overridden\n"),
+		},
+		TriggerParameters: []common.Parameter{
+			{Name: "env", Value: utils.SPtr("staging")},
+			{Name: "commit", Value: utils.SPtr("${{ .commit | truncate 3 }}")},
+		},
+		Payload: &git_provider.WebhookPayload{Event: "push", Branch: "main", Commit: "abcdef"},
+	}
+
+	params, err := BuildParameters(workflowsBatch)
+	assert.Nil(err)
+
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		_, duplicate := values[param.Name]
+		assert.False(duplicate, "duplicate parameter %s", param.Name)
+		values[param.Name] = param.Value.String()
+	}
+	assert.Equal("staging", values["env"])
+	assert.Equal("overridden", values["branch"])
+	assert.Equal("abc", values["commit"])
+	assert.Equal("push", values["event"])
+}
+
+func TestValidateParameters(t *testing.T) {
+	assert := assertion.New(t)
+	params := []v1alpha1.Parameter{
+		{Name: "env", Value: v1alpha1.AnyStringPtr("staging")},
+		{Name: "replicas", Value: v1alpha1.AnyStringPtr("3")},
+		{Name: "debug", Value: v1alpha1.AnyStringPtr("yes")},
+		{Name: "version", Value: v1alpha1.AnyStringPtr("v1.2.3")},
+		{Name: "empty", Value: v1alpha1.AnyStringPtr("")},
+	}
+
+	var tests = []struct {
+		name          string
+		triggerParams []common.Parameter
+		expected      []string
+	}{
+		{name: "No validation",
+			triggerParams: []common.Parameter{{Name: "env"}, {Name: "missing"}},
+		},
+		{name: "Valid values",
+			triggerParams: []common.Parameter{
+				{Name: "env", Enum: []string{"dev", "staging", "production"}, Required: true},
+				{Name: "replicas", Type: "number"},
+				{Name: "version", Pattern: `v\d+\.\d+\.\d+`},
+				{Name: "empty", Enum: []string{"a"}, Pattern: "a+"},
+			},
+		},
+		{name: "Required",
+			triggerParams: []common.Parameter{{Name: "empty", Required: true}, {Name: "missing", Required: true}},
+			expected:      []string{"parameter empty is required", "parameter missing is required"},
+		},
+		{name: "Enum",
+			triggerParams: []common.Parameter{{Name: "env", Enum: []string{"dev", "production"}}},
+			expected:      []string{"parameter env value staging is not one of [dev production]"},
+		},
+		{name: "Types",
+			triggerParams: []common.Parameter{{Name: "env", Type: "number"}, {Name: "debug", Type: "boolean"}, {Name: "replicas", Type: "int"}},
+			expected: []string{
+				"parameter env value staging is not a number",
+				"parameter debug value yes is not a boolean",
+				"parameter replicas has unknown type int, expected string, number or boolean",
+			},
+		},
+		{name: "Pattern matches the whole value",
+			triggerParams: []common.Parameter{{Name: "version", Pattern: `\d+\.\d+`}, {Name: "env", Pattern: "("}},
+			expected: []string{
+				`parameter version value v1.2.3 does not match pattern \d+\.\d+`,
+				"parameter env has invalid pattern (: error parsing regexp: missing closing ): `^(?:()$`",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateParameters(params, test.triggerParams)
+			if test.expected == nil {
+				assert.Nil(err)
+				return
+			}
+			var parameterErr *ParameterError
+			assert.ErrorAs(err, &parameterErr)
+			assert.Equal(test.expected, parameterErr.Errors)
+			assert.True(IsDefinitionError(err))
+		})
+	}
+}
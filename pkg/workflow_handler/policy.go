@@ -0,0 +1,345 @@
+package workflow_handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/utils"
+)
+
+const POLICY_WARNINGS_ANNOTATION = "piper.quickube.com/policy-warnings"
+
+type PolicyViolation struct {
+	Rule    string
+	Mode    conf.PolicyMode
+	Message string
+}
+
+func (v PolicyViolation) String() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+}
+
+// PolicyError holds the enforced policy violations of a workflow.
+type PolicyError struct {
+	Workflow   string
+	Violations []string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("workflow %s violates policy: %s", e.Workflow, strings.Join(e.Violations, "; "))
+}
+
+// policyContainer is a container of a template, named after the template for the violation messages.
+type policyContainer struct {
+	name      string
+	container *corev1.Container
+}
+
+// policyTemplate is a template of the spec or an inline template of a DAG task or step, named for the violation messages.
+type policyTemplate struct {
+	name     string
+	template *v1alpha1.Template
+}
+
+// EnforcePolicy checks the workflow against WORKFLOW_POLICY. Violations of warn rules are recorded in the
+// policy warnings annotation, violations of enforce rules fail with a PolicyError.
+func (wfc *WorkflowsClientImpl) EnforcePolicy(ctx context.Context, wf *v1alpha1.Workflow) error {
+	enforced := make([]string, 0)
+	warnings := make([]string, 0)
+	for _, violation := range CheckPolicy(&wfc.cfg.PolicyConfig.Policy, wf) {
+		if violation.Mode == conf.PolicyModeWarn {
+			warnings = append(warnings, violation.String())
+		} else {
+			enforced = append(enforced, violation.String())
+		}
+	}
+
+	if len(warnings) != 0 {
+		logging.OrDefault(wfc.logger).WarnContext(ctx, "workflow has policy warnings", "workflow", wf.GetGenerateName(), "warnings", strings.Join(warnings, "; "))
+		if wf.Annotations == nil {
+			wf.Annotations = make(map[string]string)
+		}
+		wf.Annotations[POLICY_WARNINGS_ANNOTATION] = strings.Join(warnings, "; ")
+	}
+	if len(enforced) != 0 {
+		return &PolicyError{
+			Workflow:   wf.GetGenerateName(),
+			Violations: enforced,
+		}
+	}
+	return nil
+}
+
+// CheckPolicy returns the policy violations of the workflow spec, including its inline templates. Templates referenced through
+// workflowTemplateRef or templateRef are not checked.
+func CheckPolicy(policy *conf.Policy, wf *v1alpha1.Workflow) []PolicyViolation {
+	violations := make([]PolicyViolation, 0)
+	spec := &wf.Spec
+	templates := workflowTemplates(spec)
+	containers := workflowContainers(templates)
+
+	if policy.Images != nil && len(policy.Images.AllowedRegistries) != 0 {
+		mode := policy.ModeOf(policy.Images.Mode)
+		for _, c := range containers {
+			image := c.container.Image
+			switch {
+			case strings.Contains(image, "{{"):
+				violations = append(violations, PolicyViolation{Rule: "images", Mode: mode, Message: fmt.Sprintf("%s image %s is not static and cannot be checked", c.name, image)})
+			case !isImageAllowed(image, policy.Images.AllowedRegistries):
+				violations = append(violations, PolicyViolation{Rule: "images", Mode: mode, Message: fmt.Sprintf("%s image %s is not from an allowed registry", c.name, image)})
+			}
+		}
+	}
+
+	if policy.SecurityContext != nil {
+		violations = append(violations, checkSecurityContext(policy.SecurityContext, policy.ModeOf(policy.SecurityContext.Mode), spec, templates, containers)...)
+	}
+
+	if policy.ActiveDeadlineSeconds != nil {
+		mode := policy.ModeOf(policy.ActiveDeadlineSeconds.Mode)
+		max := policy.ActiveDeadlineSeconds.Max
+		if spec.ActiveDeadlineSeconds == nil {
+			violations = append(violations, PolicyViolation{Rule: "activeDeadlineSeconds", Mode: mode, Message: fmt.Sprintf("workflow activeDeadlineSeconds must be set, at most %d", max)})
+		} else if *spec.ActiveDeadlineSeconds > max {
+			violations = append(violations, PolicyViolation{Rule: "activeDeadlineSeconds", Mode: mode, Message: fmt.Sprintf("workflow activeDeadlineSeconds %d exceeds %d", *spec.ActiveDeadlineSeconds, max)})
+		}
+		for _, t := range templates {
+			deadline := t.template.ActiveDeadlineSeconds
+			if deadline != nil && deadline.Type == intstr.Int && int64(deadline.IntVal) > max {
+				violations = append(violations, PolicyViolation{Rule: "activeDeadlineSeconds", Mode: mode, Message: fmt.Sprintf("%s activeDeadlineSeconds %d exceeds %d", t.name, deadline.IntVal, max)})
+			}
+		}
+	}
+
+	if policy.Resources != nil && len(policy.Resources.RequireLimits) != 0 {
+		mode := policy.ModeOf(policy.Resources.Mode)
+		for _, c := range containers {
+			for _, resource := range policy.Resources.RequireLimits {
+				if _, ok := c.container.Resources.Limits[corev1.ResourceName(resource)]; !ok {
+					violations = append(violations, PolicyViolation{Rule: "resources", Mode: mode, Message: fmt.Sprintf("%s is missing a %s limit", c.name, resource)})
+				}
+			}
+		}
+	}
+
+	if policy.Volumes != nil && len(policy.Volumes.ForbiddenTypes) != 0 {
+		mode := policy.ModeOf(policy.Volumes.Mode)
+		check := func(owner string, volumes []corev1.Volume) {
+			for _, volume := range volumes {
+				volumeType := volumeTypeOf(volume)
+				if utils.IsElementExists(policy.Volumes.ForbiddenTypes, volumeType) {
+					violations = append(violations, PolicyViolation{Rule: "volumes", Mode: mode, Message: fmt.Sprintf("%s volume %s has forbidden type %s", owner, volume.Name, volumeType)})
+				}
+			}
+		}
+		check("workflow", spec.Volumes)
+		for _, t := range templates {
+			check(t.name, t.template.Volumes)
+		}
+	}
+
+	return violations
+}
+
+func checkSecurityContext(policy *conf.SecurityContextPolicy, mode conf.PolicyMode, spec *v1alpha1.WorkflowSpec, templates []policyTemplate, containers []policyContainer) []PolicyViolation {
+	violations := make([]PolicyViolation, 0)
+	add := func(format string, args ...interface{}) {
+		violations = append(violations, PolicyViolation{Rule: "securityContext", Mode: mode, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if policy.ForbidHostNetwork && spec.HostNetwork != nil && *spec.HostNetwork {
+		add("workflow uses the host network")
+	}
+	if policy.ForbidPodSpecPatch {
+		if spec.PodSpecPatch != "" {
+			add("workflow podSpecPatch cannot be checked")
+		}
+		for _, t := range templates {
+			if t.template.PodSpecPatch != "" {
+				add("%s podSpecPatch cannot be checked", t.name)
+			}
+		}
+	}
+
+	if policy.ForbidRunAsRoot {
+		podSecurityContexts := map[string]*corev1.PodSecurityContext{"workflow": spec.SecurityContext}
+		for _, t := range templates {
+			podSecurityContexts[t.name] = t.template.SecurityContext
+		}
+		for _, owner := range sortedKeys(podSecurityContexts) {
+			securityContext := podSecurityContexts[owner]
+			if securityContext == nil {
+				continue
+			}
+			if isRoot(securityContext.RunAsUser, securityContext.RunAsNonRoot) {
+				add("%s runs as root", owner)
+			}
+		}
+	}
+
+	for _, c := range containers {
+		securityContext := c.container.SecurityContext
+		if securityContext == nil {
+			continue
+		}
+		if policy.ForbidPrivileged && securityContext.Privileged != nil && *securityContext.Privileged {
+			add("%s is privileged", c.name)
+		}
+		if policy.ForbidPrivilegeEscalation && securityContext.AllowPrivilegeEscalation != nil && *securityContext.AllowPrivilegeEscalation {
+			add("%s allows privilege escalation", c.name)
+		}
+		if policy.ForbidRunAsRoot && isRoot(securityContext.RunAsUser, securityContext.RunAsNonRoot) {
+			add("%s runs as root", c.name)
+		}
+		if securityContext.Capabilities != nil {
+			for _, capability := range securityContext.Capabilities.Add {
+				if isCapabilityForbidden(string(capability), policy.ForbiddenCapabilities) {
+					add("%s adds forbidden capability %s", c.name, capability)
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+// workflowTemplates returns the templates of the spec and the inline templates of their DAG tasks and steps,
+// which the lint accepts in place of a template name.
+func workflowTemplates(spec *v1alpha1.WorkflowSpec) []policyTemplate {
+	templates := make([]policyTemplate, 0, len(spec.Templates))
+	for i := range spec.Templates {
+		templates = appendInlineTemplates(templates, fmt.Sprintf("template %s", spec.Templates[i].Name), &spec.Templates[i])
+	}
+	return templates
+}
+
+func appendInlineTemplates(templates []policyTemplate, name string, template *v1alpha1.Template) []policyTemplate {
+	templates = append(templates, policyTemplate{name: name, template: template})
+	if template.DAG != nil {
+		for i := range template.DAG.Tasks {
+			task := &template.DAG.Tasks[i]
+			if task.Inline != nil {
+				templates = appendInlineTemplates(templates, fmt.Sprintf("%s task %s", name, task.Name), task.Inline)
+			}
+		}
+	}
+	for i := range template.Steps {
+		for j := range template.Steps[i].Steps {
+			step := &template.Steps[i].Steps[j]
+			if step.Inline != nil {
+				templates = appendInlineTemplates(templates, fmt.Sprintf("%s step %s", name, step.Name), step.Inline)
+			}
+		}
+	}
+	return templates
+}
+
+// workflowContainers returns every container, script, init container, sidecar and container set container of the templates.
+func workflowContainers(templates []policyTemplate) []policyContainer {
+	containers := make([]policyContainer, 0)
+	for _, t := range templates {
+		template := t.template
+		name := t.name
+		if template.Container != nil {
+			containers = append(containers, policyContainer{name: name, container: template.Container})
+		}
+		if template.Script != nil {
+			containers = append(containers, policyContainer{name: name, container: &template.Script.Container})
+		}
+		if template.ContainerSet != nil {
+			for j := range template.ContainerSet.Containers {
+				container := &template.ContainerSet.Containers[j].Container
+				containers = append(containers, policyContainer{name: fmt.Sprintf("%s container %s", name, container.Name), container: container})
+			}
+		}
+		for j := range template.InitContainers {
+			container := &template.InitContainers[j].Container
+			containers = append(containers, policyContainer{name: fmt.Sprintf("%s init container %s", name, container.Name), container: container})
+		}
+		for j := range template.Sidecars {
+			container := &template.Sidecars[j].Container
+			containers = append(containers, policyContainer{name: fmt.Sprintf("%s sidecar %s", name, container.Name), container: container})
+		}
+	}
+	return containers
+}
+
+// normalizeImage returns the fully qualified name of an image, e.g. alpine:3.18 is docker.io/library/alpine:3.18.
+func normalizeImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io/" + image
+	}
+	return image
+}
+
+// isImageAllowed reports whether the image is under one of the registries, given as a registry or a repository prefix.
+func isImageAllowed(image string, registries []string) bool {
+	normalized := normalizeImage(image)
+	for _, registry := range registries {
+		registry = strings.TrimSuffix(registry, "/")
+		if strings.HasPrefix(normalized, registry+"/") ||
+			strings.HasPrefix(normalized, registry+":") ||
+			strings.HasPrefix(normalized, registry+"@") ||
+			normalized == registry {
+			return true
+		}
+	}
+	return false
+}
+
+// volumeTypeOf returns the volume source type, the JSON key of the volume other than its name, e.g. hostPath.
+func volumeTypeOf(volume corev1.Volume) string {
+	volumeBytes, err := json.Marshal(volume)
+	if err != nil {
+		return ""
+	}
+	fields := make(map[string]interface{})
+	if err = json.Unmarshal(volumeBytes, &fields); err != nil {
+		return ""
+	}
+	for field := range fields {
+		if field != "name" {
+			return field
+		}
+	}
+	return ""
+}
+
+func isRoot(runAsUser *int64, runAsNonRoot *bool) bool {
+	return (runAsUser != nil && *runAsUser == 0) || (runAsNonRoot != nil && !*runAsNonRoot)
+}
+
+// isCapabilityForbidden compares capabilities without the CAP_ prefix. ALL forbids every added capability.
+func isCapabilityForbidden(capability string, forbidden []string) bool {
+	normalize := func(c string) string {
+		return strings.TrimPrefix(strings.ToUpper(c), "CAP_")
+	}
+	for _, f := range forbidden {
+		if normalize(f) == "ALL" || normalize(f) == normalize(capability) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]*corev1.PodSecurityContext) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
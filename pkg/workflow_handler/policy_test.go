@@ -0,0 +1,270 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	assertion "github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/quickube/piper/pkg/conf"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	assert := assertion.New(t)
+
+	trueValue := true
+	falseValue := false
+	rootUser := int64(0)
+	deadline := int64(3600)
+	longDeadline := int64(86400)
+	longTemplateDeadline := intstr.FromInt(86400)
+	paramTemplateDeadline := intstr.FromString("{{ inputs.parameters.timeout }}")
+
+	inlinePolicy := conf.Policy{
+		Images:          &conf.ImagePolicy{AllowedRegistries: []string{"ghcr.io/my-org"}},
+		SecurityContext: &conf.SecurityContextPolicy{ForbidPrivileged: true},
+		Volumes:         &conf.VolumesPolicy{ForbiddenTypes: []string{"hostPath"}},
+	}
+	hostPathVolume := corev1.Volume{Name: "docker", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}}
+
+	newSpec := func(container corev1.Container) v1alpha1.WorkflowSpec {
+		return v1alpha1.WorkflowSpec{
+			ActiveDeadlineSeconds: &deadline,
+			Templates: []v1alpha1.Template{
+				{Name: "build", Container: &container},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		policy             conf.Policy
+		spec               v1alpha1.WorkflowSpec
+		expectedViolations []string
+	}{
+		{
+			name:               "Empty policy",
+			policy:             conf.Policy{},
+			spec:               newSpec(corev1.Container{Image: "alpine", SecurityContext: &corev1.SecurityContext{Privileged: &trueValue}}),
+			expectedViolations: []string{},
+		},
+		{
+			name:   "Allowed registries",
+			policy: conf.Policy{Images: &conf.ImagePolicy{AllowedRegistries: []string{"ghcr.io/my-org", "docker.io/library"}}},
+			spec: v1alpha1.WorkflowSpec{Templates: []v1alpha1.Template{
+				{Name: "a", Container: &corev1.Container{Image: "alpine:3.18"}},
+				{Name: "b", Script: &v1alpha1.ScriptTemplate{Container: corev1.Container{Image: "ghcr.io/my-org/builder@sha256:abc"}}},
+				{Name: "c", Container: &corev1.Container{Image: "ghcr.io/my-org-fork/builder"}},
+				{Name: "d", Container: &corev1.Container{Image: "bitnami/kubectl"}},
+				{Name: "e", Container: &corev1.Container{Image: "{{ inputs.parameters.image }}"}},
+				{Name: "f", Container: &corev1.Container{Image: "alpine"}, Sidecars: []v1alpha1.UserContainer{
+					{Container: corev1.Container{Name: "proxy", Image: "quay.io/proxy"}},
+				}},
+			}},
+			expectedViolations: []string{
+				"images: template c image ghcr.io/my-org-fork/builder is not from an allowed registry",
+				"images: template d image bitnami/kubectl is not from an allowed registry",
+				"images: template e image {{ inputs.parameters.image }} is not static and cannot be checked",
+				"images: template f sidecar proxy image quay.io/proxy is not from an allowed registry",
+			},
+		},
+		{
+			name: "Security context",
+			policy: conf.Policy{SecurityContext: &conf.SecurityContextPolicy{
+				ForbidPrivileged:          true,
+				ForbidPrivilegeEscalation: true,
+				ForbidRunAsRoot:           true,
+				ForbidHostNetwork:         true,
+				ForbidPodSpecPatch:        true,
+				ForbiddenCapabilities:     []string{"CAP_SYS_ADMIN"},
+			}},
+			spec: v1alpha1.WorkflowSpec{
+				HostNetwork:     &trueValue,
+				SecurityContext: &corev1.PodSecurityContext{RunAsNonRoot: &falseValue},
+				Templates: []v1alpha1.Template{
+					{Name: "a", PodSpecPatch: "{}", Container: &corev1.Container{SecurityContext: &corev1.SecurityContext{
+						Privileged:               &trueValue,
+						AllowPrivilegeEscalation: &trueValue,
+						RunAsUser:                &rootUser,
+						Capabilities:             &corev1.Capabilities{Add: []corev1.Capability{"sys_admin", "NET_BIND_SERVICE"}},
+					}}},
+				},
+			},
+			expectedViolations: []string{
+				"securityContext: workflow uses the host network",
+				"securityContext: template a podSpecPatch cannot be checked",
+				"securityContext: workflow runs as root",
+				"securityContext: template a is privileged",
+				"securityContext: template a allows privilege escalation",
+				"securityContext: template a runs as root",
+				"securityContext: template a adds forbidden capability sys_admin",
+			},
+		},
+		{
+			name:   "Active deadline",
+			policy: conf.Policy{ActiveDeadlineSeconds: &conf.DeadlinePolicy{Max: 7200}},
+			spec: v1alpha1.WorkflowSpec{
+				ActiveDeadlineSeconds: &longDeadline,
+				Templates: []v1alpha1.Template{
+					{Name: "a", ActiveDeadlineSeconds: &longTemplateDeadline},
+					{Name: "b", ActiveDeadlineSeconds: &paramTemplateDeadline},
+				},
+			},
+			expectedViolations: []string{
+				"activeDeadlineSeconds: workflow activeDeadlineSeconds 86400 exceeds 7200",
+				"activeDeadlineSeconds: template a activeDeadlineSeconds 86400 exceeds 7200",
+			},
+		},
+		{
+			name:   "Missing active deadline",
+			policy: conf.Policy{ActiveDeadlineSeconds: &conf.DeadlinePolicy{Max: 7200}},
+			spec:   v1alpha1.WorkflowSpec{},
+			expectedViolations: []string{
+				"activeDeadlineSeconds: workflow activeDeadlineSeconds must be set, at most 7200",
+			},
+		},
+		{
+			name:   "Resource limits",
+			policy: conf.Policy{Resources: &conf.ResourcesPolicy{RequireLimits: []string{"cpu", "memory"}}},
+			spec: newSpec(corev1.Container{Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+			}}),
+			expectedViolations: []string{
+				"resources: template build is missing a memory limit",
+			},
+		},
+		{
+			name:   "Volumes",
+			policy: conf.Policy{Volumes: &conf.VolumesPolicy{ForbiddenTypes: []string{"hostPath"}}},
+			spec: v1alpha1.WorkflowSpec{
+				Volumes: []corev1.Volume{
+					{Name: "docker", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/run/docker.sock"}}},
+					{Name: "shared", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				},
+			},
+			expectedViolations: []string{
+				"volumes: workflow volume docker has forbidden type hostPath",
+			},
+		},
+		{
+			name:   "Inline DAG task",
+			policy: inlinePolicy,
+			spec: v1alpha1.WorkflowSpec{Templates: []v1alpha1.Template{
+				{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+					{Name: "build", Inline: &v1alpha1.Template{
+						Container: &corev1.Container{Image: "alpine", SecurityContext: &corev1.SecurityContext{Privileged: &trueValue}},
+						Volumes:   []corev1.Volume{hostPathVolume},
+					}},
+				}}},
+			}},
+			expectedViolations: []string{
+				"images: template entryPoint task build image alpine is not from an allowed registry",
+				"securityContext: template entryPoint task build is privileged",
+				"volumes: template entryPoint task build volume docker has forbidden type hostPath",
+			},
+		},
+		{
+			name:   "Inline step",
+			policy: inlinePolicy,
+			spec: v1alpha1.WorkflowSpec{Templates: []v1alpha1.Template{
+				{Name: "main", Steps: []v1alpha1.ParallelSteps{{Steps: []v1alpha1.WorkflowStep{
+					{Name: "deploy", Inline: &v1alpha1.Template{
+						Script: &v1alpha1.ScriptTemplate{Container: corev1.Container{Image: "ghcr.io/my-org/deployer"}},
+						InitContainers: []v1alpha1.UserContainer{
+							{Container: corev1.Container{Name: "setup", Image: "ghcr.io/my-org/setup", SecurityContext: &corev1.SecurityContext{Privileged: &trueValue}}},
+						},
+						Sidecars: []v1alpha1.UserContainer{
+							{Container: corev1.Container{Name: "proxy", Image: "quay.io/proxy"}},
+						},
+						ContainerSet: &v1alpha1.ContainerSetTemplate{Containers: []v1alpha1.ContainerNode{
+							{Container: corev1.Container{Name: "test", Image: "bitnami/kubectl"}},
+						}},
+						Volumes: []corev1.Volume{hostPathVolume},
+					}},
+				}}}},
+			}},
+			expectedViolations: []string{
+				"images: template main step deploy container test image bitnami/kubectl is not from an allowed registry",
+				"images: template main step deploy sidecar proxy image quay.io/proxy is not from an allowed registry",
+				"securityContext: template main step deploy init container setup is privileged",
+				"volumes: template main step deploy volume docker has forbidden type hostPath",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wf := &v1alpha1.Workflow{Spec: test.spec}
+			violations := make([]string, 0)
+			for _, violation := range CheckPolicy(&test.policy, wf) {
+				violations = append(violations, violation.String())
+			}
+			assert.Equal(test.expectedViolations, violations)
+		})
+	}
+}
+
+func TestEnforcePolicy(t *testing.T) {
+	assert := assertion.New(t)
+
+	wfcImpl := &WorkflowsClientImpl{
+		cfg: &conf.GlobalConfig{
+			PolicyConfig: conf.PolicyConfig{Policy: conf.Policy{
+				Mode:   conf.PolicyModeWarn,
+				Images: &conf.ImagePolicy{AllowedRegistries: []string{"ghcr.io/my-org"}},
+				Resources: &conf.ResourcesPolicy{
+					Mode:          conf.PolicyModeEnforce,
+					RequireLimits: []string{"memory"},
+				},
+			}},
+		},
+	}
+	newWorkflow := func(container corev1.Container) *v1alpha1.Workflow {
+		return &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: "my-repo-main-"},
+			Spec: v1alpha1.WorkflowSpec{Templates: []v1alpha1.Template{
+				{Name: "build", Container: &container},
+			}},
+		}
+	}
+	limits := corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")}}
+
+	t.Run("Warnings are annotated", func(t *testing.T) {
+		wf := newWorkflow(corev1.Container{Image: "alpine", Resources: limits})
+		err := wfcImpl.EnforcePolicy(context.Background(), wf)
+		assert.Nil(err)
+		assert.Equal("images: template build image alpine is not from an allowed registry", wf.Annotations[POLICY_WARNINGS_ANNOTATION])
+	})
+
+	t.Run("Enforced violations fail", func(t *testing.T) {
+		wf := newWorkflow(corev1.Container{Image: "ghcr.io/my-org/builder"})
+		err := wfcImpl.EnforcePolicy(context.Background(), wf)
+		var policyErr *PolicyError
+		assert.ErrorAs(err, &policyErr)
+		assert.Equal([]string{"resources: template build is missing a memory limit"}, policyErr.Violations)
+		assert.True(IsDefinitionError(err))
+		assert.NotContains(wf.Annotations, POLICY_WARNINGS_ANNOTATION)
+	})
+
+	t.Run("Enforced violations of inline templates fail", func(t *testing.T) {
+		wf := newWorkflow(corev1.Container{Image: "ghcr.io/my-org/builder", Resources: limits})
+		wf.Spec.Templates = append(wf.Spec.Templates, v1alpha1.Template{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+			{Name: "inline", Inline: &v1alpha1.Template{Container: &corev1.Container{Image: "ghcr.io/my-org/builder"}}},
+		}}})
+		err := wfcImpl.EnforcePolicy(context.Background(), wf)
+		var policyErr *PolicyError
+		assert.ErrorAs(err, &policyErr)
+		assert.Equal([]string{"resources: template entryPoint task inline is missing a memory limit"}, policyErr.Violations)
+	})
+
+	t.Run("Compliant workflow", func(t *testing.T) {
+		wf := newWorkflow(corev1.Container{Image: "ghcr.io/my-org/builder:v1", Resources: limits})
+		assert.Nil(wfcImpl.EnforcePolicy(context.Background(), wf))
+		assert.Nil(wf.Annotations)
+	})
+}
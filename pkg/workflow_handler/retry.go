@@ -0,0 +1,79 @@
+package workflow_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/quickube/piper/pkg/logging"
+)
+
+var ErrWorkflowNotRetryable = errors.New("only failed workflows can be retried")
+
+// retryResetLabels are the labels Piper set on a previous run of a workflow, dropped when it is retried.
+var retryResetLabels = []string{SUPERSEDED_BY_LABEL, "piper.quickube.com/stuck"}
+
+// resubmitResetLabels also drop the labels tying a workflow to the webhook that submitted it, so a resubmitted
+// workflow is neither taken for a duplicate delivery nor canceled along with the superseded runs of its group.
+var resubmitResetLabels = append([]string{IDEMPOTENCY_KEY_LABEL, CONCURRENCY_GROUP_LABEL}, retryResetLabels...)
+
+// checkRetryable returns ErrWorkflowNotRetryable when the workflow did not fail.
+func checkRetryable(wf *v1alpha1.Workflow) error {
+	switch wf.Status.Phase {
+	case v1alpha1.WorkflowFailed, v1alpha1.WorkflowError:
+		return nil
+	}
+	return fmt.Errorf("workflow %s is %s: %w", wf.GetName(), wf.Status.Phase, ErrWorkflowNotRetryable)
+}
+
+// resetPiperLabels removes the given bookkeeping labels of a retried or resubmitted workflow,
+// and marks it as not notified so its new phases are reported.
+func resetPiperLabels(wf *v1alpha1.Workflow, keys []string) {
+	for _, key := range keys {
+		delete(wf.Labels, key)
+	}
+	if _, ok := wf.Labels["piper.quickube.com/notified"]; ok {
+		wf.Labels["piper.quickube.com/notified"] = "false"
+	}
+}
+
+func (wfc *WorkflowsClientImpl) RetryWorkflow(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	wf, err := wfc.api.Retry(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retry workflow %s, error: %w", name, err)
+	}
+	logging.OrDefault(wfc.logger).InfoContext(ctx, "workflow retried", "workflow", name, "namespace", namespace)
+	return wf, nil
+}
+
+func (wfc *WorkflowsClientImpl) ResubmitWorkflow(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error) {
+	wf, err := wfc.api.Resubmit(ctx, namespace, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resubmit workflow %s, error: %w", name, err)
+	}
+	logging.OrDefault(wfc.logger).InfoContext(ctx, "workflow resubmitted", "workflow", name, "namespace", namespace, "resubmitted_as", wf.GetName())
+	return wf, nil
+}
+
+// ListCommitWorkflows lists the workflows Piper submitted for a commit of a repo, in the namespace the repo is routed to.
+func (wfc *WorkflowsClientImpl) ListCommitWorkflows(ctx context.Context, repo string, commit string) ([]v1alpha1.Workflow, error) {
+	namespace := wfc.cfg.WorkflowServerConfig.RouteFor(repo).Namespace
+	labelSelector := fmt.Sprintf("repo=%s,commit=%s", ConvertToValidString(repo), ConvertToValidString(commit))
+	workflows, err := wfc.api.List(ctx, namespace, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows of repo %s commit %s, error: %v", repo, commit, err)
+	}
+	return workflows.Items, nil
+}
+
+func (wfc *WorkflowsClientImpl) StopWorkflow(ctx context.Context, namespace string, name string) error {
+	err := wfc.api.Shutdown(ctx, namespace, name, v1alpha1.ShutdownStrategyStop)
+	if err != nil {
+		return fmt.Errorf("failed to stop workflow %s, error: %v", name, err)
+	}
+	logging.OrDefault(wfc.logger).InfoContext(ctx, "workflow stopped", "workflow", name, "namespace", namespace)
+	return nil
+}
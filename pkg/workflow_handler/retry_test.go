@@ -0,0 +1,110 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	"github.com/argoproj/argo-workflows/v3/workflow/common"
+	wfutil "github.com/argoproj/argo-workflows/v3/workflow/util"
+	"github.com/quickube/piper/pkg/conf"
+	assertion "github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func newFailedWorkflow() *v1alpha1.Workflow {
+	return &v1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:         "my-repo-main-abcde",
+			GenerateName: "my-repo-main-",
+			Namespace:    "workflows",
+			Labels: map[string]string{
+				"piper.quickube.com/notified": "Failed",
+				"piper.quickube.com/stuck":    "Running",
+				"repo":                        "my-repo",
+				"commit":                      "abc123",
+				SUPERSEDED_BY_LABEL:           "my-repo-main-fghij",
+				CONCURRENCY_GROUP_LABEL:       "my-repo-main",
+				IDEMPOTENCY_KEY_LABEL:         "1234",
+				common.LabelKeyCompleted:      "true",
+				common.LabelKeyPhase:          "Failed",
+			},
+			Annotations: map[string]string{CONFIG_ANNOTATION: "default"},
+		},
+		Spec: v1alpha1.WorkflowSpec{Entrypoint: ENTRYPOINT, Shutdown: v1alpha1.ShutdownStrategyStop},
+		Status: v1alpha1.WorkflowStatus{
+			Phase:   v1alpha1.WorkflowFailed,
+			Message: "child failed",
+			Nodes: v1alpha1.Nodes{
+				"root":   {ID: "root", Name: "my-repo-main-abcde", TemplateName: ENTRYPOINT, Type: v1alpha1.NodeTypeDAG, Phase: v1alpha1.NodeFailed, Children: []string{"build"}},
+				"build":  {ID: "build", Name: "my-repo-main-abcde.build", TemplateName: "build", Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeSucceeded, BoundaryID: "root", Children: []string{"test"}},
+				"test":   {ID: "test", Name: "my-repo-main-abcde.test", TemplateName: "test", Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeFailed, BoundaryID: "root"},
+				"onexit": {ID: "onexit", Name: "my-repo-main-abcde.onExit", TemplateName: ONEXIT, Type: v1alpha1.NodeTypePod, Phase: v1alpha1.NodeSucceeded},
+			},
+		},
+	}
+}
+
+func TestRetryWorkflow(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	failed := newFailedWorkflow()
+	newPod := func(nodeID string) *corev1.Pod {
+		node := failed.Status.Nodes[nodeID]
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      wfutil.GeneratePodName(failed.Name, node.Name, node.TemplateName, node.ID, wfutil.GetWorkflowPodNameVersion(failed)),
+			Namespace: "workflows",
+		}}
+	}
+	buildPod := newPod("build")
+	clientSet := fake.NewSimpleClientset(failed)
+	kubeClient := kubefake.NewSimpleClientset(buildPod, newPod("test"), newPod("onexit"))
+	wfcImpl := &WorkflowsClientImpl{
+		api: &KubernetesWorkflowsAPI{clientSet: clientSet, kubeClient: kubeClient},
+		cfg: &conf.GlobalConfig{
+			WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+		},
+	}
+
+	retried, err := wfcImpl.RetryWorkflow(ctx, "workflows", "my-repo-main-abcde")
+	assert.Nil(err)
+	assert.Equal(v1alpha1.WorkflowRunning, retried.Status.Phase)
+	assert.Equal(v1alpha1.ShutdownStrategyNone, retried.Spec.Shutdown)
+	assert.NotContains(retried.Labels, common.LabelKeyCompleted)
+	assert.NotContains(retried.Labels, SUPERSEDED_BY_LABEL)
+	assert.NotContains(retried.Labels, "piper.quickube.com/stuck")
+	assert.Equal("false", retried.Labels["piper.quickube.com/notified"])
+	assert.Equal("1234", retried.Labels[IDEMPOTENCY_KEY_LABEL])
+	assert.Equal("my-repo-main", retried.Labels[CONCURRENCY_GROUP_LABEL])
+	assert.Equal(v1alpha1.NodeSucceeded, retried.Status.Nodes["build"].Phase)
+	assert.NotContains(retried.Status.Nodes, "test")
+	assert.NotContains(retried.Status.Nodes, "onexit")
+
+	pods, err := kubeClient.CoreV1().Pods("workflows").List(ctx, metav1.ListOptions{})
+	assert.Nil(err)
+	assert.Len(pods.Items, 1)
+	assert.Equal(buildPod.Name, pods.Items[0].Name)
+
+	_, err = wfcImpl.RetryWorkflow(ctx, "workflows", "my-repo-main-abcde")
+	assert.ErrorIs(err, ErrWorkflowNotRetryable)
+
+	resubmitted, err := wfcImpl.ResubmitWorkflow(ctx, "workflows", "my-repo-main-abcde")
+	assert.Nil(err)
+	assert.Equal("my-repo-main-", resubmitted.GenerateName)
+	assert.Equal(map[string]string{
+		"piper.quickube.com/notified":       "false",
+		"repo":                              "my-repo",
+		"commit":                            "abc123",
+		common.LabelKeyPreviousWorkflowName: "my-repo-main-abcde",
+	}, resubmitted.Labels)
+	assert.Equal("default", resubmitted.Annotations[CONFIG_ANNOTATION])
+	assert.Equal(v1alpha1.ShutdownStrategyNone, resubmitted.Spec.Shutdown)
+
+	workflows, err := wfcImpl.ListCommitWorkflows(ctx, "my-repo", "abc123")
+	assert.Nil(err)
+	assert.Len(workflows, 2)
+}
@@ -0,0 +1,99 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/quickube/piper/pkg/common"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/git_provider"
+	assertion "github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestRouting(t *testing.T) {
+	assert := assertion.New(t)
+
+	routes := conf.Routes{}
+	err := routes.Decode(`
+- repo: "payments-*"
+  namespace: payments
+  serviceAccount: payments-workflows
+  allowedConfigs: ["default"]
+- repo: "*"
+  serviceAccount: workflows
+`)
+	assert.Nil(err)
+
+	wfcImpl := &WorkflowsClientImpl{
+		cfg: &conf.GlobalConfig{
+			WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows", Routes: routes},
+			WorkflowsConfig: conf.WorkflowsConfig{Configs: map[string]*conf.ConfigInstance{
+				"default":    {},
+				"privileged": {},
+			}},
+		},
+	}
+	assert.Equal([]string{"workflows", "payments"}, wfcImpl.cfg.Namespaces())
+
+	var tests = []struct {
+		name                   string
+		repo                   string
+		config                 string
+		expectedNamespace      string
+		expectedServiceAccount string
+		expectedError          bool
+	}{
+		{name: "Routed repo", repo: "payments-api", config: "default", expectedNamespace: "payments", expectedServiceAccount: "payments-workflows"},
+		{name: "Config not allowed", repo: "payments-api", config: "privileged", expectedError: true},
+		{name: "Catch all route", repo: "frontend", config: "privileged", expectedNamespace: "workflows", expectedServiceAccount: "workflows"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			workflowsBatch := &common.WorkflowsBatch{
+				Config:  &test.config,
+				Payload: &git_provider.WebhookPayload{Repo: test.repo, Branch: "main"},
+			}
+
+			_, err := wfcImpl.SelectConfig(context.Background(), workflowsBatch)
+			if test.expectedError {
+				assert.NotNil(err)
+				return
+			}
+			assert.Nil(err)
+
+			workflow, err := wfcImpl.CreateWorkflow(&v1alpha1.WorkflowSpec{}, workflowsBatch)
+			assert.Nil(err)
+			assert.Equal(test.expectedNamespace, workflow.GetNamespace())
+			assert.Equal(test.expectedServiceAccount, workflow.Spec.ServiceAccountName)
+		})
+	}
+
+	assert.NotNil(routes.Decode(`[{"namespace": "payments"}]`))
+	assert.NotNil(routes.Decode(`[{"repo": "[payments"}]`))
+}
+
+func TestMultiWatcher(t *testing.T) {
+	assert := assertion.New(t)
+
+	workflows := watch.NewFake()
+	payments := watch.NewFake()
+	watcher := NewMultiWatcher([]watch.Interface{workflows, payments})
+
+	go workflows.Modify(&v1alpha1.Workflow{})
+	event := <-watcher.ResultChan()
+	assert.Equal(watch.Modified, event.Type)
+
+	go payments.Add(&v1alpha1.Workflow{})
+	event = <-watcher.ResultChan()
+	assert.Equal(watch.Added, event.Type)
+
+	// One of the watches ending ends the merged watch
+	payments.Stop()
+	_, ok := <-watcher.ResultChan()
+	assert.False(ok)
+	assert.True(workflows.IsStopped())
+
+	watcher.Stop()
+}
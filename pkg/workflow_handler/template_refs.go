@@ -0,0 +1,136 @@
+package workflow_handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/utils"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// TemplateRef is a WorkflowTemplate or ClusterWorkflowTemplate a workflow depends on,
+// with the names of the templates used from it.
+type TemplateRef struct {
+	Name         string
+	ClusterScope bool
+	Templates    []string
+}
+
+func (r *TemplateRef) Kind() string {
+	if r.ClusterScope {
+		return "ClusterWorkflowTemplate"
+	}
+	return "WorkflowTemplate"
+}
+
+// CollectTemplateRefs returns the WorkflowTemplates and ClusterWorkflowTemplates referenced by the workflow
+// through workflowTemplateRef, templateRef tasks and steps, and the config templates lists.
+func CollectTemplateRefs(wf *v1alpha1.Workflow, config *conf.ConfigInstance) []*TemplateRef {
+	refs := make([]*TemplateRef, 0)
+
+	if wf.Spec.WorkflowTemplateRef != nil {
+		refs = addTemplateRef(refs, wf.Spec.WorkflowTemplateRef.Name, wf.Spec.WorkflowTemplateRef.ClusterScope, "")
+	}
+	if config != nil {
+		for _, name := range config.WorkflowTemplates {
+			refs = addTemplateRef(refs, name, false, "")
+		}
+		for _, name := range config.ClusterWorkflowTemplates {
+			refs = addTemplateRef(refs, name, true, "")
+		}
+	}
+
+	for _, template := range wf.Spec.Templates {
+		if template.DAG != nil {
+			for _, task := range template.DAG.Tasks {
+				if task.TemplateRef != nil {
+					refs = addTemplateRef(refs, task.TemplateRef.Name, task.TemplateRef.ClusterScope, task.TemplateRef.Template)
+				}
+			}
+		}
+		for _, parallelSteps := range template.Steps {
+			for _, step := range parallelSteps.Steps {
+				if step.TemplateRef != nil {
+					refs = addTemplateRef(refs, step.TemplateRef.Name, step.TemplateRef.ClusterScope, step.TemplateRef.Template)
+				}
+			}
+		}
+	}
+
+	return refs
+}
+
+func addTemplateRef(refs []*TemplateRef, name string, clusterScope bool, template string) []*TemplateRef {
+	for _, ref := range refs {
+		if ref.Name == name && ref.ClusterScope == clusterScope {
+			if template != "" && !utils.IsElementExists(ref.Templates, template) {
+				ref.Templates = append(ref.Templates, template)
+			}
+			return refs
+		}
+	}
+
+	ref := &TemplateRef{Name: name, ClusterScope: clusterScope, Templates: make([]string, 0)}
+	if template != "" {
+		ref.Templates = append(ref.Templates, template)
+	}
+	return append(refs, ref)
+}
+
+// ResolveTemplateRefs checks that every WorkflowTemplate and ClusterWorkflowTemplate the workflow depends on
+// exists in the cluster and defines the templates referenced from it.
+func (wfc *WorkflowsClientImpl) ResolveTemplateRefs(ctx context.Context, wf *v1alpha1.Workflow) error {
+	var config *conf.ConfigInstance
+	if configName, ok := wf.GetAnnotations()[CONFIG_ANNOTATION]; ok {
+		config, _ = wfc.cfg.WorkflowsConfig.GetConfig(configName)
+	}
+
+	namespace := wf.GetNamespace()
+	if namespace == "" {
+		namespace = wfc.cfg.Namespace
+	}
+
+	lintErrors := make([]string, 0)
+	for _, ref := range CollectTemplateRefs(wf, config) {
+		templates, err := wfc.getReferencedTemplates(ctx, ref, namespace)
+		if k8serrors.IsNotFound(err) {
+			lintErrors = append(lintErrors, fmt.Sprintf("%s %s not found", ref.Kind(), ref.Name))
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get %s %s, error: %v", ref.Kind(), ref.Name, err)
+		}
+		for _, name := range ref.Templates {
+			if !IsTemplateExists(templates, name) {
+				lintErrors = append(lintErrors, fmt.Sprintf("%s %s has no template %s", ref.Kind(), ref.Name, name))
+			}
+		}
+	}
+
+	if len(lintErrors) != 0 {
+		return &LintError{
+			Workflow: wf.GetGenerateName(),
+			Errors:   lintErrors,
+		}
+	}
+
+	return nil
+}
+
+func (wfc *WorkflowsClientImpl) getReferencedTemplates(ctx context.Context, ref *TemplateRef, namespace string) ([]v1alpha1.Template, error) {
+	if ref.ClusterScope {
+		clusterWorkflowTemplate, err := wfc.api.GetClusterWorkflowTemplate(ctx, ref.Name)
+		if err != nil {
+			return nil, err
+		}
+		return clusterWorkflowTemplate.Spec.Templates, nil
+	}
+
+	workflowTemplate, err := wfc.api.GetWorkflowTemplate(ctx, namespace, ref.Name)
+	if err != nil {
+		return nil, err
+	}
+	return workflowTemplate.Spec.Templates, nil
+}
@@ -0,0 +1,105 @@
+package workflow_handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
+	"github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned/fake"
+	"github.com/quickube/piper/pkg/conf"
+	assertion "github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCollectTemplateRefs(t *testing.T) {
+	assert := assertion.New(t)
+
+	fromTemplate := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			WorkflowTemplateRef: &v1alpha1.WorkflowTemplateRef{Name: "pipeline"},
+		},
+	}
+	workflow := &v1alpha1.Workflow{
+		Spec: v1alpha1.WorkflowSpec{
+			Templates: []v1alpha1.Template{
+				{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: []v1alpha1.DAGTask{
+					{Name: "a", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "versioning", ClusterScope: true}},
+					{Name: "b", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "release", ClusterScope: true}},
+					{Name: "c", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "versioning", ClusterScope: true}},
+				}}},
+			},
+		},
+	}
+	config := &conf.ConfigInstance{
+		WorkflowTemplates: []string{"org-templates"},
+	}
+
+	assert.Equal([]*TemplateRef{
+		{Name: "pipeline", ClusterScope: false, Templates: []string{}},
+		{Name: "org-templates", ClusterScope: false, Templates: []string{}},
+	}, CollectTemplateRefs(fromTemplate, config))
+
+	assert.Equal([]*TemplateRef{
+		{Name: "org-templates", ClusterScope: false, Templates: []string{}},
+		{Name: "common-toolkit", ClusterScope: true, Templates: []string{"versioning", "release"}},
+	}, CollectTemplateRefs(workflow, config))
+}
+
+func TestResolveTemplateRefs(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	clientSet := fake.NewSimpleClientset(
+		&v1alpha1.ClusterWorkflowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "common-toolkit"},
+			Spec: v1alpha1.WorkflowSpec{
+				Templates: []v1alpha1.Template{{Name: "versioning"}},
+			},
+		},
+		&v1alpha1.WorkflowTemplate{
+			ObjectMeta: metav1.ObjectMeta{Name: "org-templates", Namespace: "workflows"},
+		},
+	)
+	wfcImpl := &WorkflowsClientImpl{
+		api: &KubernetesWorkflowsAPI{clientSet: clientSet},
+		cfg: &conf.GlobalConfig{
+			WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+			WorkflowsConfig: conf.WorkflowsConfig{Configs: map[string]*conf.ConfigInstance{
+				"default": {WorkflowTemplates: []string{"org-templates"}},
+			}},
+		},
+	}
+
+	newWorkflow := func(tasks ...v1alpha1.DAGTask) *v1alpha1.Workflow {
+		return &v1alpha1.Workflow{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "my-repo-my-branch-",
+				Namespace:    "workflows",
+				Annotations:  map[string]string{CONFIG_ANNOTATION: "default"},
+			},
+			Spec: v1alpha1.WorkflowSpec{
+				Templates: []v1alpha1.Template{{Name: ENTRYPOINT, DAG: &v1alpha1.DAGTemplate{Tasks: tasks}}},
+			},
+		}
+	}
+
+	t.Run("Existing references", func(t *testing.T) {
+		err := wfcImpl.ResolveTemplateRefs(ctx, newWorkflow(
+			v1alpha1.DAGTask{Name: "a", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "versioning", ClusterScope: true}},
+		))
+		assert.Nil(err)
+	})
+
+	t.Run("Missing template and missing WorkflowTemplate", func(t *testing.T) {
+		err := wfcImpl.ResolveTemplateRefs(ctx, newWorkflow(
+			v1alpha1.DAGTask{Name: "a", TemplateRef: &v1alpha1.TemplateRef{Name: "common-toolkit", Template: "release", ClusterScope: true}},
+			v1alpha1.DAGTask{Name: "b", TemplateRef: &v1alpha1.TemplateRef{Name: "not-exists", Template: "build"}},
+		))
+		var lintErr *LintError
+		assert.ErrorAs(err, &lintErr)
+		assert.Equal([]string{
+			"ClusterWorkflowTemplate common-toolkit has no template release",
+			"WorkflowTemplate not-exists not found",
+		}, lintErr.Errors)
+	})
+}
@@ -12,10 +12,30 @@ type WorkflowsClient interface {
 	ConstructTemplates(workflowsBatch *common.WorkflowsBatch, configName string) ([]v1alpha1.Template, error)
 	ConstructSpec(templates []v1alpha1.Template, params []v1alpha1.Parameter, configName string) (*v1alpha1.WorkflowSpec, error)
 	CreateWorkflow(spec *v1alpha1.WorkflowSpec, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error)
-	SelectConfig(workflowsBatch *common.WorkflowsBatch) (string, error)
+	SelectConfig(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (string, error)
+	RenderWorkflow(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error)
 	Lint(wf *v1alpha1.Workflow) error
-	Submit(ctx context.Context, wf *v1alpha1.Workflow) error
-	HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) error
-	Watch(ctx context.Context, labelSelector *metav1.LabelSelector) (watch.Interface, error)
-	UpdatePiperWorkflowLabel(ctx context.Context, workflowName string, label string, value string) error
+	ResolveTemplateRefs(ctx context.Context, wf *v1alpha1.Workflow) error
+	EnforcePolicy(ctx context.Context, wf *v1alpha1.Workflow) error
+	Submit(ctx context.Context, wf *v1alpha1.Workflow) (*v1alpha1.Workflow, error)
+	HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	UpdatePiperWorkflowLabel(ctx context.Context, namespace string, workflowName string, label string, value string) error
+	RetryWorkflow(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error)
+	ResubmitWorkflow(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error)
+	ListCommitWorkflows(ctx context.Context, repo string, commit string) ([]v1alpha1.Workflow, error)
+	StopWorkflow(ctx context.Context, namespace string, name string) error
+}
+
+type WorkflowsAPI interface {
+	Create(ctx context.Context, namespace string, wf *v1alpha1.Workflow) (*v1alpha1.Workflow, error)
+	Watch(ctx context.Context, namespace string, opts metav1.ListOptions) (watch.Interface, error)
+	List(ctx context.Context, namespace string, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error)
+	SetLabels(ctx context.Context, namespace string, name string, labels map[string]string) error
+	Shutdown(ctx context.Context, namespace string, name string, strategy v1alpha1.ShutdownStrategy) error
+	Retry(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error)
+	Resubmit(ctx context.Context, namespace string, name string) (*v1alpha1.Workflow, error)
+	GetWorkflowTemplate(ctx context.Context, namespace string, name string) (*v1alpha1.WorkflowTemplate, error)
+	GetClusterWorkflowTemplate(ctx context.Context, name string) (*v1alpha1.ClusterWorkflowTemplate, error)
 }
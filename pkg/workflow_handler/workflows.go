@@ -2,51 +2,90 @@ package workflow_handler
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
-	wfClientSet "github.com/argoproj/argo-workflows/v3/pkg/client/clientset/versioned"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
-	"log"
-	"strings"
+	"log/slog"
+	"strconv"
 
 	"github.com/quickube/piper/pkg/common"
 	"github.com/quickube/piper/pkg/conf"
+	"github.com/quickube/piper/pkg/logging"
+	"github.com/quickube/piper/pkg/tracing"
 	"github.com/quickube/piper/pkg/utils"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
-	ENTRYPOINT = "entryPoint"
-	ONEXIT     = "exitHandler"
+	ENTRYPOINT        = "entryPoint"
+	ONEXIT            = "exitHandler"
+	CONFIG_ANNOTATION = "piper.quickube.com/config"
+
+	// REPO_ANNOTATION and BRANCH_ANNOTATION hold the repo and branch as received, as their labels are sanitized.
+	REPO_ANNOTATION   = "piper.quickube.com/repo"
+	BRANCH_ANNOTATION = "piper.quickube.com/branch"
+
+	CONCURRENCY_GROUP_LABEL = "piper.quickube.com/concurrency-group"
+	SUPERSEDED_BY_LABEL     = "piper.quickube.com/superseded-by"
+
+	IDEMPOTENCY_KEY_LABEL  = "piper.quickube.com/idempotency-key"
+	DELIVERY_ID_ANNOTATION = "piper.quickube.com/delivery-id"
+
+	// NODE_STATUSES_ANNOTATION makes the event handler report a commit status per top-level DAG task.
+	NODE_STATUSES_ANNOTATION = "piper.quickube.com/node-statuses"
+
+	// PULL_REQUEST_LABEL holds the number of the pull request that triggered the workflow, and
+	// PULL_REQUEST_COMMENT_LABEL the ID of the summary comment the event handler keeps on it.
+	PULL_REQUEST_LABEL         = "piper.quickube.com/pull-request"
+	PULL_REQUEST_COMMENT_LABEL = "piper.quickube.com/pull-request-comment"
 )
 
 type WorkflowsClientImpl struct {
-	clientSet *wfClientSet.Clientset
+	api       WorkflowsAPI
 	cfg       *conf.GlobalConfig
+	submitted *utils.ExpiringSet
+	// logger may be nil, logging.OrDefault uses the default logger then.
+	logger *slog.Logger
 }
 
-func NewWorkflowsClient(cfg *conf.GlobalConfig) (WorkflowsClient, error) {
-	restClientConfig, err := utils.GetClientConfig(cfg.WorkflowServerConfig.KubeConfig)
+func NewWorkflowsClient(cfg *conf.GlobalConfig, logger *slog.Logger) (WorkflowsClient, error) {
+	var api WorkflowsAPI
+	var err error
+	if cfg.WorkflowServerConfig.CreateCRD {
+		api, err = NewKubernetesWorkflowsAPI(cfg)
+	} else {
+		api, err = NewArgoServerWorkflowsAPI(cfg)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	clientSet := wfClientSet.NewForConfigOrDie(restClientConfig) //.ArgoprojV1alpha1().Workflows(namespace)
 	return &WorkflowsClientImpl{
-		clientSet: clientSet,
+		api:       api,
 		cfg:       cfg,
+		submitted: utils.NewExpiringSet(cfg.WebhookConfig.DeduplicationWindow),
+		logger:    logger,
 	}, nil
 }
 
+// ConstructTemplates returns the templates of the batch, with the onExit of the config when the batch has none.
+// A workflow created from a WorkflowTemplate can't have templates of its own, so it gets no templates, and its
+// onExit is the one of the WorkflowTemplate.
 func (wfc *WorkflowsClientImpl) ConstructTemplates(workflowsBatch *common.WorkflowsBatch, configName string) ([]v1alpha1.Template, error) {
 	finalTemplate := make([]v1alpha1.Template, 0)
+	if workflowsBatch.WorkflowTemplateRef != nil {
+		return finalTemplate, nil
+	}
+
 	onStart, err := CreateDAGTemplate(workflowsBatch.OnStart, ENTRYPOINT)
 	if err != nil {
 		return nil, err
 	}
+	if onStart == nil {
+		return nil, fmt.Errorf("no onStart tasks or workflowTemplateRef for repo %s branch %s", workflowsBatch.Payload.Repo, workflowsBatch.Payload.Branch)
+	}
 	finalTemplate = append(finalTemplate, *onStart)
 
 	onExit, err := CreateDAGTemplate(workflowsBatch.OnExit, ONEXIT)
@@ -54,11 +93,11 @@ func (wfc *WorkflowsClientImpl) ConstructTemplates(workflowsBatch *common.Workfl
 		return nil, err
 	}
 	if onExit == nil || len(onExit.DAG.Tasks) == 0 {
-		if IsConfigExists(&wfc.cfg.WorkflowsConfig, configName) && IsConfigsOnExitExists(&wfc.cfg.WorkflowsConfig, configName) {
+		if config, ok := wfc.cfg.WorkflowsConfig.GetConfig(configName); ok && len(config.OnExit) != 0 {
 			template := &v1alpha1.Template{
 				Name: ONEXIT,
 				DAG: &v1alpha1.DAGTemplate{
-					Tasks: wfc.cfg.WorkflowsConfig.Configs[configName].OnExit,
+					Tasks: config.OnExit,
 				},
 			}
 
@@ -78,14 +117,16 @@ func (wfc *WorkflowsClientImpl) ConstructTemplates(workflowsBatch *common.Workfl
 
 func (wfc *WorkflowsClientImpl) ConstructSpec(templates []v1alpha1.Template, params []v1alpha1.Parameter, configName string) (*v1alpha1.WorkflowSpec, error) {
 	finalSpec := &v1alpha1.WorkflowSpec{}
-	if IsConfigExists(&wfc.cfg.WorkflowsConfig, configName) {
-		*finalSpec = wfc.cfg.WorkflowsConfig.Configs[configName].Spec
-		if len(wfc.cfg.WorkflowsConfig.Configs[configName].OnExit) != 0 {
+	if config, ok := wfc.cfg.WorkflowsConfig.GetConfig(configName); ok {
+		*finalSpec = *config.Spec.DeepCopy()
+		if len(config.OnExit) != 0 && IsTemplateExists(templates, ONEXIT) {
 			finalSpec.OnExit = ONEXIT
 		}
 	}
 
-	finalSpec.Entrypoint = ENTRYPOINT
+	if IsTemplateExists(templates, ENTRYPOINT) {
+		finalSpec.Entrypoint = ENTRYPOINT
+	}
 	finalSpec.Templates = templates
 	finalSpec.Arguments.Parameters = params
 
@@ -93,15 +134,25 @@ func (wfc *WorkflowsClientImpl) ConstructSpec(templates []v1alpha1.Template, par
 }
 
 func (wfc *WorkflowsClientImpl) CreateWorkflow(spec *v1alpha1.WorkflowSpec, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error) {
+	route := wfc.cfg.WorkflowServerConfig.RouteFor(workflowsBatch.Payload.Repo)
+	if route.ServiceAccount != "" {
+		spec.ServiceAccountName = route.ServiceAccount
+	}
+
 	workflow := &v1alpha1.Workflow{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.WorkflowSchemaGroupVersionKind.GroupVersion().String(),
+			Kind:       v1alpha1.WorkflowSchemaGroupVersionKind.Kind,
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			GenerateName: ConvertToValidString(workflowsBatch.Payload.Repo + "-" + workflowsBatch.Payload.Branch + "-"),
-			Namespace:    wfc.cfg.Namespace,
+			Namespace:    route.Namespace,
 			Labels: map[string]string{
 				"piper.quickube.com/notified": "false",
 				"repo":                        ConvertToValidString(workflowsBatch.Payload.Repo),
 				"branch":                      ConvertToValidString(workflowsBatch.Payload.Branch),
 				"user":                        ConvertToValidString(workflowsBatch.Payload.User),
+				"event":                       ConvertToValidString(workflowsBatch.Payload.Event),
 				"commit":                      ConvertToValidString(workflowsBatch.Payload.Commit),
 			},
 		},
@@ -111,7 +162,7 @@ func (wfc *WorkflowsClientImpl) CreateWorkflow(spec *v1alpha1.WorkflowSpec, work
 	return workflow, nil
 }
 
-func (wfc *WorkflowsClientImpl) SelectConfig(workflowsBatch *common.WorkflowsBatch) (string, error) {
+func (wfc *WorkflowsClientImpl) SelectConfig(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (string, error) {
 	var configName string
 	if IsConfigExists(&wfc.cfg.WorkflowsConfig, "default") {
 		configName = "default"
@@ -129,116 +180,225 @@ func (wfc *WorkflowsClientImpl) SelectConfig(workflowsBatch *common.WorkflowsBat
 		}
 	}
 
-	log.Printf(
-		"%s config selected for workflow in repo: %s branch %s",
-		configName,
-		workflowsBatch.Payload.Repo,
-		workflowsBatch.Payload.Branch,
-	) // Info
+	route := wfc.cfg.WorkflowServerConfig.RouteFor(workflowsBatch.Payload.Repo)
+	if configName != "" && !route.IsConfigAllowed(configName) {
+		return configName, fmt.Errorf(
+			"config %s is not allowed for repo %s, allowed configs: %v",
+			configName,
+			workflowsBatch.Payload.Repo,
+			route.AllowedConfigs,
+		)
+	}
+
+	logging.OrDefault(wfc.logger).InfoContext(ctx, "config selected", "config", configName, "repo", workflowsBatch.Payload.Repo, "branch", workflowsBatch.Payload.Branch)
 
 	return configName, nil
 }
 
 func (wfc *WorkflowsClientImpl) Lint(wf *v1alpha1.Workflow) error {
-	//TODO implement me
-	panic("implement me")
+	lintErrors := LintWorkflowSpec(&wf.Spec)
+	if len(lintErrors) != 0 {
+		return &LintError{
+			Workflow: wf.GetGenerateName(),
+			Errors:   lintErrors,
+		}
+	}
+
+	return nil
 }
 
-func (wfc *WorkflowsClientImpl) Submit(ctx context.Context, wf *v1alpha1.Workflow) error {
-	workflowsClient := wfc.clientSet.ArgoprojV1alpha1().Workflows(wfc.cfg.Namespace)
-	_, err := workflowsClient.Create(ctx, wf, metav1.CreateOptions{})
+func (wfc *WorkflowsClientImpl) Submit(ctx context.Context, wf *v1alpha1.Workflow) (*v1alpha1.Workflow, error) {
+	created, err := wfc.api.Create(ctx, wfc.namespaceOf(wf), wf)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return created, nil
 }
 
-func (wfc *WorkflowsClientImpl) HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) error {
-	var params []v1alpha1.Parameter
+func (wfc *WorkflowsClientImpl) RenderWorkflow(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error) {
+	params, err := BuildParameters(workflowsBatch)
+	if err != nil {
+		return nil, err
+	}
 
-	configName, err := wfc.SelectConfig(workflowsBatch)
+	configName, err := wfc.SelectConfig(ctx, workflowsBatch)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	templates, err := wfc.ConstructTemplates(workflowsBatch, configName)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if workflowsBatch.Parameters != nil {
-		params, err = GetParameters(workflowsBatch.Parameters)
-		if err != nil {
-			return err
-		}
+	spec, err := wfc.ConstructSpec(templates, params, configName)
+	if err != nil {
+		return nil, err
 	}
 
-	globalParams := []v1alpha1.Parameter{
-		{Name: "event", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.Event)},
-		{Name: "action", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.Action)},
-		{Name: "repo", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.Repo)},
-		{Name: "branch", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.Branch)},
-		{Name: "commit", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.Commit)},
-		{Name: "user", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.User)},
-		{Name: "user_email", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.UserEmail)},
-		{Name: "pull_request_url", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.PullRequestURL)},
-		{Name: "pull_request_title", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.PullRequestTitle)},
-		{Name: "dest_branch", Value: v1alpha1.AnyStringPtr(workflowsBatch.Payload.DestBranch)},
-		{Name: "pull_request_labels", Value: v1alpha1.AnyStringPtr(strings.Join(workflowsBatch.Payload.Labels, ","))},
+	spec.WorkflowTemplateRef = workflowsBatch.WorkflowTemplateRef
+
+	workflow, err := wfc.CreateWorkflow(spec, workflowsBatch)
+	if err != nil {
+		return nil, err
+	}
+	workflow.SetAnnotations(map[string]string{
+		CONFIG_ANNOTATION: configName,
+		REPO_ANNOTATION:   workflowsBatch.Payload.Repo,
+		BRANCH_ANNOTATION: workflowsBatch.Payload.Branch,
+	})
+	if workflowsBatch.Concurrency != nil {
+		workflow.Labels[CONCURRENCY_GROUP_LABEL] = ConvertToValidLabelValue(workflowsBatch.Concurrency.Group)
+		workflow.Annotations[CONCURRENCY_GROUP_LABEL] = workflowsBatch.Concurrency.Group
+	}
+	if workflowsBatch.IdempotencyKey != "" {
+		workflow.Labels[IDEMPOTENCY_KEY_LABEL] = workflowsBatch.IdempotencyKey
+	}
+	if workflowsBatch.Payload.DeliveryID != "" {
+		workflow.Annotations[DELIVERY_ID_ANNOTATION] = workflowsBatch.Payload.DeliveryID
+	}
+	if workflowsBatch.NodeStatuses {
+		workflow.Annotations[NODE_STATUSES_ANNOTATION] = "true"
+	}
+	if workflowsBatch.Payload.PullRequestID != 0 {
+		workflow.Labels[PULL_REQUEST_LABEL] = strconv.Itoa(workflowsBatch.Payload.PullRequestID)
 	}
 
-	params = append(params, globalParams...)
+	return workflow, nil
+}
 
-	spec, err := wfc.ConstructSpec(templates, params, configName)
+// HandleWorkflowBatch renders, checks and submits the workflow of the batch. It returns the submitted workflow,
+// or nil when the workflow was already submitted within the deduplication window.
+func (wfc *WorkflowsClientImpl) HandleWorkflowBatch(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (*v1alpha1.Workflow, error) {
+	workflow, err := wfc.buildWorkflow(ctx, workflowsBatch)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	workflow, err := wfc.CreateWorkflow(spec, workflowsBatch)
+	duplicate, err := wfc.IsDuplicate(ctx, workflow.GetNamespace(), workflowsBatch.IdempotencyKey)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if duplicate {
+		logging.OrDefault(wfc.logger).InfoContext(ctx, "skipping duplicate workflow", "idempotency_key", workflowsBatch.IdempotencyKey)
+		return nil, nil
 	}
 
-	err = wfc.Submit(ctx, workflow)
+	// The submission span is annotated on the workflow, so the spans of its notifications link back to it
+	submitCtx, span := tracing.Tracer().Start(ctx, "Submit", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("workflow.generate_name", workflow.GetGenerateName())))
+	tracing.Annotate(submitCtx, workflow.Annotations)
+	created, err := wfc.Submit(submitCtx, workflow)
+	tracing.End(span, err)
 	if err != nil {
-		return fmt.Errorf("failed to submit workflow, error: %v", err)
+		wfc.releaseIdempotencyKey(workflowsBatch.IdempotencyKey)
+		return nil, fmt.Errorf("failed to submit workflow, error: %v", err)
 	}
 
-	log.Printf("submit workflow for branch %s repo %s commit %s", workflowsBatch.Payload.Branch, workflowsBatch.Payload.Repo, workflowsBatch.Payload.Commit)
-	return nil
+	logging.OrDefault(wfc.logger).InfoContext(ctx, "submitted workflow", "workflow", created.GetName())
+
+	// Canceling once the superseding workflow is submitted, so a failed submission leaves the group running
+	if workflowsBatch.Concurrency != nil && workflowsBatch.Concurrency.CancelInProgress {
+		err = wfc.CancelInProgress(ctx, workflow.GetNamespace(), workflowsBatch.Concurrency, workflowsBatch.Payload.Commit)
+		if err != nil {
+			logging.OrDefault(wfc.logger).ErrorContext(ctx, "failed to cancel in progress workflows", "group", workflowsBatch.Concurrency.Group, "error", err)
+		}
+	}
+	return created, nil
 }
 
-func (wfc *WorkflowsClientImpl) Watch(ctx context.Context, labelSelector *metav1.LabelSelector) (watch.Interface, error) {
-	workflowsClient := wfc.clientSet.ArgoprojV1alpha1().Workflows(wfc.cfg.Namespace)
-	opts := v1.ListOptions{
-		Watch:         true,
-		LabelSelector: metav1.FormatLabelSelector(labelSelector),
+// buildWorkflow renders, lints, resolves the template references of and enforces the policy on the workflow of the batch.
+func (wfc *WorkflowsClientImpl) buildWorkflow(ctx context.Context, workflowsBatch *common.WorkflowsBatch) (workflow *v1alpha1.Workflow, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "BuildWorkflow")
+	defer func() { tracing.End(span, err) }()
+
+	workflow, err = wfc.RenderWorkflow(ctx, workflowsBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wfc.Lint(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	err = wfc.ResolveTemplateRefs(ctx, workflow)
+	if err != nil {
+		return nil, err
 	}
-	watcher, err := workflowsClient.Watch(ctx, opts)
+
+	err = wfc.EnforcePolicy(ctx, workflow)
 	if err != nil {
 		return nil, err
 	}
+	return workflow, nil
+}
 
-	return watcher, nil
+// watchedNamespaces returns the namespaces Piper creates workflows in.
+func (wfc *WorkflowsClientImpl) watchedNamespaces() []string {
+	if wfc.cfg.ControllerConfig.Enabled {
+		// PiperRepository resources can route repos to new namespaces at any time
+		return []string{metav1.NamespaceAll}
+	}
+	return wfc.cfg.WorkflowServerConfig.Namespaces()
 }
 
-func (wfc *WorkflowsClientImpl) UpdatePiperWorkflowLabel(ctx context.Context, workflowName string, label string, value string) error {
-	workflowsClient := wfc.clientSet.ArgoprojV1alpha1().Workflows(wfc.cfg.Namespace)
+// List lists the workflows of the watched namespaces. The resource version of the list is only kept for a single
+// namespace, so a watch following the list of several namespaces starts with the existing workflows.
+func (wfc *WorkflowsClientImpl) List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.WorkflowList, error) {
+	namespaces := wfc.watchedNamespaces()
+	workflows := &v1alpha1.WorkflowList{Items: make([]v1alpha1.Workflow, 0)}
+	for _, namespace := range namespaces {
+		list, err := wfc.api.List(ctx, namespace, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list workflows in namespace %s, error: %v", namespace, err)
+		}
+		if len(namespaces) == 1 {
+			workflows.ResourceVersion = list.ResourceVersion
+		}
+		workflows.Items = append(workflows.Items, list.Items...)
+	}
+	return workflows, nil
+}
 
-	patch, err := json.Marshal(map[string]interface{}{"metadata": metav1.ObjectMeta{
-		Labels: map[string]string{
-			fmt.Sprintf("piper.quickube.com/%s", label): value,
-		},
-	}})
-	if err != nil {
-		return err
+// Watch watches the workflows of ARGO_WORKFLOWS_NAMESPACE and of every routed namespace, or of all namespaces
+// when the controller is enabled.
+func (wfc *WorkflowsClientImpl) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	watchers := make([]watch.Interface, 0)
+	for _, namespace := range wfc.watchedNamespaces() {
+		watcher, err := wfc.api.Watch(ctx, namespace, opts)
+		if err != nil {
+			for _, w := range watchers {
+				w.Stop()
+			}
+			return nil, fmt.Errorf("failed to watch workflows in namespace %s, error: %v", namespace, err)
+		}
+		watchers = append(watchers, watcher)
 	}
-	_, err = workflowsClient.Patch(ctx, workflowName, types.MergePatchType, patch, v1.PatchOptions{})
+
+	if len(watchers) == 1 {
+		return watchers[0], nil
+	}
+	return NewMultiWatcher(watchers), nil
+}
+
+func (wfc *WorkflowsClientImpl) UpdatePiperWorkflowLabel(ctx context.Context, namespace string, workflowName string, label string, value string) error {
+	labels := map[string]string{
+		fmt.Sprintf("piper.quickube.com/%s", label): value,
+	}
+	err := wfc.api.SetLabels(ctx, namespace, workflowName, labels)
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf("workflow %s labels piper.quickube.com/%s updated to %s\n", workflowName, label, value)
+	logging.OrDefault(wfc.logger).DebugContext(ctx, "workflow label updated", "workflow", workflowName, "label", "piper.quickube.com/"+label, "value", value)
 	return nil
 }
+
+func (wfc *WorkflowsClientImpl) namespaceOf(wf *v1alpha1.Workflow) string {
+	if wf.GetNamespace() != "" {
+		return wf.GetNamespace()
+	}
+	return wfc.cfg.Namespace
+}
@@ -1,6 +1,7 @@
 package workflow_handler
 
 import (
+	"context"
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/common"
 	"github.com/quickube/piper/pkg/conf"
@@ -30,12 +31,12 @@ func TestSelectConfig(t *testing.T) {
 
 	wfcImpl := &WorkflowsClientImpl{
 		cfg: &conf.GlobalConfig{
-			WorkflowsConfig: *wfc,
+			WorkflowsConfig: conf.WorkflowsConfig{Configs: wfc.Configs},
 		},
 	}
 
 	// Call the SelectConfig function
-	returnConfigName, err := wfcImpl.SelectConfig(workflowsBatch)
+	returnConfigName, err := wfcImpl.SelectConfig(context.Background(), workflowsBatch)
 
 	// Assert the expected output
 	assert.Equal("default", returnConfigName)
@@ -49,7 +50,7 @@ func TestSelectConfig(t *testing.T) {
 	}
 
 	// Call the SelectConfig function
-	returnConfigName, err = wfcImpl.SelectConfig(workflowsBatch)
+	returnConfigName, err = wfcImpl.SelectConfig(context.Background(), workflowsBatch)
 
 	// Assert the expected output
 	assert.Equal("config1", returnConfigName)
@@ -63,7 +64,7 @@ func TestSelectConfig(t *testing.T) {
 	}
 
 	// Call the SelectConfig function
-	returnConfigName, err = wfcImpl.SelectConfig(workflowsBatch)
+	returnConfigName, err = wfcImpl.SelectConfig(context.Background(), workflowsBatch)
 
 	// Assert the expected output
 	assert.Equal("default", returnConfigName)
@@ -83,12 +84,12 @@ func TestSelectConfig(t *testing.T) {
 
 	wfcImpl4 := &WorkflowsClientImpl{
 		cfg: &conf.GlobalConfig{
-			WorkflowsConfig: *wfc4,
+			WorkflowsConfig: conf.WorkflowsConfig{Configs: wfc4.Configs},
 		},
 	}
 
 	// Call the SelectConfig function
-	returnConfigName, err = wfcImpl4.SelectConfig(workflowsBatch)
+	returnConfigName, err = wfcImpl4.SelectConfig(context.Background(), workflowsBatch)
 
 	// Assert the expected output
 	assert.NotNil(returnConfigName)
@@ -113,7 +114,7 @@ func TestCreateWorkflow(t *testing.T) {
 
 	wfcImpl := &WorkflowsClientImpl{
 		cfg: &conf.GlobalConfig{
-			WorkflowsConfig:      *wfc,
+			WorkflowsConfig:      conf.WorkflowsConfig{Configs: wfc.Configs},
 			WorkflowServerConfig: *wfs,
 		},
 	}
@@ -134,6 +135,7 @@ func TestCreateWorkflow(t *testing.T) {
 			Branch: "my-branch",
 			User:   "my-user",
 			Commit: "my-commit",
+			Event:  "pull_request",
 		},
 	}
 
@@ -155,8 +157,41 @@ func TestCreateWorkflow(t *testing.T) {
 		"branch":                     "my-branch",
 		"user":                       "my-user",
 		"commit":                     "my-commit",
+		"event":                      "pull-request",
 	}, workflow.ObjectMeta.Labels)
 
 	// Assert that the workflow's Spec is assigned correctly
 	assert.Equal(*spec, workflow.Spec)
 }
+
+func TestRenderWorkflow_WorkflowTemplateRef(t *testing.T) {
+	assert := assertion.New(t)
+	ctx := context.Background()
+
+	wfcImpl := &WorkflowsClientImpl{
+		cfg: &conf.GlobalConfig{
+			WorkflowServerConfig: conf.WorkflowServerConfig{Namespace: "workflows"},
+			WorkflowsConfig: conf.WorkflowsConfig{Configs: map[string]*conf.ConfigInstance{
+				"default": {
+					Spec:   v1alpha1.WorkflowSpec{ServiceAccountName: "argo-wf"},
+					OnExit: []v1alpha1.DAGTask{{Name: "github-status", Template: "exit-handler"}},
+				},
+			}},
+		},
+	}
+	config := ""
+	workflowsBatch := &common.WorkflowsBatch{
+		Config:              &config,
+		WorkflowTemplateRef: &v1alpha1.WorkflowTemplateRef{Name: "org-release-pipeline", ClusterScope: true},
+		Payload:             &git_provider.WebhookPayload{Repo: "my-repo", Branch: "main", Commit: "abc123"},
+	}
+
+	workflow, err := wfcImpl.RenderWorkflow(ctx, workflowsBatch)
+	assert.Nil(err)
+	assert.Equal(workflowsBatch.WorkflowTemplateRef, workflow.Spec.WorkflowTemplateRef)
+	assert.Empty(workflow.Spec.Templates)
+	assert.Empty(workflow.Spec.OnExit)
+	assert.Empty(workflow.Spec.Entrypoint)
+	assert.Equal("argo-wf", workflow.Spec.ServiceAccountName)
+	assert.Nil(wfcImpl.Lint(workflow))
+}
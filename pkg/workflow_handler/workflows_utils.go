@@ -8,14 +8,12 @@ import (
 	"github.com/quickube/piper/pkg/git_provider"
 	"github.com/quickube/piper/pkg/utils"
 	"gopkg.in/yaml.v3"
-	"log"
 	"regexp"
 	"strings"
 )
 
 func CreateDAGTemplate(fileList []*git_provider.CommitFile, name string) (*v1alpha1.Template, error) {
 	if len(fileList) == 0 {
-		log.Printf("empty file list for %s", name)
 		return nil, nil
 	}
 	DAGs := make([]v1alpha1.DAGTask, 0)
@@ -80,12 +78,22 @@ func GetParameters(paramsFile *git_provider.CommitFile) ([]v1alpha1.Parameter, e
 }
 
 func IsConfigExists(cfg *conf.WorkflowsConfig, config string) bool {
-	_, ok := cfg.Configs[config]
+	_, ok := cfg.GetConfig(config)
 	return ok
 }
 
+func IsTemplateExists(templates []v1alpha1.Template, name string) bool {
+	for _, template := range templates {
+		if template.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func IsConfigsOnExitExists(cfg *conf.WorkflowsConfig, config string) bool {
-	return len(cfg.Configs[config].OnExit) != 0
+	configInstance, ok := cfg.GetConfig(config)
+	return ok && len(configInstance.OnExit) != 0
 }
 
 func ValidateDAGTasks(tasks []v1alpha1.DAGTask) error {
@@ -116,3 +124,9 @@ func ConvertToValidString(input string) string {
 
 	return validString
 }
+
+func ConvertToValidLabelValue(input string) string {
+	// Label values are limited to 63 characters and must start and end with an alphanumeric character
+	validString := utils.TrimString(ConvertToValidString(input), 63)
+	return strings.Trim(validString, ".-")
+}
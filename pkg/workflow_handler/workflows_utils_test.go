@@ -5,6 +5,7 @@ import (
 	"github.com/argoproj/argo-workflows/v3/pkg/apis/workflow/v1alpha1"
 	"github.com/quickube/piper/pkg/git_provider"
 	assertion "github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
@@ -222,3 +223,22 @@ func TestConvertToValidString(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertToValidLabelValue(t *testing.T) {
+	assert := assertion.New(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"my-repo-main", "my-repo-main"},
+		{"-My_Repo-feature/new-", "my-repo-featurenew"},
+		{strings.Repeat("a", 62) + "-b", strings.Repeat("a", 62)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			assert.Equal(test.expected, ConvertToValidLabelValue(test.input))
+		})
+	}
+}